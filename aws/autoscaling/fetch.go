@@ -0,0 +1,128 @@
+package autoscaling
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asTypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func FetchAllGroups(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]asTypes.AutoScalingGroup, error) {
+	log.Debugf("Fetching all %s Auto Scaling groups", cfg.Region)
+
+	groups := []asTypes.AutoScalingGroup{}
+	client := autoscaling.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, describeResult.AutoScalingGroups...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("auto scaling groups", load)
+	if err != nil {
+		return groups, err
+	}
+
+	log.Infof("Fetched %d %s Auto Scaling groups", len(groups), cfg.Region)
+	return groups, nil
+}
+
+func FetchAllLaunchConfigurations(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]asTypes.LaunchConfiguration, error) {
+	log.Debugf("Fetching all %s launch configurations", cfg.Region)
+
+	configurations := []asTypes.LaunchConfiguration{}
+	client := autoscaling.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeLaunchConfigurations(ctx, &autoscaling.DescribeLaunchConfigurationsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		configurations = append(configurations, describeResult.LaunchConfigurations...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("launch configurations", load)
+	if err != nil {
+		return configurations, err
+	}
+
+	log.Infof("Fetched %d %s launch configurations", len(configurations), cfg.Region)
+	return configurations, nil
+}
+
+func FetchAllPolicies(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]asTypes.ScalingPolicy, error) {
+	log.Debugf("Fetching all %s Auto Scaling policies", cfg.Region)
+
+	policies := []asTypes.ScalingPolicy{}
+	client := autoscaling.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribePolicies(ctx, &autoscaling.DescribePoliciesInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, describeResult.ScalingPolicies...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("auto scaling policies", load)
+	if err != nil {
+		return policies, err
+	}
+
+	log.Infof("Fetched %d %s Auto Scaling policies", len(policies), cfg.Region)
+	return policies, nil
+}
+
+func FetchAllScheduledActions(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]asTypes.ScheduledUpdateGroupAction, error) {
+	log.Debugf("Fetching all %s Auto Scaling scheduled actions", cfg.Region)
+
+	actions := []asTypes.ScheduledUpdateGroupAction{}
+	client := autoscaling.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeScheduledActions(ctx, &autoscaling.DescribeScheduledActionsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, describeResult.ScheduledUpdateGroupActions...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("auto scaling scheduled actions", load)
+	if err != nil {
+		return actions, err
+	}
+
+	log.Infof("Fetched %d %s Auto Scaling scheduled actions", len(actions), cfg.Region)
+	return actions, nil
+}
@@ -3,6 +3,10 @@ package aws
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"awstool/http"
@@ -11,6 +15,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	smithylogging "github.com/aws/smithy-go/logging"
 	"github.com/davecgh/go-spew/spew"
 	log "github.com/sirupsen/logrus"
@@ -20,11 +27,41 @@ const defaultMaxRequestsInFlight = 50
 const defaultMaxRetries = 9
 const defaultMaxRetryTime = 10 * time.Second
 
+// environment variables used when the equivalent options are not set. They allow pointing the
+// tool at an emulator like LocalStack or moto without passing flags to every command
+const (
+	EndpointURLEnv         = "AWSTOOL_ENDPOINT_URL"
+	ServiceEndpointURLsEnv = "AWSTOOL_SERVICE_ENDPOINT_URLS"
+	S3PathStyleEnv         = "AWSTOOL_S3_PATH_STYLE"
+	AccessKeyIdEnv         = "AWSTOOL_ACCESS_KEY_ID"
+	SecretAccessKeyEnv     = "AWSTOOL_SECRET_ACCESS_KEY"
+	SessionTokenEnv        = "AWSTOOL_SESSION_TOKEN"
+)
+
 type AWSConfigOptions struct {
 	Profile             string
 	MaxRequestsInFlight int
 	MaxRetries          int
 	MaxRetryTime        time.Duration
+
+	// EndpointURL overrides the endpoint of all services, eg http://localhost:4566 for LocalStack
+	EndpointURL string
+	// ServiceEndpointURLs overrides the endpoint of specific services and takes precedence over
+	// EndpointURL. Services are keyed by the name of their sdk package, eg s3, ec2 or
+	// elasticsearchservice
+	ServiceEndpointURLs map[string]string
+	// S3PathStyle puts bucket names in the path of s3 requests instead of in the hostname. Only
+	// applies when the s3 endpoint is overridden, which is when emulators usually require it
+	S3PathStyle bool
+
+	// static credentials are used instead of the ones configured for the profile when set
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// HTTPClient sends requests to the AWS APIs, eg an http.Recorder to record fixtures.
+	// Defaults to the sdk client
+	HTTPClient config.HTTPClient
 }
 
 func NewAWSConfigOptions() AWSConfigOptions {
@@ -32,7 +69,42 @@ func NewAWSConfigOptions() AWSConfigOptions {
 		MaxRequestsInFlight: defaultMaxRequestsInFlight,
 		MaxRetries:          defaultMaxRetries,
 		MaxRetryTime:        defaultMaxRetryTime,
+		ServiceEndpointURLs: map[string]string{},
+	}
+}
+
+// LoadEnv fills options that were not set from their environment variables
+func (o *AWSConfigOptions) LoadEnv() error {
+	if o.EndpointURL == "" {
+		o.EndpointURL = os.Getenv(EndpointURLEnv)
+	}
+	if value := os.Getenv(ServiceEndpointURLsEnv); value != "" {
+		if o.ServiceEndpointURLs == nil {
+			o.ServiceEndpointURLs = map[string]string{}
+		}
+		for _, pair := range strings.Split(value, ",") {
+			service, endpointURL, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid %s: expected service=url pairs, got %q", ServiceEndpointURLsEnv, pair)
+			}
+			if _, set := o.ServiceEndpointURLs[service]; !set {
+				o.ServiceEndpointURLs[service] = endpointURL
+			}
+		}
+	}
+	if value := os.Getenv(S3PathStyleEnv); value != "" && !o.S3PathStyle {
+		pathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", S3PathStyleEnv, err)
+		}
+		o.S3PathStyle = pathStyle
 	}
+	if o.AccessKeyId == "" && o.SecretAccessKey == "" {
+		o.AccessKeyId = os.Getenv(AccessKeyIdEnv)
+		o.SecretAccessKey = os.Getenv(SecretAccessKeyEnv)
+		o.SessionToken = os.Getenv(SessionTokenEnv)
+	}
+	return nil
 }
 
 func (o *AWSConfigOptions) Validate() error {
@@ -45,6 +117,31 @@ func (o *AWSConfigOptions) Validate() error {
 	if o.MaxRetryTime < 0 {
 		return fmt.Errorf("maxRetryTime must be a positive time (passed: %v)", o.MaxRetryTime)
 	}
+	if err := validateEndpointURL(o.EndpointURL); err != nil {
+		return fmt.Errorf("invalid endpointURL: %w", err)
+	}
+	for service, endpointURL := range o.ServiceEndpointURLs {
+		if err := validateEndpointURL(endpointURL); err != nil {
+			return fmt.Errorf("invalid endpoint url for service %s: %w", service, err)
+		}
+	}
+	if (o.AccessKeyId == "") != (o.SecretAccessKey == "") {
+		return fmt.Errorf("accessKeyId and secretAccessKey must be passed together")
+	}
+	return nil
+}
+
+func validateEndpointURL(endpointURL string) error {
+	if endpointURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(endpointURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute url, eg http://localhost:4566", endpointURL)
+	}
 	return nil
 }
 
@@ -53,12 +150,20 @@ func NewAWSConfig(ctx context.Context, options AWSConfigOptions) (aws.Config, er
 		return aws.Config{}, err
 	}
 
-	log.Debug(spew.Sprintf("Creating config with the following options: %+v", options))
+	logged := options
+	if logged.SecretAccessKey != "" {
+		logged.SecretAccessKey = "<redacted>"
+	}
+	if logged.SessionToken != "" {
+		logged.SessionToken = "<redacted>"
+	}
+	log.Debug(spew.Sprintf("Creating config with the following options: %+v", logged))
 
-	httpClient := http.NewParallelLimitedHTTPClient(
-		awshttp.NewBuildableClient(),
-		options.MaxRequestsInFlight,
-	)
+	var baseClient config.HTTPClient = awshttp.NewBuildableClient()
+	if options.HTTPClient != nil {
+		baseClient = options.HTTPClient
+	}
+	httpClient := http.NewParallelLimitedHTTPClient(baseClient, options.MaxRequestsInFlight)
 
 	createRetryer := func() aws.Retryer {
 		return retry.NewStandard(
@@ -90,5 +195,74 @@ func NewAWSConfig(ctx context.Context, options AWSConfigOptions) (aws.Config, er
 		cfgOptions = append(cfgOptions, config.WithSharedConfigProfile(options.Profile))
 	}
 
+	if options.EndpointURL != "" || len(options.ServiceEndpointURLs) > 0 {
+		cfgOptions = append(cfgOptions, config.WithEndpointResolverWithOptions(endpointResolver(options)))
+	}
+
+	if options.AccessKeyId != "" {
+		cfgOptions = append(cfgOptions, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(options.AccessKeyId, options.SecretAccessKey, options.SessionToken),
+		))
+	}
+
 	return config.LoadDefaultConfig(ctx, cfgOptions...)
 }
+
+// serviceKey converts sdk service ids (eg "Elastic Load Balancing v2") to the name of their
+// sdk package (eg elasticloadbalancingv2), which is how endpoint overrides are keyed
+func serviceKey(serviceId string) string {
+	return strings.ToLower(strings.ReplaceAll(serviceId, " ", ""))
+}
+
+func endpointResolver(options AWSConfigOptions) aws.EndpointResolverWithOptions {
+	return aws.EndpointResolverWithOptionsFunc(
+		func(service, region string, opts ...interface{}) (aws.Endpoint, error) {
+			key := serviceKey(service)
+			endpointURL, ok := options.ServiceEndpointURLs[key]
+			if !ok {
+				endpointURL = options.EndpointURL
+			}
+			if endpointURL == "" {
+				// falls back to the default endpoint of the service
+				return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+			}
+			return aws.Endpoint{
+				URL:           endpointURL,
+				SigningRegion: region,
+				Source:        aws.EndpointSourceCustom,
+				// for s3 an immutable hostname means bucket names go in the path instead
+				HostnameImmutable: key != "s3" || options.S3PathStyle,
+			}, nil
+		},
+	)
+}
+
+// OverriddenEndpoint returns the endpoint url configured for the service, if any. Services are
+// identified by their sdk service id, eg "Elasticsearch Service"
+func OverriddenEndpoint(cfg aws.Config, serviceId string) (string, bool) {
+	if cfg.EndpointResolverWithOptions == nil {
+		return "", false
+	}
+	endpoint, err := cfg.EndpointResolverWithOptions.ResolveEndpoint(serviceId, cfg.Region)
+	if err != nil || endpoint.URL == "" {
+		return "", false
+	}
+	return endpoint.URL, true
+}
+
+// AssumeRoleConfig returns a copy of the given config that uses credentials obtained by assuming
+// the given role with the credentials of the original config. Credentials are only retrieved
+// when first needed and are refreshed automatically when they expire
+func AssumeRoleConfig(cfg aws.Config, roleArn string) aws.Config {
+	log.Debugf("Creating config assuming role %s", roleArn)
+	provider := stscreds.NewAssumeRoleProvider(
+		sts.NewFromConfig(cfg),
+		roleArn,
+		func(opts *stscreds.AssumeRoleOptions) {
+			opts.RoleSessionName = "awstool"
+		},
+	)
+	result := cfg.Copy()
+	result.Credentials = aws.NewCredentialsCache(provider)
+	return result
+}
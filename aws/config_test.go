@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestEndpointOverride(t *testing.T) {
+	var paths []string
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Host+r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/credentials")
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv(EndpointURLEnv, server.URL)
+	t.Setenv(S3PathStyleEnv, "true")
+	t.Setenv(AccessKeyIdEnv, "test")
+	t.Setenv(SecretAccessKeyEnv, "test")
+
+	options := NewAWSConfigOptions()
+	if err := options.LoadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewAWSConfig(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s3.NewFromConfig(cfg).HeadBucket(context.Background(), &s3.HeadBucketInput{Bucket: aws.String("bucket")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.TrimPrefix(server.URL, "http://") + "/bucket"
+	if len(paths) != 1 || paths[0] != expected {
+		t.Errorf("expected a single request to %s, got %v", expected, paths)
+	}
+	if !strings.Contains(authorization, "Credential=test/") {
+		t.Errorf("expected request to be signed with the static credentials, got %q", authorization)
+	}
+
+	if _, ok := OverriddenEndpoint(cfg, s3.ServiceID); !ok {
+		t.Errorf("expected s3 endpoint to be overridden")
+	}
+}
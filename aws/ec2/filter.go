@@ -0,0 +1,148 @@
+package ec2
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// instanceFilterValues maps DescribeInstances filter names to functions extracting the
+// values of an instance that are checked against that filter. Only filters present here
+// are supported when filtering instances locally
+var instanceFilterValues = map[string]func(*ec2Types.Instance) []string{
+	"instance-id": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.InstanceId)}
+	},
+	"instance-type": func(i *ec2Types.Instance) []string {
+		return []string{string(i.InstanceType)}
+	},
+	"instance-state-name": func(i *ec2Types.Instance) []string {
+		if i.State == nil {
+			return nil
+		}
+		return []string{string(i.State.Name)}
+	},
+	"image-id": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.ImageId)}
+	},
+	"vpc-id": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.VpcId)}
+	},
+	"subnet-id": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.SubnetId)}
+	},
+	"availability-zone": func(i *ec2Types.Instance) []string {
+		if i.Placement == nil {
+			return nil
+		}
+		return []string{aws.ToString(i.Placement.AvailabilityZone)}
+	},
+	"private-ip-address": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.PrivateIpAddress)}
+	},
+	"ip-address": func(i *ec2Types.Instance) []string {
+		return []string{aws.ToString(i.PublicIpAddress)}
+	},
+	"tag-key": func(i *ec2Types.Instance) []string {
+		keys := make([]string, len(i.Tags))
+		for idx, tag := range i.Tags {
+			keys[idx] = aws.ToString(tag.Key)
+		}
+		return keys
+	},
+}
+
+// FilterInstances applies the same fetch options accepted by FetchAllInstances to reservations
+// that were already fetched, eg when working from a previous dump. Reservations left with no
+// instances are dropped from the result
+func FilterInstances(reservations []ec2Types.Reservation, options ...FetchOption) ([]ec2Types.Reservation, error) {
+	input := newDescribeInput(options...)
+	matchers := make([]func(*ec2Types.Instance) bool, 0, len(input.Filters))
+	for _, filter := range input.Filters {
+		matcher, err := filterMatcher(filter)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	instanceIds := map[string]struct{}{}
+	for _, id := range input.InstanceIds {
+		instanceIds[id] = struct{}{}
+	}
+
+	result := []ec2Types.Reservation{}
+	for _, reservation := range reservations {
+		instances := []ec2Types.Instance{}
+		for idx := range reservation.Instances {
+			instance := &reservation.Instances[idx]
+			if len(instanceIds) > 0 {
+				if _, ok := instanceIds[aws.ToString(instance.InstanceId)]; !ok {
+					continue
+				}
+			}
+			matches := true
+			for _, matcher := range matchers {
+				if !matcher(instance) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				instances = append(instances, *instance)
+			}
+		}
+		if len(instances) > 0 {
+			reservation.Instances = instances
+			result = append(result, reservation)
+		}
+	}
+	return result, nil
+}
+
+// filterMatcher builds a function checking a single filter. As in the DescribeInstances API, an
+// instance matches a filter if any of its values match any of the filter values, and filter values
+// may contain * and ? wildcards
+func filterMatcher(filter ec2Types.Filter) (func(*ec2Types.Instance) bool, error) {
+	name := aws.ToString(filter.Name)
+	patterns := make([]*regexp.Regexp, len(filter.Values))
+	for idx, value := range filter.Values {
+		patterns[idx] = common.WildcardRegexp(value)
+	}
+
+	var values func(*ec2Types.Instance) []string
+	if strings.HasPrefix(name, "tag:") {
+		key := strings.TrimPrefix(name, "tag:")
+		values = func(i *ec2Types.Instance) []string {
+			result := []string{}
+			for _, tag := range i.Tags {
+				if aws.ToString(tag.Key) == key {
+					result = append(result, aws.ToString(tag.Value))
+				}
+			}
+			return result
+		}
+	} else {
+		var ok bool
+		values, ok = instanceFilterValues[name]
+		if !ok {
+			return nil, fmt.Errorf("filter %q is not supported when filtering instances locally", name)
+		}
+	}
+
+	return func(i *ec2Types.Instance) bool {
+		for _, value := range values(i) {
+			for _, pattern := range patterns {
+				if pattern.MatchString(value) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
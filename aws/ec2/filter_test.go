@@ -0,0 +1,135 @@
+package ec2
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFilterInstances(t *testing.T) {
+	reservations := []ec2Types.Reservation{
+		{
+			ReservationId: aws.String("r-1"),
+			Instances: []ec2Types.Instance{
+				{
+					InstanceId:       aws.String("i-1"),
+					InstanceType:     ec2Types.InstanceTypeT3Micro,
+					State:            &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameRunning},
+					PrivateIpAddress: aws.String("10.0.0.1"),
+					Tags:             []ec2Types.Tag{{Key: aws.String("Env"), Value: aws.String("prod")}},
+				},
+				{
+					InstanceId:       aws.String("i-2"),
+					InstanceType:     ec2Types.InstanceTypeM5Large,
+					State:            &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameStopped},
+					PrivateIpAddress: aws.String("10.0.1.2"),
+					Tags:             []ec2Types.Tag{{Key: aws.String("Env"), Value: aws.String("staging")}},
+				},
+			},
+		},
+		{
+			ReservationId: aws.String("r-2"),
+			Instances: []ec2Types.Instance{
+				{
+					InstanceId:      aws.String("i-3"),
+					InstanceType:    ec2Types.InstanceTypeT3Micro,
+					PublicIpAddress: aws.String("54.1.2.3"),
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		options      []FetchOption
+		instances    []string
+		reservations int
+	}{
+		{
+			name:         "no options",
+			instances:    []string{"i-1", "i-2", "i-3"},
+			reservations: 2,
+		},
+		{
+			name:         "instance ids",
+			options:      []FetchOption{WithInstanceIds("i-2", "i-3")},
+			instances:    []string{"i-2", "i-3"},
+			reservations: 2,
+		},
+		{
+			name:         "tag wildcard",
+			options:      []FetchOption{WithTag("Env", "pro*")},
+			instances:    []string{"i-1"},
+			reservations: 1,
+		},
+		{
+			name:         "alternative values",
+			options:      []FetchOption{WithFilter("instance-state-name", "stopped", "terminated")},
+			instances:    []string{"i-2"},
+			reservations: 1,
+		},
+		{
+			name: "all filters must match",
+			options: []FetchOption{
+				WithFilter("instance-type", "t3.micro"),
+				WithFilter("private-ip-address", "10.0.?.*"),
+			},
+			instances:    []string{"i-1"},
+			reservations: 1,
+		},
+		{
+			name:         "tag keys",
+			options:      []FetchOption{WithFilter("tag-key", "Env")},
+			instances:    []string{"i-1", "i-2"},
+			reservations: 1,
+		},
+		{
+			name:         "ids and filters",
+			options:      []FetchOption{WithInstanceIds("i-1", "i-3"), WithFilter("ip-address", "54.*")},
+			instances:    []string{"i-3"},
+			reservations: 1,
+		},
+		{
+			name:         "no match",
+			options:      []FetchOption{WithTag("Env", "dev")},
+			instances:    []string{},
+			reservations: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := FilterInstances(reservations, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, reservation := range result {
+				for _, instance := range reservation.Instances {
+					ids = append(ids, aws.ToString(instance.InstanceId))
+				}
+			}
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, test.instances) {
+				t.Errorf("expected instances %v, got %v", test.instances, ids)
+			}
+			if len(result) != test.reservations {
+				t.Errorf("expected %d reservations, got %d", test.reservations, len(result))
+			}
+		})
+	}
+
+	// filtering works on copies, the given reservations are left untouched
+	if len(reservations[0].Instances) != 2 {
+		t.Errorf("expected the input reservations to be left untouched, got %v", reservations[0].Instances)
+	}
+}
+
+func TestFilterInstancesUnsupportedFilter(t *testing.T) {
+	_, err := FilterInstances(nil, WithFilter("network-interface.addresses.association.public-ip", "1.2.3.4"))
+	if err == nil {
+		t.Error("expected an error for a filter that can't be applied locally")
+	}
+}
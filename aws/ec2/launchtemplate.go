@@ -0,0 +1,78 @@
+package ec2
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func FetchAllLaunchTemplates(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.LaunchTemplate, error) {
+	log.Debugf("Fetching all %s launch templates", cfg.Region)
+
+	templates := []ec2Types.LaunchTemplate{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeLaunchTemplates(ctx, &ec2.DescribeLaunchTemplatesInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, describeResult.LaunchTemplates...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("launch templates", load)
+	if err != nil {
+		return templates, err
+	}
+
+	log.Infof("Fetched %d %s launch templates", len(templates), cfg.Region)
+
+	return templates, nil
+}
+
+// FetchAllDefaultAndLatestLaunchTemplateVersions fetches the default and the latest version of
+// every launch template with a single listing, instead of one call per template. A version that
+// is both default and latest is only returned once
+func FetchAllDefaultAndLatestLaunchTemplateVersions(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.LaunchTemplateVersion, error) {
+	log.Debugf("Fetching all %s default and latest launch template versions", cfg.Region)
+
+	versions := []ec2Types.LaunchTemplateVersion{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+			Versions:  []string{"$Default", "$Latest"},
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, describeResult.LaunchTemplateVersions...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("launch template versions", load)
+	if err != nil {
+		return versions, err
+	}
+
+	log.Infof("Fetched %d %s default and latest launch template versions", len(versions), cfg.Region)
+
+	return versions, nil
+}
@@ -0,0 +1,340 @@
+package ec2
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func FetchAllVpcs(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.Vpc, error) {
+	log.Debugf("Fetching all %s VPCs", cfg.Region)
+
+	vpcs := []ec2Types.Vpc{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		vpcs = append(vpcs, describeResult.Vpcs...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("vpcs", load)
+	if err != nil {
+		return vpcs, err
+	}
+
+	log.Infof("Fetched %d %s VPCs", len(vpcs), cfg.Region)
+
+	return vpcs, nil
+}
+
+func FetchAllSubnets(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.Subnet, error) {
+	log.Debugf("Fetching all %s subnets", cfg.Region)
+
+	subnets := []ec2Types.Subnet{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, describeResult.Subnets...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("subnets", load)
+	if err != nil {
+		return subnets, err
+	}
+
+	log.Infof("Fetched %d %s subnets", len(subnets), cfg.Region)
+
+	return subnets, nil
+}
+
+func FetchAllRouteTables(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.RouteTable, error) {
+	log.Debugf("Fetching all %s route tables", cfg.Region)
+
+	routeTables := []ec2Types.RouteTable{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		routeTables = append(routeTables, describeResult.RouteTables...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("route tables", load)
+	if err != nil {
+		return routeTables, err
+	}
+
+	log.Infof("Fetched %d %s route tables", len(routeTables), cfg.Region)
+
+	return routeTables, nil
+}
+
+func FetchAllSecurityGroups(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.SecurityGroup, error) {
+	log.Debugf("Fetching all %s security groups", cfg.Region)
+
+	securityGroups := []ec2Types.SecurityGroup{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		securityGroups = append(securityGroups, describeResult.SecurityGroups...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("security groups", load)
+	if err != nil {
+		return securityGroups, err
+	}
+
+	log.Infof("Fetched %d %s security groups", len(securityGroups), cfg.Region)
+
+	return securityGroups, nil
+}
+
+func FetchAllNetworkAcls(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.NetworkAcl, error) {
+	log.Debugf("Fetching all %s network ACLs", cfg.Region)
+
+	networkAcls := []ec2Types.NetworkAcl{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		networkAcls = append(networkAcls, describeResult.NetworkAcls...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("network acls", load)
+	if err != nil {
+		return networkAcls, err
+	}
+
+	log.Infof("Fetched %d %s network ACLs", len(networkAcls), cfg.Region)
+
+	return networkAcls, nil
+}
+
+func FetchAllNatGateways(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.NatGateway, error) {
+	log.Debugf("Fetching all %s NAT gateways", cfg.Region)
+
+	natGateways := []ec2Types.NatGateway{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		natGateways = append(natGateways, describeResult.NatGateways...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("nat gateways", load)
+	if err != nil {
+		return natGateways, err
+	}
+
+	log.Infof("Fetched %d %s NAT gateways", len(natGateways), cfg.Region)
+
+	return natGateways, nil
+}
+
+func FetchAllInternetGateways(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.InternetGateway, error) {
+	log.Debugf("Fetching all %s internet gateways", cfg.Region)
+
+	internetGateways := []ec2Types.InternetGateway{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		internetGateways = append(internetGateways, describeResult.InternetGateways...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("internet gateways", load)
+	if err != nil {
+		return internetGateways, err
+	}
+
+	log.Infof("Fetched %d %s internet gateways", len(internetGateways), cfg.Region)
+
+	return internetGateways, nil
+}
+
+func FetchAllVpcPeeringConnections(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.VpcPeeringConnection, error) {
+	log.Debugf("Fetching all %s VPC peering connections", cfg.Region)
+
+	vpcPeeringConnections := []ec2Types.VpcPeeringConnection{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		vpcPeeringConnections = append(vpcPeeringConnections, describeResult.VpcPeeringConnections...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("vpc peering connections", load)
+	if err != nil {
+		return vpcPeeringConnections, err
+	}
+
+	log.Infof("Fetched %d %s VPC peering connections", len(vpcPeeringConnections), cfg.Region)
+
+	return vpcPeeringConnections, nil
+}
+
+func FetchAllVpcEndpoints(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.VpcEndpoint, error) {
+	log.Debugf("Fetching all %s VPC endpoints", cfg.Region)
+
+	vpcEndpoints := []ec2Types.VpcEndpoint{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		vpcEndpoints = append(vpcEndpoints, describeResult.VpcEndpoints...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("vpc endpoints", load)
+	if err != nil {
+		return vpcEndpoints, err
+	}
+
+	log.Infof("Fetched %d %s VPC endpoints", len(vpcEndpoints), cfg.Region)
+
+	return vpcEndpoints, nil
+}
+
+func FetchAllNetworkInterfaces(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.NetworkInterface, error) {
+	log.Debugf("Fetching all %s network interfaces", cfg.Region)
+
+	networkInterfaces := []ec2Types.NetworkInterface{}
+
+	client := ec2.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		describeResult, err := client.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		networkInterfaces = append(networkInterfaces, describeResult.NetworkInterfaces...)
+		return describeResult.NextToken, nil
+	}
+
+	err := common.FetchAll("network interfaces", load)
+	if err != nil {
+		return networkInterfaces, err
+	}
+
+	log.Infof("Fetched %d %s network interfaces", len(networkInterfaces), cfg.Region)
+
+	return networkInterfaces, nil
+}
+
+// FetchAllAddresses fetches all Elastic IPs. Addresses are not paginated
+func FetchAllAddresses(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ec2Types.Address, error) {
+	log.Debugf("Fetching all %s Elastic IPs", cfg.Region)
+
+	client := ec2.NewFromConfig(cfg)
+	describeResult, err := client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Fetched %d %s Elastic IPs", len(describeResult.Addresses), cfg.Region)
+
+	return describeResult.Addresses, nil
+}
@@ -0,0 +1,71 @@
+package ec2instanceconnect
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+)
+
+const keyType = "ssh-ed25519"
+
+// Key is an ephemeral ed25519 ssh key pair
+type Key struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+}
+
+func GenerateKey() (*Key, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{public: public, private: private}, nil
+}
+
+// AuthorizedKey returns the public key in the authorized_keys format, eg "ssh-ed25519 AAAA..."
+func (k *Key) AuthorizedKey() string {
+	return keyType + " " + base64.StdEncoding.EncodeToString(k.publicWire())
+}
+
+func (k *Key) publicWire() []byte {
+	return appendString(appendString(nil, []byte(keyType)), k.public)
+}
+
+// PrivateKeyPEM returns the unencrypted private key in the openssh format, as read by ssh -i.
+// The format is described at https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+func (k *Key) PrivateKeyPEM() ([]byte, error) {
+	checkBytes := make([]byte, 4)
+	if _, err := rand.Read(checkBytes); err != nil {
+		return nil, err
+	}
+	check := binary.BigEndian.Uint32(checkBytes)
+
+	private := binary.BigEndian.AppendUint32(nil, check)
+	private = binary.BigEndian.AppendUint32(private, check)
+	private = appendString(private, []byte(keyType))
+	private = appendString(private, k.public)
+	private = appendString(private, k.private)
+	private = appendString(private, []byte("awstool"))
+	// without encryption the block size is 8
+	for padding := byte(1); len(private)%8 != 0; padding++ {
+		private = append(private, padding)
+	}
+
+	data := append([]byte("openssh-key-v1"), 0)
+	data = appendString(data, []byte("none"))
+	data = appendString(data, []byte("none"))
+	data = appendString(data, nil)
+	data = binary.BigEndian.AppendUint32(data, 1)
+	data = appendString(data, k.publicWire())
+	data = appendString(data, private)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: data}), nil
+}
+
+// appendString appends data in the ssh wire format for strings: length prefixed
+func appendString(buf []byte, data []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
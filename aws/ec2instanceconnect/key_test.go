@@ -0,0 +1,138 @@
+package ec2instanceconnect
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wireReader reads values in the ssh wire format
+type wireReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (r *wireReader) uint32() uint32 {
+	if len(r.data) < 4 {
+		r.t.Fatalf("expected an uint32, got %d bytes", len(r.data))
+	}
+	value := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return value
+}
+
+func (r *wireReader) string() []byte {
+	length := r.uint32()
+	if uint32(len(r.data)) < length {
+		r.t.Fatalf("expected a string of %d bytes, got %d bytes", length, len(r.data))
+	}
+	value := r.data[:length]
+	r.data = r.data[length:]
+	return value
+}
+
+func TestPrivateKeyPEM(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := key.PrivateKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, rest := pem.Decode(encoded)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" || len(rest) != 0 {
+		t.Fatalf("expected a single OPENSSH PRIVATE KEY block, got %q", encoded)
+	}
+	magic := []byte("openssh-key-v1\x00")
+	if !bytes.HasPrefix(block.Bytes, magic) {
+		t.Fatalf("missing the openssh-key-v1 magic")
+	}
+	reader := &wireReader{t: t, data: block.Bytes[len(magic):]}
+	if cipher, kdf, kdfOptions := reader.string(), reader.string(), reader.string(); string(cipher) != "none" || string(kdf) != "none" || len(kdfOptions) != 0 {
+		t.Errorf("expected an unencrypted key, got cipher %q, kdf %q and kdf options %q", cipher, kdf, kdfOptions)
+	}
+	if keys := reader.uint32(); keys != 1 {
+		t.Fatalf("expected 1 key, got %d", keys)
+	}
+	publicWire := reader.string()
+	private := &wireReader{t: t, data: reader.string()}
+	if len(reader.data) != 0 {
+		t.Errorf("unexpected %d trailing bytes", len(reader.data))
+	}
+
+	authorizedKey := strings.Fields(key.AuthorizedKey())
+	if len(authorizedKey) != 2 || authorizedKey[0] != keyType {
+		t.Fatalf("unexpected authorized key %q", key.AuthorizedKey())
+	}
+	if base64.StdEncoding.EncodeToString(publicWire) != authorizedKey[1] {
+		t.Errorf("the public key of the private key file does not match the authorized key")
+	}
+
+	if len(private.data)%8 != 0 {
+		t.Errorf("expected the private section to be padded to 8 bytes, got %d bytes", len(private.data))
+	}
+	if first, second := private.uint32(), private.uint32(); first != second {
+		t.Errorf("check ints do not match: %d and %d", first, second)
+	}
+	if keyName := private.string(); string(keyName) != keyType {
+		t.Errorf("expected key type %q, got %q", keyType, keyName)
+	}
+	public := private.string()
+	privateKey := private.string()
+	private.string() // comment
+	for idx, padding := range private.data {
+		if padding != byte(idx+1) {
+			t.Fatalf("invalid padding %v", private.data)
+		}
+	}
+
+	// the key parsed back must be the one generated, and its private half must derive the public one
+	if !bytes.Equal(public, key.public) {
+		t.Errorf("public key was not encoded as generated")
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		t.Fatalf("expected a private key of %d bytes, got %d", ed25519.PrivateKeySize, len(privateKey))
+	}
+	derived := ed25519.NewKeyFromSeed(privateKey[:ed25519.SeedSize]).Public().(ed25519.PublicKey)
+	if !bytes.Equal(derived, key.public) {
+		t.Errorf("private key does not match the public key")
+	}
+}
+
+func TestPrivateKeyPEMWithSSHKeygen(t *testing.T) {
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := key.PrivateKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, encoded, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// ssh-keygen -y derives the public key from the private key file, as ssh -i would load it
+	output, err := exec.Command(sshKeygen, "-y", "-f", path).Output()
+	if err != nil {
+		t.Fatalf("ssh-keygen failed to read the private key: %v", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 || fields[0]+" "+fields[1] != key.AuthorizedKey() {
+		t.Errorf("expected public key %q, got %q", key.AuthorizedKey(), output)
+	}
+}
@@ -0,0 +1,80 @@
+package ec2instanceconnect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2instanceconnect"
+	log "github.com/sirupsen/logrus"
+)
+
+// SendSSHPublicKey authorizes the public key to log in to the instance as the given os user.
+// Keys pushed this way are only valid for 60 seconds, so connections must start right after
+func SendSSHPublicKey(ctx context.Context, cfg aws.Config, instanceId string, availabilityZone string, user string, publicKey string) error {
+	log.Debugf("Sending ssh public key for user %s to %s instance %s", user, cfg.Region, instanceId)
+	client := ec2instanceconnect.NewFromConfig(cfg)
+	result, err := client.SendSSHPublicKey(ctx, &ec2instanceconnect.SendSSHPublicKeyInput{
+		InstanceId:       aws.String(instanceId),
+		InstanceOSUser:   aws.String(user),
+		SSHPublicKey:     aws.String(publicKey),
+		AvailabilityZone: aws.String(availabilityZone),
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("instance connect did not accept the key (request %s)", aws.ToString(result.RequestId))
+	}
+	log.Infof("Sent ssh public key for user %s to instance %s", user, instanceId)
+	return nil
+}
+
+// PushEphemeralKey generates a new key pair, authorizes it for the user on the instance and
+// writes the private key to a temporary file, to be used with ssh -i. Call cleanup once the
+// connection is done to remove the key file
+func PushEphemeralKey(ctx context.Context, cfg aws.Config, instance *ec2Types.Instance, user string) (keyPath string, cleanup func(), err error) {
+	if user == "" {
+		return "", nil, fmt.Errorf("instance connect requires a user, pass one in with user@instance")
+	}
+	if instance.Placement == nil || instance.Placement.AvailabilityZone == nil {
+		return "", nil, fmt.Errorf("could not find the availability zone of instance %s", aws.ToString(instance.InstanceId))
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate ssh key: %w", err)
+	}
+	privateKey, err := key.PrivateKeyPEM()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode ssh key: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "awstool-ssh-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warnf("Failed to remove ephemeral ssh key at %s: %v", dir, err)
+		}
+	}
+	keyPath = filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, privateKey, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	err = SendSSHPublicKey(
+		ctx, cfg, aws.ToString(instance.InstanceId), aws.ToString(instance.Placement.AvailabilityZone),
+		user, key.AuthorizedKey(),
+	)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to send ssh public key: %w", err)
+	}
+	return keyPath, cleanup, nil
+}
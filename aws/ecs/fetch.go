@@ -0,0 +1,309 @@
+package ecs
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// list calls are paged with the maximum number of resources accepted by their describe calls, so
+// each page is described with a single request
+const (
+	clustersPageSize           = 100
+	servicesPageSize           = 10
+	tasksPageSize              = 100
+	containerInstancesPageSize = 100
+)
+
+type fetchOptions struct {
+	workloadsOnly bool
+}
+
+func newFetchOptions(opts ...FetchOption) fetchOptions {
+	options := fetchOptions{}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	return options
+}
+
+type FetchOption func(opt *fetchOptions)
+
+// WithWorkloadsOnly skips task definitions, which are loaded one family at a time and are not
+// needed to map services to their tasks and instances
+func WithWorkloadsOnly() FetchOption {
+	return func(opt *fetchOptions) {
+		opt.workloadsOnly = true
+	}
+}
+
+// WorkloadsOnly tells whether WithWorkloadsOnly is part of the fetch options
+func WorkloadsOnly(fetchOptions ...FetchOption) bool {
+	return newFetchOptions(fetchOptions...).workloadsOnly
+}
+
+func FetchAllClusters(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]ecsTypes.Cluster, error) {
+	log.Debugf("Fetching all %s ECS clusters", cfg.Region)
+
+	clusters := []ecsTypes.Cluster{}
+	client := ecs.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListClusters(ctx, &ecs.ListClustersInput{
+			MaxResults: aws.Int32(clustersPageSize),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(listResult.ClusterArns) == 0 {
+			return listResult.NextToken, nil
+		}
+		describeResult, err := client.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: listResult.ClusterArns,
+			Include:  []ecsTypes.ClusterField{ecsTypes.ClusterFieldTags, ecsTypes.ClusterFieldSettings},
+		})
+		if err != nil {
+			return nil, err
+		}
+		logFailures(describeResult.Failures)
+		clusters = append(clusters, describeResult.Clusters...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("ecs clusters", load)
+	if err != nil {
+		return clusters, err
+	}
+
+	log.Infof("Fetched %d %s ECS clusters", len(clusters), cfg.Region)
+	return clusters, nil
+}
+
+func FetchAllServices(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterArn string,
+) ([]ecsTypes.Service, error) {
+	log.Debugf("Fetching all %s ECS services for %s", cfg.Region, clusterArn)
+
+	services := []ecsTypes.Service{}
+	client := ecs.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListServices(ctx, &ecs.ListServicesInput{
+			Cluster:    &clusterArn,
+			MaxResults: aws.Int32(servicesPageSize),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(listResult.ServiceArns) == 0 {
+			return listResult.NextToken, nil
+		}
+		describeResult, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  &clusterArn,
+			Services: listResult.ServiceArns,
+			Include:  []ecsTypes.ServiceField{ecsTypes.ServiceFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+		logFailures(describeResult.Failures)
+		services = append(services, describeResult.Services...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("ecs services", load)
+	if err != nil {
+		return services, err
+	}
+
+	log.Debugf("Fetched %d %s ECS services for %s", len(services), cfg.Region, clusterArn)
+	return services, nil
+}
+
+// FetchAllTasks fetches the running tasks of a cluster. Stopped tasks are only kept by ECS for a
+// short while, so they are not loaded
+func FetchAllTasks(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterArn string,
+) ([]ecsTypes.Task, error) {
+	log.Debugf("Fetching all %s ECS tasks for %s", cfg.Region, clusterArn)
+
+	tasks := []ecsTypes.Task{}
+	client := ecs.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListTasks(ctx, &ecs.ListTasksInput{
+			Cluster:       &clusterArn,
+			DesiredStatus: ecsTypes.DesiredStatusRunning,
+			MaxResults:    aws.Int32(tasksPageSize),
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(listResult.TaskArns) == 0 {
+			return listResult.NextToken, nil
+		}
+		describeResult, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: &clusterArn,
+			Tasks:   listResult.TaskArns,
+			Include: []ecsTypes.TaskField{ecsTypes.TaskFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+		logFailures(describeResult.Failures)
+		for idx := range describeResult.Tasks {
+			if overrides := describeResult.Tasks[idx].Overrides; overrides != nil {
+				for _, container := range overrides.ContainerOverrides {
+					redactEnvironment(container.Environment)
+				}
+			}
+		}
+		tasks = append(tasks, describeResult.Tasks...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("ecs tasks", load)
+	if err != nil {
+		return tasks, err
+	}
+
+	log.Debugf("Fetched %d %s ECS tasks for %s", len(tasks), cfg.Region, clusterArn)
+	return tasks, nil
+}
+
+// FetchAllContainerInstances fetches the EC2 instances registered to a cluster, which tell the
+// instance each task with the EC2 launch type runs on
+func FetchAllContainerInstances(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterArn string,
+) ([]ecsTypes.ContainerInstance, error) {
+	log.Debugf("Fetching all %s ECS container instances for %s", cfg.Region, clusterArn)
+
+	instances := []ecsTypes.ContainerInstance{}
+	client := ecs.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListContainerInstances(ctx, &ecs.ListContainerInstancesInput{
+			Cluster:    &clusterArn,
+			MaxResults: aws.Int32(containerInstancesPageSize),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(listResult.ContainerInstanceArns) == 0 {
+			return listResult.NextToken, nil
+		}
+		describeResult, err := client.DescribeContainerInstances(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            &clusterArn,
+			ContainerInstances: listResult.ContainerInstanceArns,
+			Include:            []ecsTypes.ContainerInstanceField{ecsTypes.ContainerInstanceFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+		logFailures(describeResult.Failures)
+		instances = append(instances, describeResult.ContainerInstances...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("ecs container instances", load)
+	if err != nil {
+		return instances, err
+	}
+
+	log.Debugf("Fetched %d %s ECS container instances for %s", len(instances), cfg.Region, clusterArn)
+	return instances, nil
+}
+
+// ListAllTaskDefinitionFamilies lists the task definition families with at least one active
+// revision
+func ListAllTaskDefinitionFamilies(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]string, error) {
+	log.Debugf("Listing all %s ECS task definition families", cfg.Region)
+
+	families := []string{}
+	client := ecs.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListTaskDefinitionFamilies(ctx, &ecs.ListTaskDefinitionFamiliesInput{
+			Status:    ecsTypes.TaskDefinitionFamilyStatusActive,
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, listResult.Families...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("ecs task definition families", load)
+	if err != nil {
+		return families, err
+	}
+
+	log.Infof("Listed %d %s ECS task definition families", len(families), cfg.Region)
+	return families, nil
+}
+
+// FetchLatestTaskDefinition fetches the latest active revision of a task definition family,
+// along with its tags
+func FetchLatestTaskDefinition(
+	ctx context.Context,
+	cfg aws.Config,
+	family string,
+) (*ecsTypes.TaskDefinition, []ecsTypes.Tag, error) {
+	log.Debugf("Fetching %s ECS task definition %s", cfg.Region, family)
+
+	client := ecs.NewFromConfig(cfg)
+	result, err := client.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: &family,
+		Include:        []ecsTypes.TaskDefinitionField{ecsTypes.TaskDefinitionFieldTags},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("Fetched %s ECS task definition %s", cfg.Region, family)
+	if result.TaskDefinition != nil {
+		for _, container := range result.TaskDefinition.ContainerDefinitions {
+			redactEnvironment(container.Environment)
+		}
+	}
+	return result.TaskDefinition, result.Tags, nil
+}
+
+// redactEnvironment hides environment variable values, as done for Lambda functions. Variables
+// with no value are left as they are
+func redactEnvironment(environment []ecsTypes.KeyValuePair) {
+	for idx := range environment {
+		if environment[idx].Value != nil {
+			environment[idx].Value = aws.String(common.RedactedValue)
+		}
+	}
+}
+
+// logFailures logs resources that were listed but could not be described, which happens when
+// they are deleted in between, eg tasks that stopped
+func logFailures(failures []ecsTypes.Failure) {
+	for _, failure := range failures {
+		log.Debugf("Could not describe ECS resource %s: %s", aws.ToString(failure.Arn), aws.ToString(failure.Reason))
+	}
+}
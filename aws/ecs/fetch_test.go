@@ -0,0 +1,28 @@
+package ecs
+
+import (
+	"reflect"
+	"testing"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestRedactEnvironment(t *testing.T) {
+	environment := []ecsTypes.KeyValuePair{
+		{Name: aws.String("DB_PASSWORD"), Value: aws.String("hunter2")},
+		{Name: aws.String("EMPTY")},
+	}
+
+	redactEnvironment(environment)
+
+	expected := []ecsTypes.KeyValuePair{
+		{Name: aws.String("DB_PASSWORD"), Value: aws.String(common.RedactedValue)},
+		{Name: aws.String("EMPTY")},
+	}
+	if !reflect.DeepEqual(environment, expected) {
+		t.Errorf("expected environment %+v, got %+v", expected, environment)
+	}
+}
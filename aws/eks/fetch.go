@@ -0,0 +1,161 @@
+package eks
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	log "github.com/sirupsen/logrus"
+)
+
+func ListAllClusterNames(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]string, error) {
+	log.Debugf("Listing all %s EKS clusters", cfg.Region)
+
+	names := []string{}
+	client := eks.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListClusters(ctx, &eks.ListClustersInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, listResult.Clusters...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("eks clusters", load)
+	if err != nil {
+		return names, err
+	}
+
+	log.Infof("Listed %d %s EKS clusters", len(names), cfg.Region)
+	return names, nil
+}
+
+func FetchCluster(
+	ctx context.Context,
+	cfg aws.Config,
+	name string,
+) (*eksTypes.Cluster, error) {
+	log.Debugf("Fetching %s EKS cluster %s", cfg.Region, name)
+
+	client := eks.NewFromConfig(cfg)
+	result, err := client.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: &name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Fetched %s EKS cluster %s", cfg.Region, name)
+	return result.Cluster, nil
+}
+
+func ListAllNodegroupNames(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterName string,
+) ([]string, error) {
+	log.Debugf("Listing all %s EKS node groups for %s", cfg.Region, clusterName)
+
+	names := []string{}
+	client := eks.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListNodegroups(ctx, &eks.ListNodegroupsInput{
+			ClusterName: &clusterName,
+			NextToken:   nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, listResult.Nodegroups...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("eks node groups", load)
+	if err != nil {
+		return names, err
+	}
+
+	log.Debugf("Listed %d %s EKS node groups for %s", len(names), cfg.Region, clusterName)
+	return names, nil
+}
+
+func FetchNodegroup(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterName string,
+	name string,
+) (*eksTypes.Nodegroup, error) {
+	log.Debugf("Fetching %s EKS node group %s/%s", cfg.Region, clusterName, name)
+
+	client := eks.NewFromConfig(cfg)
+	result, err := client.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   &clusterName,
+		NodegroupName: &name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Fetched %s EKS node group %s/%s", cfg.Region, clusterName, name)
+	return result.Nodegroup, nil
+}
+
+func ListAllFargateProfileNames(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterName string,
+) ([]string, error) {
+	log.Debugf("Listing all %s EKS fargate profiles for %s", cfg.Region, clusterName)
+
+	names := []string{}
+	client := eks.NewFromConfig(cfg)
+
+	load := func(nextToken *string) (*string, error) {
+		listResult, err := client.ListFargateProfiles(ctx, &eks.ListFargateProfilesInput{
+			ClusterName: &clusterName,
+			NextToken:   nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, listResult.FargateProfileNames...)
+		return listResult.NextToken, nil
+	}
+
+	err := common.FetchAll("eks fargate profiles", load)
+	if err != nil {
+		return names, err
+	}
+
+	log.Debugf("Listed %d %s EKS fargate profiles for %s", len(names), cfg.Region, clusterName)
+	return names, nil
+}
+
+func FetchFargateProfile(
+	ctx context.Context,
+	cfg aws.Config,
+	clusterName string,
+	name string,
+) (*eksTypes.FargateProfile, error) {
+	log.Debugf("Fetching %s EKS fargate profile %s/%s", cfg.Region, clusterName, name)
+
+	client := eks.NewFromConfig(cfg)
+	result, err := client.DescribeFargateProfile(ctx, &eks.DescribeFargateProfileInput{
+		ClusterName:        &clusterName,
+		FargateProfileName: &name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Fetched %s EKS fargate profile %s/%s", cfg.Region, clusterName, name)
+	return result.FargateProfile, nil
+}
@@ -53,16 +53,7 @@ func ListAllDomainNames(
 		result[idx] = *domainName.DomainName
 	}
 
-	// filter out domains if specified
-	if len(opts.domains) > 0 {
-		filteredResult := []string{}
-		for _, domain := range result {
-			if _, ok := opts.domains[domain]; ok {
-				filteredResult = append(filteredResult, domain)
-			}
-		}
-		result = filteredResult
-	}
+	result = filterDomainNames(result, opts)
 
 	log.Infof(
 		"Listed %d %s Elasticsearch domain names",
@@ -72,6 +63,25 @@ func ListAllDomainNames(
 	return result, nil
 }
 
+// FilterDomainNames applies the same fetch options accepted by ListAllDomainNames to domain
+// names that were already listed, eg when working from a previous dump
+func FilterDomainNames(domains []string, fetchOptions ...FetchOption) []string {
+	return filterDomainNames(domains, newFetchOptions(fetchOptions...))
+}
+
+func filterDomainNames(domains []string, opts fetchOptions) []string {
+	if len(opts.domains) == 0 {
+		return domains
+	}
+	result := []string{}
+	for _, domain := range domains {
+		if _, ok := opts.domains[domain]; ok {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
 func FetchDomainStatus(
 	ctx context.Context,
 	cfg aws.Config,
@@ -0,0 +1,215 @@
+package lambda
+
+import (
+	"context"
+	"errors"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	log "github.com/sirupsen/logrus"
+)
+
+type fetchOptions struct {
+	functionsOnly bool
+}
+
+func newFetchOptions(opts ...FetchOption) fetchOptions {
+	options := fetchOptions{}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	return options
+}
+
+type FetchOption func(opt *fetchOptions)
+
+// WithFunctionsOnly only loads functions and their tags, skipping aliases, versions, event
+// source mappings and policies, which are slow to load and not needed to find functions
+func WithFunctionsOnly() FetchOption {
+	return func(opt *fetchOptions) {
+		opt.functionsOnly = true
+	}
+}
+
+// FunctionsOnly tells whether WithFunctionsOnly is part of the fetch options
+func FunctionsOnly(fetchOptions ...FetchOption) bool {
+	return newFetchOptions(fetchOptions...).functionsOnly
+}
+
+func FetchAllFunctions(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]lambdaTypes.FunctionConfiguration, error) {
+	log.Debugf("Fetching all %s Lambda functions", cfg.Region)
+
+	functions := []lambdaTypes.FunctionConfiguration{}
+	client := lambda.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		listResult, err := client.ListFunctions(ctx, &lambda.ListFunctionsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, listResult.Functions...)
+		return listResult.NextMarker, nil
+	}
+
+	err := common.FetchAll("lambda functions", load)
+	redactEnvironments(functions)
+	if err != nil {
+		return functions, err
+	}
+
+	log.Infof("Fetched %d %s Lambda functions", len(functions), cfg.Region)
+	return functions, nil
+}
+
+func FetchAllVersions(
+	ctx context.Context,
+	cfg aws.Config,
+	functionName string,
+) ([]lambdaTypes.FunctionConfiguration, error) {
+	log.Debugf("Fetching all %s Lambda versions for %s", cfg.Region, functionName)
+
+	versions := []lambdaTypes.FunctionConfiguration{}
+	client := lambda.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		listResult, err := client.ListVersionsByFunction(ctx, &lambda.ListVersionsByFunctionInput{
+			FunctionName: &functionName,
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, listResult.Versions...)
+		return listResult.NextMarker, nil
+	}
+
+	err := common.FetchAll("lambda versions", load)
+	redactEnvironments(versions)
+	if err != nil {
+		return versions, err
+	}
+
+	log.Debugf("Fetched %d %s Lambda versions for %s", len(versions), cfg.Region, functionName)
+	return versions, nil
+}
+
+func FetchAllAliases(
+	ctx context.Context,
+	cfg aws.Config,
+	functionName string,
+) ([]lambdaTypes.AliasConfiguration, error) {
+	log.Debugf("Fetching all %s Lambda aliases for %s", cfg.Region, functionName)
+
+	aliases := []lambdaTypes.AliasConfiguration{}
+	client := lambda.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		listResult, err := client.ListAliases(ctx, &lambda.ListAliasesInput{
+			FunctionName: &functionName,
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, listResult.Aliases...)
+		return listResult.NextMarker, nil
+	}
+
+	err := common.FetchAll("lambda aliases", load)
+	if err != nil {
+		return aliases, err
+	}
+
+	log.Debugf("Fetched %d %s Lambda aliases for %s", len(aliases), cfg.Region, functionName)
+	return aliases, nil
+}
+
+func FetchAllEventSourceMappings(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]lambdaTypes.EventSourceMappingConfiguration, error) {
+	log.Debugf("Fetching all %s Lambda event source mappings", cfg.Region)
+
+	mappings := []lambdaTypes.EventSourceMappingConfiguration{}
+	client := lambda.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		listResult, err := client.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, listResult.EventSourceMappings...)
+		return listResult.NextMarker, nil
+	}
+
+	err := common.FetchAll("lambda event source mappings", load)
+	if err != nil {
+		return mappings, err
+	}
+
+	log.Infof("Fetched %d %s Lambda event source mappings", len(mappings), cfg.Region)
+	return mappings, nil
+}
+
+// FetchPolicy fetches the resource policy of a function as a json document. Functions without a
+// policy return nil
+func FetchPolicy(
+	ctx context.Context,
+	cfg aws.Config,
+	functionName string,
+) (*string, error) {
+	log.Debugf("Fetching %s Lambda policy for %s", cfg.Region, functionName)
+
+	client := lambda.NewFromConfig(cfg)
+	result, err := client.GetPolicy(ctx, &lambda.GetPolicyInput{
+		FunctionName: &functionName,
+	})
+	if err != nil {
+		notFound := &lambdaTypes.ResourceNotFoundException{}
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	log.Debugf("Fetched %s Lambda policy for %s", cfg.Region, functionName)
+	return result.Policy, nil
+}
+
+func FetchTags(
+	ctx context.Context,
+	cfg aws.Config,
+	functionArn string,
+) (map[string]string, error) {
+	log.Debugf("Fetching %s Lambda tags for %s", cfg.Region, functionArn)
+
+	client := lambda.NewFromConfig(cfg)
+	result, err := client.ListTags(ctx, &lambda.ListTagsInput{
+		Resource: &functionArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Fetched %s Lambda tags for %s", cfg.Region, functionArn)
+	return result.Tags, nil
+}
+
+func redactEnvironments(functions []lambdaTypes.FunctionConfiguration) {
+	for _, function := range functions {
+		if function.Environment == nil {
+			continue
+		}
+		for name := range function.Environment.Variables {
+			function.Environment.Variables[name] = common.RedactedValue
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package lambda
+
+import (
+	"reflect"
+	"testing"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestRedactEnvironments(t *testing.T) {
+	functions := []lambdaTypes.FunctionConfiguration{
+		{
+			FunctionName: aws.String("api"),
+			Environment: &lambdaTypes.EnvironmentResponse{
+				Variables: map[string]string{"DB_PASSWORD": "hunter2", "STAGE": "prod"},
+			},
+		},
+		{FunctionName: aws.String("no-env")},
+	}
+
+	redactEnvironments(functions)
+
+	expected := map[string]string{"DB_PASSWORD": common.RedactedValue, "STAGE": common.RedactedValue}
+	if variables := functions[0].Environment.Variables; !reflect.DeepEqual(variables, expected) {
+		t.Errorf("expected variables %v, got %v", expected, variables)
+	}
+	if functions[1].Environment != nil {
+		t.Errorf("expected functions with no environment to be left alone, got %+v", functions[1].Environment)
+	}
+}
@@ -35,7 +35,9 @@ func FetchAllAccounts(ctx context.Context, cfg aws.Config) ([]orgTypes.Account,
 		accounts = append(accounts, result.Accounts...)
 		return result.NextToken, nil
 	}
-	common.FetchAll("accounts", load)
+	if err := common.FetchAll("accounts", load); err != nil {
+		return nil, err
+	}
 	log.Infof("Fetched %d AWS accounts", len(accounts))
 	return accounts, nil
 }
@@ -0,0 +1,313 @@
+package rds
+
+import (
+	"context"
+
+	"awstool/common"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	log "github.com/sirupsen/logrus"
+)
+
+type fetchOptions struct {
+	identifiers   map[string]struct{}
+	databasesOnly bool
+}
+
+func newFetchOptions(opts ...FetchOption) fetchOptions {
+	options := fetchOptions{
+		identifiers: map[string]struct{}{},
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	return options
+}
+
+type FetchOption func(opt *fetchOptions)
+
+// WithIdentifiers only keeps the DB instances and clusters with these identifiers. Instances
+// are also kept when their cluster is one of the identifiers
+func WithIdentifiers(identifiers ...string) FetchOption {
+	return func(opt *fetchOptions) {
+		for _, identifier := range identifiers {
+			opt.identifiers[identifier] = struct{}{}
+		}
+	}
+}
+
+// WithDatabasesOnly skips snapshots, parameter groups and subnet groups, which are slow to load
+// and not needed to find databases
+func WithDatabasesOnly() FetchOption {
+	return func(opt *fetchOptions) {
+		opt.databasesOnly = true
+	}
+}
+
+// DatabasesOnly tells whether WithDatabasesOnly is part of the fetch options
+func DatabasesOnly(fetchOptions ...FetchOption) bool {
+	return newFetchOptions(fetchOptions...).databasesOnly
+}
+
+func FetchAllInstances(
+	ctx context.Context,
+	cfg aws.Config,
+	fetchOptions ...FetchOption,
+) ([]rdsTypes.DBInstance, error) {
+	log.Debugf("Fetching all %s RDS DB instances", cfg.Region)
+
+	instances := []rdsTypes.DBInstance{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, describeResult.DBInstances...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db instances", load)
+	if err != nil {
+		return instances, err
+	}
+	instances = FilterInstances(instances, fetchOptions...)
+
+	log.Infof("Fetched %d %s RDS DB instances", len(instances), cfg.Region)
+	return instances, nil
+}
+
+func FetchAllClusters(
+	ctx context.Context,
+	cfg aws.Config,
+	fetchOptions ...FetchOption,
+) ([]rdsTypes.DBCluster, error) {
+	log.Debugf("Fetching all %s RDS DB clusters", cfg.Region)
+
+	clusters := []rdsTypes.DBCluster{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, describeResult.DBClusters...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db clusters", load)
+	if err != nil {
+		return clusters, err
+	}
+	clusters = FilterClusters(clusters, fetchOptions...)
+
+	log.Infof("Fetched %d %s RDS DB clusters", len(clusters), cfg.Region)
+	return clusters, nil
+}
+
+// FilterInstances applies the same fetch options accepted by FetchAllInstances to instances
+// that were already fetched, eg when working from a previous dump
+func FilterInstances(instances []rdsTypes.DBInstance, fetchOptions ...FetchOption) []rdsTypes.DBInstance {
+	opts := newFetchOptions(fetchOptions...)
+	if len(opts.identifiers) == 0 {
+		return instances
+	}
+	result := []rdsTypes.DBInstance{}
+	for _, instance := range instances {
+		_, instanceMatches := opts.identifiers[aws.ToString(instance.DBInstanceIdentifier)]
+		_, clusterMatches := opts.identifiers[aws.ToString(instance.DBClusterIdentifier)]
+		if instanceMatches || clusterMatches {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// FilterClusters applies the same fetch options accepted by FetchAllClusters to clusters that
+// were already fetched, eg when working from a previous dump
+func FilterClusters(clusters []rdsTypes.DBCluster, fetchOptions ...FetchOption) []rdsTypes.DBCluster {
+	opts := newFetchOptions(fetchOptions...)
+	if len(opts.identifiers) == 0 {
+		return clusters
+	}
+	result := []rdsTypes.DBCluster{}
+	for _, cluster := range clusters {
+		if _, ok := opts.identifiers[aws.ToString(cluster.DBClusterIdentifier)]; ok {
+			result = append(result, cluster)
+		}
+	}
+	return result
+}
+
+func FetchAllSnapshots(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]rdsTypes.DBSnapshot, error) {
+	log.Debugf("Fetching all %s RDS DB snapshots", cfg.Region)
+
+	snapshots := []rdsTypes.DBSnapshot{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBSnapshots(ctx, &rds.DescribeDBSnapshotsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, describeResult.DBSnapshots...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db snapshots", load)
+	if err != nil {
+		return snapshots, err
+	}
+
+	log.Infof("Fetched %d %s RDS DB snapshots", len(snapshots), cfg.Region)
+	return snapshots, nil
+}
+
+func FetchAllClusterSnapshots(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]rdsTypes.DBClusterSnapshot, error) {
+	log.Debugf("Fetching all %s RDS DB cluster snapshots", cfg.Region)
+
+	snapshots := []rdsTypes.DBClusterSnapshot{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBClusterSnapshots(ctx, &rds.DescribeDBClusterSnapshotsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, describeResult.DBClusterSnapshots...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db cluster snapshots", load)
+	if err != nil {
+		return snapshots, err
+	}
+
+	log.Infof("Fetched %d %s RDS DB cluster snapshots", len(snapshots), cfg.Region)
+	return snapshots, nil
+}
+
+func FetchAllParameterGroups(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]rdsTypes.DBParameterGroup, error) {
+	log.Debugf("Fetching all %s RDS DB parameter groups", cfg.Region)
+
+	groups := []rdsTypes.DBParameterGroup{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBParameterGroups(ctx, &rds.DescribeDBParameterGroupsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, describeResult.DBParameterGroups...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db parameter groups", load)
+	if err != nil {
+		return groups, err
+	}
+
+	log.Infof("Fetched %d %s RDS DB parameter groups", len(groups), cfg.Region)
+	return groups, nil
+}
+
+func FetchAllClusterParameterGroups(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]rdsTypes.DBClusterParameterGroup, error) {
+	log.Debugf("Fetching all %s RDS DB cluster parameter groups", cfg.Region)
+
+	groups := []rdsTypes.DBClusterParameterGroup{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBClusterParameterGroups(ctx, &rds.DescribeDBClusterParameterGroupsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, describeResult.DBClusterParameterGroups...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db cluster parameter groups", load)
+	if err != nil {
+		return groups, err
+	}
+
+	log.Infof("Fetched %d %s RDS DB cluster parameter groups", len(groups), cfg.Region)
+	return groups, nil
+}
+
+func FetchAllSubnetGroups(
+	ctx context.Context,
+	cfg aws.Config,
+) ([]rdsTypes.DBSubnetGroup, error) {
+	log.Debugf("Fetching all %s RDS DB subnet groups", cfg.Region)
+
+	groups := []rdsTypes.DBSubnetGroup{}
+	client := rds.NewFromConfig(cfg)
+
+	load := func(marker *string) (*string, error) {
+		describeResult, err := client.DescribeDBSubnetGroups(ctx, &rds.DescribeDBSubnetGroupsInput{
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, describeResult.DBSubnetGroups...)
+		return describeResult.Marker, nil
+	}
+
+	err := common.FetchAll("rds db subnet groups", load)
+	if err != nil {
+		return groups, err
+	}
+
+	log.Infof("Fetched %d %s RDS DB subnet groups", len(groups), cfg.Region)
+	return groups, nil
+}
+
+// FetchTags fetches the tags of an RDS resource. Only needed for resources that are not
+// described along with their tags, like parameter and subnet groups
+func FetchTags(
+	ctx context.Context,
+	cfg aws.Config,
+	arn string,
+) ([]rdsTypes.Tag, error) {
+	log.Debugf("Fetching %s RDS tags for %s", cfg.Region, arn)
+
+	client := rds.NewFromConfig(cfg)
+	result, err := client.ListTagsForResource(ctx, &rds.ListTagsForResourceInput{
+		ResourceName: &arn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Fetched %s RDS tags for %s", cfg.Region, arn)
+	return result.TagList, nil
+}
@@ -2,6 +2,9 @@ package s3
 
 import (
 	"context"
+	"io"
+
+	"awstool/common"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -36,3 +39,97 @@ func FetchBucketTags(ctx context.Context, cfg aws.Config, bucket string) ([]s3Ty
 	log.Debugf("Fetched %d tags for %s S3 bucket %s", len(result.TagSet), cfg.Region, bucket)
 	return result.TagSet, nil
 }
+
+// ObjectsBatchFn is called for every page of objects listed from a bucket. Returning an error
+// stops the listing
+type ObjectsBatchFn = func(objects []s3Types.Object) error
+
+// ObjectVersionsBatchFn is called for every page of object versions listed from a bucket.
+// Versions of the same key are always passed in order, from the latest to the oldest,
+// but they may span more than a single page. Returning an error stops the listing
+type ObjectVersionsBatchFn = func(versions []s3Types.ObjectVersion) error
+
+func ListAllObjects(ctx context.Context, cfg aws.Config, bucket string, fn ObjectsBatchFn) error {
+	log.Debugf("Listing all objects in %s S3 bucket %s", cfg.Region, bucket)
+	client := s3.NewFromConfig(cfg)
+	numObjects := 0
+	load := func(nextToken *string) (*string, error) {
+		result, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &bucket,
+			ContinuationToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		numObjects += len(result.Contents)
+		if err := fn(result.Contents); err != nil {
+			return nil, err
+		}
+		if !result.IsTruncated {
+			return nil, nil
+		}
+		return result.NextContinuationToken, nil
+	}
+	if err := common.FetchAll("objects", load); err != nil {
+		return err
+	}
+	log.Infof("Listed %d objects in %s S3 bucket %s", numObjects, cfg.Region, bucket)
+	return nil
+}
+
+func ListAllObjectVersions(ctx context.Context, cfg aws.Config, bucket string, fn ObjectVersionsBatchFn) error {
+	log.Debugf("Listing all object versions in %s S3 bucket %s", cfg.Region, bucket)
+	client := s3.NewFromConfig(cfg)
+	numVersions := 0
+	// ListObjectVersions paginates with two markers. The key marker is used as the
+	// pagination token while the version id marker is carried along in this closure
+	var versionIdMarker *string
+	load := func(keyMarker *string) (*string, error) {
+		result, err := client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:          &bucket,
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIdMarker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		numVersions += len(result.Versions)
+		if err := fn(result.Versions); err != nil {
+			return nil, err
+		}
+		if !result.IsTruncated {
+			return nil, nil
+		}
+		versionIdMarker = result.NextVersionIdMarker
+		return result.NextKeyMarker, nil
+	}
+	if err := common.FetchAll("object versions", load); err != nil {
+		return err
+	}
+	log.Infof("Listed %d object versions in %s S3 bucket %s", numVersions, cfg.Region, bucket)
+	return nil
+}
+
+// DownloadObject writes the contents of the given object to w, returning how many bytes were
+// written. If versionId is nil, the latest version is downloaded
+func DownloadObject(
+	ctx context.Context,
+	cfg aws.Config,
+	bucket string,
+	key string,
+	versionId *string,
+	w io.Writer,
+) (int64, error) {
+	log.Tracef("Downloading %s S3 object s3://%s/%s (version %s)", cfg.Region, bucket, key, aws.ToString(versionId))
+	client := s3.NewFromConfig(cfg)
+	result, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: versionId,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer result.Body.Close()
+	return io.Copy(w, result.Body)
+}
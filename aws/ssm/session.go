@@ -0,0 +1,110 @@
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	log "github.com/sirupsen/logrus"
+)
+
+// PluginBinary is the AWS session manager plugin, which implements the session manager
+// protocol. It is installed alongside the aws cli, see
+// https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html
+const PluginBinary = "session-manager-plugin"
+
+// Documents used to start sessions
+const (
+	// SSHDocument tunnels a connection to the ssh port of the instance, to be used as an ssh ProxyCommand
+	SSHDocument = "AWS-StartSSHSession"
+	// PortForwardingDocument forwards a local port to a port of the instance
+	PortForwardingDocument = "AWS-StartPortForwardingSession"
+	// RemotePortForwardingDocument forwards a local port to a host and port reachable from the instance
+	RemotePortForwardingDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+)
+
+// CheckPlugin fails if the session manager plugin is not installed
+func CheckPlugin() error {
+	if _, err := exec.LookPath(PluginBinary); err != nil {
+		return fmt.Errorf(
+			"%s is required for ssm sessions but was not found in PATH. Install it following "+
+				"https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html",
+			PluginBinary,
+		)
+	}
+	return nil
+}
+
+// StartSession starts a session manager session to the instance with the given document and
+// parameters, and hands it to the session manager plugin, which streams the session over
+// stdin and stdout until it ends. The session is terminated once the plugin exits
+func StartSession(ctx context.Context, cfg aws.Config, instanceId string, document string, parameters map[string][]string) error {
+	if err := CheckPlugin(); err != nil {
+		return err
+	}
+
+	input := ssm.StartSessionInput{
+		Target:     aws.String(instanceId),
+		Parameters: parameters,
+	}
+	if document != "" {
+		input.DocumentName = aws.String(document)
+	}
+
+	log.Debugf("Starting %s ssm session to %s with document %q", cfg.Region, instanceId, document)
+	client := ssm.NewFromConfig(cfg)
+	session, err := client.StartSession(ctx, &input)
+	if err != nil {
+		return fmt.Errorf("failed to start ssm session to %s: %w", instanceId, err)
+	}
+	log.Infof("Started ssm session %s to %s", aws.ToString(session.SessionId), instanceId)
+
+	defer func() {
+		// the plugin usually terminates the session itself, so failures here are expected
+		_, err := client.TerminateSession(context.Background(), &ssm.TerminateSessionInput{SessionId: session.SessionId})
+		if err != nil {
+			log.Debugf("Failed to terminate ssm session %s: %v", aws.ToString(session.SessionId), err)
+		}
+	}()
+
+	sessionJSON, err := json.Marshal(map[string]string{
+		"SessionId":  aws.ToString(session.SessionId),
+		"TokenValue": aws.ToString(session.TokenValue),
+		"StreamUrl":  aws.ToString(session.StreamUrl),
+	})
+	if err != nil {
+		return err
+	}
+	inputJSON, err := json.Marshal(map[string]interface{}{
+		"Target":       instanceId,
+		"DocumentName": document,
+		"Parameters":   parameters,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint, ok := awst.OverriddenEndpoint(cfg, ssm.ServiceID)
+	if !ok {
+		endpoint = fmt.Sprintf("https://ssm.%s.amazonaws.com", cfg.Region)
+	}
+
+	// same arguments the aws cli passes in to the plugin
+	plugin := exec.CommandContext(
+		ctx, PluginBinary,
+		string(sessionJSON), cfg.Region, "StartSession", "", string(inputJSON), endpoint,
+	)
+	plugin.Stdin = os.Stdin
+	plugin.Stdout = os.Stdout
+	plugin.Stderr = os.Stderr
+	if err := plugin.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", PluginBinary, err)
+	}
+	return nil
+}
@@ -3,21 +3,31 @@ package aws
 import (
 	"awstool/aws/iam"
 
+	asTypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	ebtTypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
 	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
 	elbv2Types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	esTypes "github.com/aws/aws-sdk-go-v2/service/elasticsearchservice/types"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	opswTypes "github.com/aws/aws-sdk-go-v2/service/opsworks/types"
 	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
 	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// AWS holds data loaded from a single account. Services lists the services that were selected
+// for loading, which tells a service with no resources apart from one that was not loaded. It is
+// nil in dumps generated before it was recorded
 type AWS struct {
 	Organization *orgTypes.Organization
 	Accounts     map[string]orgTypes.Account
 	Regions      map[string]Region
 	IAM          iam.IAM
+	Services     []string
+	Failures     []Failure
 }
 
 func New() AWS {
@@ -25,9 +35,41 @@ func New() AWS {
 		Accounts: map[string]orgTypes.Account{},
 		Regions:  map[string]Region{},
 		IAM:      iam.New(),
+		Failures: []Failure{},
 	}
 }
 
+// Failure describes data that could not be loaded. Region is empty for global services, and
+// Operation and ErrorCode are empty when the failure did not come from an AWS API call
+type Failure struct {
+	Service    string
+	Region     string
+	Operation  string
+	ErrorClass string
+	ErrorCode  string
+	Message    string
+}
+
+// Accounts holds data loaded from multiple accounts of an organization, keyed by account id
+type Accounts struct {
+	Organization *orgTypes.Organization
+	Accounts     map[string]*Account
+}
+
+func NewAccounts() Accounts {
+	return Accounts{
+		Accounts: map[string]*Account{},
+	}
+}
+
+// Account holds the data loaded from a single account. If loading the account failed, AWS
+// is nil and Errors describes why
+type Account struct {
+	Account orgTypes.Account
+	AWS     *AWS
+	Errors  []string
+}
+
 type Region struct {
 	Region           string
 	EC2              EC2
@@ -36,6 +78,11 @@ type Region struct {
 	Opsworks         Opsworks
 	ElasticBeanstalk ElasticBeanstalk
 	Elasticsearch    Elasticsearch
+	RDS              RDS
+	Lambda           Lambda
+	ECS              ECS
+	EKS              EKS
+	AutoScaling      AutoScaling
 }
 
 func NewRegion(region string) Region {
@@ -47,21 +94,58 @@ func NewRegion(region string) Region {
 		Opsworks:         NewOpsworks(),
 		ElasticBeanstalk: NewElasticBeanstalk(),
 		Elasticsearch:    NewElasticsearch(),
+		RDS:              NewRDS(),
+		Lambda:           NewLambda(),
+		ECS:              NewECS(),
+		EKS:              NewEKS(),
+		AutoScaling:      NewAutoScaling(),
 	}
 }
 
 type EC2 struct {
-	Reservations []ec2Types.Reservation
-	Volumes      []ec2Types.Volume
+	Reservations          []ec2Types.Reservation
+	Volumes               []ec2Types.Volume
+	Vpcs                  []ec2Types.Vpc
+	Subnets               []ec2Types.Subnet
+	RouteTables           []ec2Types.RouteTable
+	SecurityGroups        []ec2Types.SecurityGroup
+	NetworkAcls           []ec2Types.NetworkAcl
+	NatGateways           []ec2Types.NatGateway
+	InternetGateways      []ec2Types.InternetGateway
+	VpcPeeringConnections []ec2Types.VpcPeeringConnection
+	VpcEndpoints          []ec2Types.VpcEndpoint
+	NetworkInterfaces     []ec2Types.NetworkInterface
+	Addresses             []ec2Types.Address
+	LaunchTemplates       []LaunchTemplate
 }
 
 func NewEC2() EC2 {
 	return EC2{
-		Reservations: []ec2Types.Reservation{},
-		Volumes:      []ec2Types.Volume{},
+		Reservations:          []ec2Types.Reservation{},
+		Volumes:               []ec2Types.Volume{},
+		Vpcs:                  []ec2Types.Vpc{},
+		Subnets:               []ec2Types.Subnet{},
+		RouteTables:           []ec2Types.RouteTable{},
+		SecurityGroups:        []ec2Types.SecurityGroup{},
+		NetworkAcls:           []ec2Types.NetworkAcl{},
+		NatGateways:           []ec2Types.NatGateway{},
+		InternetGateways:      []ec2Types.InternetGateway{},
+		VpcPeeringConnections: []ec2Types.VpcPeeringConnection{},
+		VpcEndpoints:          []ec2Types.VpcEndpoint{},
+		NetworkInterfaces:     []ec2Types.NetworkInterface{},
+		Addresses:             []ec2Types.Address{},
+		LaunchTemplates:       []LaunchTemplate{},
 	}
 }
 
+// LaunchTemplate holds a launch template along with its default and latest versions, which are
+// the same version unless a newer one was created without being made the default
+type LaunchTemplate struct {
+	Template       ec2Types.LaunchTemplate
+	DefaultVersion *ec2Types.LaunchTemplateVersion
+	LatestVersion  *ec2Types.LaunchTemplateVersion
+}
+
 type ELB struct {
 	V1 ELBv1
 	V2 ELBv2
@@ -141,3 +225,110 @@ func NewElasticsearch() Elasticsearch {
 		Domains: map[string]*ElasticsearchDomain{},
 	}
 }
+
+// RDS holds DB instances and Aurora clusters along with their snapshots and groups. Parameter and
+// subnet groups are not described with their tags, so their tags are kept in Tags, keyed by ARN
+type RDS struct {
+	Instances              []rdsTypes.DBInstance
+	Clusters               []rdsTypes.DBCluster
+	Snapshots              []rdsTypes.DBSnapshot
+	ClusterSnapshots       []rdsTypes.DBClusterSnapshot
+	ParameterGroups        []rdsTypes.DBParameterGroup
+	ClusterParameterGroups []rdsTypes.DBClusterParameterGroup
+	SubnetGroups           []rdsTypes.DBSubnetGroup
+	Tags                   map[string][]rdsTypes.Tag
+}
+
+func NewRDS() RDS {
+	return RDS{
+		Instances:              []rdsTypes.DBInstance{},
+		Clusters:               []rdsTypes.DBCluster{},
+		Snapshots:              []rdsTypes.DBSnapshot{},
+		ClusterSnapshots:       []rdsTypes.DBClusterSnapshot{},
+		ParameterGroups:        []rdsTypes.DBParameterGroup{},
+		ClusterParameterGroups: []rdsTypes.DBClusterParameterGroup{},
+		SubnetGroups:           []rdsTypes.DBSubnetGroup{},
+		Tags:                   map[string][]rdsTypes.Tag{},
+	}
+}
+
+// LambdaFunction holds a function along with its versions, aliases, resource policy and tags.
+// Values of environment variables are never loaded, only their names
+type LambdaFunction struct {
+	Configuration *lambdaTypes.FunctionConfiguration
+	Versions      []lambdaTypes.FunctionConfiguration
+	Aliases       []lambdaTypes.AliasConfiguration
+	Policy        *string
+	Tags          map[string]string
+}
+
+type Lambda struct {
+	Functions           map[string]*LambdaFunction
+	EventSourceMappings []lambdaTypes.EventSourceMappingConfiguration
+}
+
+func NewLambda() Lambda {
+	return Lambda{
+		Functions:           map[string]*LambdaFunction{},
+		EventSourceMappings: []lambdaTypes.EventSourceMappingConfiguration{},
+	}
+}
+
+// ECSCluster holds a cluster along with its services, running tasks and the EC2 instances
+// registered to it
+type ECSCluster struct {
+	Cluster            *ecsTypes.Cluster
+	Services           []ecsTypes.Service
+	Tasks              []ecsTypes.Task
+	ContainerInstances []ecsTypes.ContainerInstance
+}
+
+// ECS holds clusters keyed by name and the latest active revision of each task definition
+// family. The tags of task definitions are not part of them, so they are kept in
+// TaskDefinitionTags, keyed by ARN
+type ECS struct {
+	Clusters           map[string]*ECSCluster
+	TaskDefinitions    []ecsTypes.TaskDefinition
+	TaskDefinitionTags map[string][]ecsTypes.Tag
+}
+
+func NewECS() ECS {
+	return ECS{
+		Clusters:           map[string]*ECSCluster{},
+		TaskDefinitions:    []ecsTypes.TaskDefinition{},
+		TaskDefinitionTags: map[string][]ecsTypes.Tag{},
+	}
+}
+
+// EKSCluster holds a cluster along with its managed node groups and fargate profiles
+type EKSCluster struct {
+	Cluster         *eksTypes.Cluster
+	Nodegroups      []eksTypes.Nodegroup
+	FargateProfiles []eksTypes.FargateProfile
+}
+
+type EKS struct {
+	Clusters map[string]*EKSCluster
+}
+
+func NewEKS() EKS {
+	return EKS{
+		Clusters: map[string]*EKSCluster{},
+	}
+}
+
+type AutoScaling struct {
+	Groups               []asTypes.AutoScalingGroup
+	LaunchConfigurations []asTypes.LaunchConfiguration
+	Policies             []asTypes.ScalingPolicy
+	ScheduledActions     []asTypes.ScheduledUpdateGroupAction
+}
+
+func NewAutoScaling() AutoScaling {
+	return AutoScaling{
+		Groups:               []asTypes.AutoScalingGroup{},
+		LaunchConfigurations: []asTypes.LaunchConfiguration{},
+		Policies:             []asTypes.ScalingPolicy{},
+		ScheduledActions:     []asTypes.ScheduledUpdateGroupAction{},
+	}
+}
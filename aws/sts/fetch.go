@@ -0,0 +1,21 @@
+package sts
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	log "github.com/sirupsen/logrus"
+)
+
+// FetchAccountId returns the id of the account the credentials in the config belong to
+func FetchAccountId(ctx context.Context, cfg aws.Config) (string, error) {
+	log.Debug("Fetching caller identity")
+	client := sts.NewFromConfig(cfg)
+	result, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	log.Debugf("Fetched caller identity: account %s", aws.ToString(result.Account))
+	return aws.ToString(result.Account), nil
+}
@@ -8,33 +8,52 @@ package awstool
 import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2instanceconnect"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	"github.com/aws/aws-sdk-go-v2/service/elasticsearchservice"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/opsworks"
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/semaphore"
+	_ "modernc.org/sqlite"
 )
 
 var _ aws.HTTPClient
+var _ autoscaling.Client
 var _ cobra.Command
 var _ config.Config
 var _ ec2.Client
+var _ ec2instanceconnect.Client
+var _ ecs.Client
+var _ eks.Client
 var _ elasticbeanstalk.Client
 var _ elasticloadbalancing.Client
 var _ elasticloadbalancingv2.Client
 var _ elasticsearchservice.Client
 var _ iam.Client
+var _ lambda.Client
 var _ logrus.Level
 var _ opsworks.Client
 var _ organizations.Client
+var _ rds.Client
 var _ s3.Client
+var _ ssm.Client
+var _ stscreds.AssumeRoleProvider
+var _ sts.Client
 var _ semaphore.Weighted
 var _ spew.ConfigState
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	awstcmd "awstool/cmd"
+	"awstool/diff"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+// exit codes follow the diff(1) convention. Usage errors exit with failureExitCode too, so that
+// they can not be mistaken for changes
+const changesFoundExitCode = 1
+const failureExitCode = 2
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "diff OLD_DUMP NEW_DUMP",
+		Short: "compares two dumps and reports added, removed and modified resources",
+		Long: "Compares two json files generated by the dump command and reports which resources were added, " +
+			"removed or modified between them, including field level changes for modified resources. " +
+			"Resources are matched by their identity (eg instance id, volume id, load balancer arn, bucket name). " +
+			"Regions and services missing from one of the dumps, or that failed to load in either of them, are " +
+			"reported as not compared instead of having all their resources reported as added or removed. " +
+			"Exits with 0 when no changes are found, 1 when changes are found and 2 on failures, including usage errors",
+		SilenceErrors: true,
+	}
+
+	// failures of the root command setup, eg an invalid profile, must not read as changes found
+	awstcmd.SetFailureExitCode(&cmd, failureExitCode)
+
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return &exitErr{code: failureExitCode, err: err}
+		}
+		return nil
+	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &exitErr{code: failureExitCode, err: err}
+	})
+
+	var jsonOutput bool
+	var ignoreFields []string
+
+	cmd.Flags().BoolVarP(
+		&jsonOutput, "json", "j", false,
+		"Print the differences as json instead of human readable text",
+	)
+
+	cmd.Flags().StringSliceVarP(
+		&ignoreFields, "ignore-fields", "I", []string{},
+		"Ignore changes to fields matching those patterns. Patterns match the field path as printed "+
+			"by this command and accept wildcards, eg --ignore-fields PasswordLastUsed,Tags.LastDeploy",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		before, err := loader.LoadDumpFile(args[0])
+		if err != nil {
+			return &exitErr{code: failureExitCode, err: err}
+		}
+		after, err := loader.LoadDumpFile(args[1])
+		if err != nil {
+			return &exitErr{code: failureExitCode, err: err}
+		}
+
+		result, err := diff.Diff(before, after, diff.WithIgnoredFields(ignoreFields...))
+		if err != nil {
+			return &exitErr{code: failureExitCode, err: err}
+		}
+
+		if jsonOutput {
+			if err := printJSON(result); err != nil {
+				return &exitErr{code: failureExitCode, err: err}
+			}
+		} else {
+			printText(result)
+		}
+
+		if result.HasChanges() {
+			return &exitErr{code: changesFoundExitCode}
+		}
+		return nil
+	}
+
+	return &cmd
+}
+
+type exitErr struct {
+	code int
+	err  error
+}
+
+func (e *exitErr) ExitCode() int {
+	return e.code
+}
+
+// Error returns an empty message when changes are found, as this is an expected outcome
+// signaled only through the exit code
+func (e *exitErr) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+func (e *exitErr) Unwrap() error {
+	return e.err
+}
+
+func printJSON(result *diff.Result) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func printText(result *diff.Result) {
+	symbols := map[string]string{
+		diff.Added:    "+",
+		diff.Removed:  "-",
+		diff.Modified: "~",
+	}
+	for _, resource := range result.Resources {
+		region := resource.Region
+		if region == "" {
+			region = "global"
+		}
+		fmt.Printf("%s %s %s %s\n", symbols[resource.Status], resource.Type, region, resource.Id)
+		for _, change := range resource.Changes {
+			fmt.Printf("    %s: %s -> %s\n", change.Path, valueString(change.Old), valueString(change.New))
+		}
+	}
+	for _, skipped := range result.NotCompared {
+		scope := []string{}
+		if skipped.Service != "" {
+			scope = append(scope, "service "+skipped.Service)
+		}
+		if skipped.Region != "" {
+			scope = append(scope, "region "+skipped.Region)
+		}
+		fmt.Printf("! not compared: %s (%s)\n", strings.Join(scope, " in "), skipped.Reason)
+	}
+}
+
+func valueString(value interface{}) string {
+	if value == nil {
+		return "<N/A>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -6,19 +6,34 @@ import (
 	"fmt"
 	"os"
 
+	"awstool/aws/sts"
 	"awstool/loader"
+	"awstool/sqlite"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// partialResultsExitCode is used when the dump was generated but some data failed to be loaded
+const partialResultsExitCode = 3
+
+const jsonFormat = "json"
+const ndjsonFormat = "ndjson"
+
 func Command(awsCfg **aws.Config) *cobra.Command {
 	var regions []string
 	var excludeRegions []string
 	var services []string
 	var excludeServices []string
 	var listServicesOnly bool
+	var assumeRole string
+	var accounts []string
+	var excludeAccounts []string
+	var accountParallelism int
+	var bestEffort bool
+	var outputFormat string
+	var sqliteFile string
 
 	cmd := cobra.Command{
 		Use:           "dump",
@@ -50,12 +65,79 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		"List which services can be dumped and exit. Those are inputs for the --services and --exclude-services flags",
 	)
 
+	cmd.PersistentFlags().StringVar(
+		&assumeRole, "assume-role", "",
+		"Dump all accounts of the organization instead of only the current one. For each account the "+
+			"role with this name is assumed (eg OrganizationAccountAccessRole) and the dump is keyed by account id. "+
+			"Accounts that fail to be dumped have their errors reported in the output instead of failing the "+
+			"whole dump. See also --accounts and --exclude-accounts",
+	)
+	cmd.PersistentFlags().StringSliceVarP(
+		&accounts, "accounts", "a", []string{},
+		"When --assume-role is used, dump only those accounts, identified by id or name. "+
+			"If not specified, all active accounts will be dumped",
+	)
+	cmd.PersistentFlags().StringSliceVarP(
+		&excludeAccounts, "exclude-accounts", "A", []string{},
+		"When --assume-role is used, do not dump those accounts, identified by id or name. "+
+			"This takes precedence over --accounts",
+	)
+	cmd.PersistentFlags().IntVar(
+		&accountParallelism, "account-parallelism", loader.DefaultAccountParallelism,
+		"When --assume-role is used, how many accounts are dumped at once. Each account already loads its "+
+			"regions and services in parallel, so raising this mostly leads to throttling",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&bestEffort, "best-effort", false,
+		"By default the dump fails if any data fails to be loaded. With this flag the dump is still generated "+
+			"with all data that could be loaded, and what failed (service, region, api operation and error class, "+
+			"eg AccessDenied or Throttled) is listed in the Failures field of the output. The command then exits "+
+			"with code 3",
+	)
+
+	cmd.PersistentFlags().StringVarP(
+		&outputFormat, "output-format", "f", jsonFormat,
+		"Either json, to output a single json document once all data is loaded, or ndjson, to stream one json "+
+			"document per line for each resource as soon as it is loaded. Each line of ndjson output has the "+
+			"Account, Region, Service and Type of the resource along with the Resource itself. ndjson keeps memory "+
+			"usage low regardless of how many resources are dumped",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&sqliteFile, "sqlite", "",
+		"Write the dump to a SQLite database at this path instead of printing json. The database has "+
+			"one table per resource type (eg ec2_instances, tags, iam_users), so resources can be queried and "+
+			"joined with plain SQL. An existing database at this path is replaced. See also the sqlite command "+
+			"to convert a previously generated dump",
+	)
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if outputFormat != jsonFormat && outputFormat != ndjsonFormat {
+			return fmt.Errorf("invalid output format %q: must be either %s or %s", outputFormat, jsonFormat, ndjsonFormat)
+		}
+		if sqliteFile != "" && outputFormat != jsonFormat {
+			return fmt.Errorf("--sqlite can not be used with --output-format %s", outputFormat)
+		}
+
 		loaderOptions := []loader.Option{
 			loader.WithRegions(regions...),
 			loader.WithoutRegions(excludeRegions...),
 			loader.WithServices(services...),
 			loader.WithoutServices(excludeServices...),
+			loader.WithAccounts(accounts...),
+			loader.WithoutAccounts(excludeAccounts...),
+			loader.WithAccountParallelism(accountParallelism),
+		}
+		if bestEffort {
+			loaderOptions = append(loaderOptions, loader.WithPartialResults())
+		}
+
+		if assumeRole == "" && (len(accounts) > 0 || len(excludeAccounts) > 0) {
+			return fmt.Errorf("--accounts and --exclude-accounts can only be used with --assume-role")
+		}
+		if accountParallelism < 1 {
+			return fmt.Errorf("--account-parallelism must be at least 1")
 		}
 
 		if listServicesOnly {
@@ -69,7 +151,7 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		// see more at https://github.com/spf13/cobra/issues/340
 		cmd.SilenceUsage = true
 
-		return dump(cmd.Context(), **awsCfg, loaderOptions...)
+		return dump(cmd.Context(), **awsCfg, assumeRole, outputFormat, sqliteFile, loaderOptions...)
 	}
 
 	return &cmd
@@ -81,11 +163,79 @@ func listServices() {
 	}
 }
 
-func dump(ctx context.Context, cfg aws.Config, options ...loader.Option) error {
-	result, err := loader.LoadAWS(ctx, cfg, options...)
-	if err != nil {
-		log.Errorf("Error while loading data: %v", err)
-		return err
+func dump(
+	ctx context.Context,
+	cfg aws.Config,
+	assumeRole string,
+	outputFormat string,
+	sqliteFile string,
+	options ...loader.Option,
+) error {
+	var records *recordWriter
+	if outputFormat == ndjsonFormat {
+		records = newRecordWriter(os.Stdout)
+		options = append(options, loader.WithRecordHandler(records.write))
+	}
+
+	var result interface{}
+	var failures int
+	if assumeRole != "" {
+		accounts, err := loader.LoadAccounts(ctx, cfg, assumeRole, options...)
+		if err != nil {
+			log.Errorf("Error while loading data: %v", err)
+			return err
+		}
+		for _, account := range accounts.Accounts {
+			failures += len(account.Errors)
+			if account.AWS != nil {
+				failures += len(account.AWS.Failures)
+			}
+		}
+		if records != nil {
+			records.writeAccounts(accounts)
+		}
+		if sqliteFile != "" {
+			if err := sqlite.WriteAccounts(sqliteFile, accounts); err != nil {
+				log.Errorf("Error while writing SQLite database: %v", err)
+				return err
+			}
+			return checkFailures(failures)
+		}
+		result = accounts
+	} else {
+		if records != nil {
+			accountId, err := sts.FetchAccountId(ctx, cfg)
+			if err != nil {
+				log.Errorf("Error while fetching the account id: %v", err)
+				return err
+			}
+			records.account = accountId
+		}
+		data, err := loader.LoadAWS(ctx, cfg, options...)
+		if err != nil {
+			log.Errorf("Error while loading data: %v", err)
+			return err
+		}
+		failures = len(data.Failures)
+		if records != nil {
+			records.writeFailures("", data.Failures)
+		}
+		if sqliteFile != "" {
+			if err := sqlite.Write(sqliteFile, data); err != nil {
+				log.Errorf("Error while writing SQLite database: %v", err)
+				return err
+			}
+			return checkFailures(failures)
+		}
+		result = data
+	}
+
+	if records != nil {
+		if records.err != nil {
+			log.Errorf("Error while writing records: %v", records.err)
+			return records.err
+		}
+		return checkFailures(failures)
 	}
 
 	log.Info("Data fully loaded, encoding to json")
@@ -100,5 +250,24 @@ func dump(ctx context.Context, cfg aws.Config, options ...loader.Option) error {
 	os.Stdout.Write(jsonBytes)
 	os.Stdout.Write([]byte{'\n'})
 
+	return checkFailures(failures)
+}
+
+func checkFailures(failures int) error {
+	if failures > 0 {
+		return &partialResultsErr{failures: failures}
+	}
 	return nil
 }
+
+type partialResultsErr struct {
+	failures int
+}
+
+func (e *partialResultsErr) ExitCode() int {
+	return partialResultsExitCode
+}
+
+func (e *partialResultsErr) Error() string {
+	return fmt.Sprintf("dump generated with %d failures", e.failures)
+}
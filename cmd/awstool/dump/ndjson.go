@@ -0,0 +1,94 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+
+	awst "awstool/aws"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// recordWriter writes loader records as newline delimited json, one record per line. Records
+// are flushed as soon as they are written, so output starts as soon as the first service loads
+type recordWriter struct {
+	lock    sync.Mutex
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+
+	// account is set on records that have no account, which happens when loading a single account
+	account string
+}
+
+func newRecordWriter(w io.Writer) *recordWriter {
+	writer := bufio.NewWriter(w)
+	return &recordWriter{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (w *recordWriter) write(records []loader.Record) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return
+	}
+	for _, record := range records {
+		if record.Account == "" {
+			record.Account = w.account
+		}
+		if err := w.encoder.Encode(record); err != nil {
+			w.err = err
+			return
+		}
+	}
+	w.err = w.writer.Flush()
+}
+
+func (w *recordWriter) writeFailures(account string, failures []awst.Failure) {
+	records := make([]loader.Record, len(failures))
+	for idx, failure := range failures {
+		records[idx] = loader.Record{
+			Account:  account,
+			Region:   failure.Region,
+			Service:  failure.Service,
+			Type:     "Failures",
+			Resource: failure,
+		}
+	}
+	w.write(records)
+}
+
+// writeAccounts writes what is not streamed by the loader when loading multiple accounts:
+// the organization, the accounts themselves and their failures
+func (w *recordWriter) writeAccounts(accounts *awst.Accounts) {
+	if accounts.Organization != nil {
+		w.write([]loader.Record{{
+			Service:  "organizations",
+			Type:     "Organization",
+			Resource: accounts.Organization,
+		}})
+	}
+	for accountId, account := range accounts.Accounts {
+		w.write([]loader.Record{{
+			Account:  accountId,
+			Service:  "organizations",
+			Type:     "Accounts",
+			Key:      aws.ToString(account.Account.Name),
+			Resource: account.Account,
+		}})
+		failures := []awst.Failure{}
+		for _, err := range account.Errors {
+			failures = append(failures, awst.Failure{ErrorClass: loader.ErrorClassOther, Message: err})
+		}
+		if account.AWS != nil {
+			failures = append(failures, account.AWS.Failures...)
+		}
+		w.writeFailures(accountId, failures)
+	}
+}
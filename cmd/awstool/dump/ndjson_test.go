@@ -0,0 +1,108 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	awst "awstool/aws"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+// line is what is read back from a line of ndjson output
+type line struct {
+	Account  string
+	Region   string
+	Service  string
+	Type     string
+	Key      string
+	Resource map[string]interface{}
+}
+
+func readLines(t *testing.T, output string) []line {
+	result := []line{}
+	for _, text := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		parsed := line{}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			t.Fatalf("line %q is not a json document: %v", text, err)
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
+
+func TestRecordWriter(t *testing.T) {
+	out := bytes.Buffer{}
+	writer := newRecordWriter(&out)
+	writer.account = "123456789012"
+
+	writer.write([]loader.Record{
+		{Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-1", Resource: ec2Types.Instance{InstanceId: aws.String("i-1")}},
+		{Account: "210987654321", Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-2", Resource: ec2Types.Instance{InstanceId: aws.String("i-2")}},
+	})
+	// records are flushed as soon as they are written
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Fatalf("expected 2 lines to be written, got %d", lines)
+	}
+	writer.writeFailures("", []awst.Failure{{Service: "ebs", Region: "eu-west-1", Operation: "DescribeVolumes", ErrorClass: loader.ErrorClassAccessDenied}})
+	if writer.err != nil {
+		t.Fatal(writer.err)
+	}
+
+	lines := readLines(t, out.String())
+	expected := []line{
+		{Account: "123456789012", Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-1", Resource: map[string]interface{}{"InstanceId": "i-1"}},
+		{Account: "210987654321", Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-2", Resource: map[string]interface{}{"InstanceId": "i-2"}},
+		{Account: "123456789012", Region: "eu-west-1", Service: "ebs", Type: "Failures"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %+v", len(expected), lines)
+	}
+	for idx, exp := range expected {
+		got := lines[idx]
+		if got.Account != exp.Account || got.Region != exp.Region || got.Service != exp.Service || got.Type != exp.Type || got.Key != exp.Key {
+			t.Errorf("line #%d: expected %+v, got %+v", idx, exp, got)
+		}
+		if exp.Resource != nil && got.Resource["InstanceId"] != exp.Resource["InstanceId"] {
+			t.Errorf("line #%d: expected resource %v, got %v", idx, exp.Resource, got.Resource)
+		}
+	}
+	if failure := lines[2].Resource; failure["Operation"] != "DescribeVolumes" || failure["ErrorClass"] != loader.ErrorClassAccessDenied {
+		t.Errorf("unexpected failure resource %v", failure)
+	}
+}
+
+func TestRecordWriterAccounts(t *testing.T) {
+	accounts := awst.NewAccounts()
+	accounts.Organization = &orgTypes.Organization{Id: aws.String("o-1")}
+	accounts.Accounts["111111111111"] = &awst.Account{
+		Account: orgTypes.Account{Id: aws.String("111111111111"), Name: aws.String("prod")},
+		Errors:  []string{"failed to assume role"},
+	}
+
+	out := bytes.Buffer{}
+	writer := newRecordWriter(&out)
+	writer.writeAccounts(&accounts)
+	if writer.err != nil {
+		t.Fatal(writer.err)
+	}
+
+	summary := []string{}
+	for _, parsed := range readLines(t, out.String()) {
+		summary = append(summary, strings.Join([]string{parsed.Account, parsed.Service, parsed.Type, parsed.Key}, "|"))
+	}
+	expected := []string{
+		"|organizations|Organization|",
+		"111111111111|organizations|Accounts|prod",
+		"111111111111||Failures|",
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected lines %v, got %v", expected, summary)
+	}
+}
@@ -2,8 +2,12 @@ package ec2
 
 import (
 	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/exec"
 	"awstool/cmd/awstool/ec2/resolve"
+	"awstool/cmd/awstool/ec2/scp"
 	"awstool/cmd/awstool/ec2/ssh"
+	"awstool/cmd/awstool/ec2/sshconfig"
+	"awstool/cmd/awstool/ec2/tunnel"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/spf13/cobra"
@@ -17,5 +21,10 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 	}
 	awstcmd.AddSubCommand(&cmd, resolve.Command(awsCfg))
 	awstcmd.AddSubCommand(&cmd, ssh.Command(awsCfg))
+	awstcmd.AddSubCommand(&cmd, ssh.SSMProxyCommand(awsCfg))
+	awstcmd.AddSubCommand(&cmd, exec.Command(awsCfg))
+	awstcmd.AddSubCommand(&cmd, sshconfig.Command(awsCfg))
+	awstcmd.AddSubCommand(&cmd, tunnel.Command(awsCfg))
+	awstcmd.AddSubCommand(&cmd, scp.Command(awsCfg))
 	return &cmd
 }
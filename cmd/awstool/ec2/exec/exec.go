@@ -0,0 +1,290 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"awstool/aws/ec2"
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/resolve"
+	"awstool/cmd/awstool/ec2/ssh"
+	"awstool/cmd/awstool/ec2/target"
+	"awstool/executor"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const failedInstancesExitCode = 2
+
+type options struct {
+	user        string
+	publicIp    bool
+	useSSM      bool
+	sshOptions  []string
+	parallelism int
+}
+
+// result is how running the command on an instance went
+type result struct {
+	match    target.Match
+	exitCode int
+	err      error
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "exec [flags] COMMAND [ARGS...]",
+		Short: "runs a command via ssh on all instances matching a set of filters",
+		Long: "Runs a command via ssh on all instances matching the given filters, in parallel. Unless --state " +
+			"is given only running instances are selected. Each line of output is prefixed with the instance " +
+			"it came from, and a summary with the exit code for each instance is printed at the end. If the " +
+			"command fails on any instance the exit code is 2",
+		Example:       "  awstool ec2 exec --tags Role:api -- cat /etc/app/config.yml",
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.MinimumNArgs(1)
+	// flags after the command belong to the command, eg ec2 exec -t Role:api ls -la
+	cmd.Flags().SetInterspersed(false)
+
+	filters := resolve.Filters{}
+	options := options{}
+
+	filters.AddFlags(cmd.Flags())
+
+	cmd.Flags().StringVarP(
+		&options.user, "user", "l", "",
+		"Log in with this user. By default the user is discovered from the image of each instance",
+	)
+
+	cmd.Flags().BoolVarP(
+		&options.publicIp, "public-ip", "P", false,
+		"Connect through the public ip of the instances instead of their private ip",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.useSSM, "ssm", false,
+		"Connect through AWS Systems Manager sessions, as in ec2 ssh --ssm",
+	)
+
+	cmd.Flags().StringArrayVarP(
+		&options.sshOptions, "ssh-option", "o", []string{},
+		"Pass an option to ssh, eg -o StrictHostKeyChecking=no. Can be repeated",
+	)
+
+	cmd.Flags().IntVarP(
+		&options.parallelism, "parallelism", "n", 10,
+		"How many instances to run the command on at the same time",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := filters.Parse(); err != nil {
+			return err
+		}
+		if filters.IsEmpty() {
+			return fmt.Errorf("at least one filter is required, eg --tags or --instance-id")
+		}
+		if options.useSSM && options.publicIp {
+			return fmt.Errorf("cannot use both --ssm and --public-ip")
+		}
+		if options.parallelism <= 0 {
+			return fmt.Errorf("--parallelism must be positive")
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		fetchOpts := filters.FetchOptions()
+		if !filters.HasState() {
+			fetchOpts = append(fetchOpts, ec2.WithFilter("instance-state-name", "running"))
+		}
+		matches, err := target.FindInstances(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, fetchOpts...)
+		if err != nil {
+			return fmt.Errorf("failed while fetching instances: %w", err)
+		}
+		matches = filters.FilterMatches(matches)
+		if len(matches) == 0 {
+			return fmt.Errorf("no instances match the given filters")
+		}
+
+		users, err := ssh.DiscoverUsers(cmd, **awsCfg, matches, options.user)
+		if err != nil {
+			return err
+		}
+
+		results := run(cmd, matches, users, args, options)
+		printSummary(os.Stderr, results)
+
+		failed := 0
+		for _, result := range results {
+			if result.err != nil || result.exitCode != 0 {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return &failedInstancesErr{failed: failed, total: len(results)}
+		}
+		return nil
+	}
+
+	return &cmd
+}
+
+func run(cmd *cobra.Command, matches []target.Match, users map[string]string, command []string, options options) []result {
+	ctx := cmd.Context()
+	executor := executor.NewExecutor(options.parallelism)
+	results := make([]result, len(matches))
+	// lines of different instances are written whole, so they do not get mixed up
+	outputLock := sync.Mutex{}
+
+	for idx := range matches {
+		idx := idx
+		match := matches[idx]
+		results[idx].match = match
+		executor.Launch(ctx, func() {
+			args, err := sshArgs(cmd, match, users[*match.Instance.InstanceId], command, options)
+			if err != nil {
+				results[idx].err = err
+				return
+			}
+			prefix := "[" + label(match) + "] "
+			stdout := &prefixWriter{lock: &outputLock, out: os.Stdout, prefix: prefix}
+			stderr := &prefixWriter{lock: &outputLock, out: os.Stderr, prefix: prefix}
+
+			log.Debugf("Running ssh %s", strings.Join(args, " "))
+			execution := osexec.CommandContext(ctx, "ssh", args...)
+			execution.Stdout = stdout
+			execution.Stderr = stderr
+			err = execution.Run()
+			stdout.Flush()
+			stderr.Flush()
+
+			exitErr := &osexec.ExitError{}
+			if errors.As(err, &exitErr) {
+				results[idx].exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				results[idx].err = fmt.Errorf("failed to launch ssh: %w", err)
+			}
+		})
+	}
+
+	// on cancellation the ssh processes are killed, but results can only be read once every
+	// launched function is done writing them
+	<-executor.Done()
+	if err := ctx.Err(); err != nil {
+		for idx := range results {
+			if results[idx].err == nil && results[idx].exitCode == 0 {
+				results[idx].err = err
+			}
+		}
+	}
+	return results
+}
+
+func sshArgs(cmd *cobra.Command, match target.Match, user string, command []string, options options) ([]string, error) {
+	instance := match.Instance
+	// never prompt for passwords or host keys, as there is no one to answer them
+	args := []string{"-o", "BatchMode=yes"}
+	for _, option := range options.sshOptions {
+		args = append(args, "-o", option)
+	}
+
+	address, addressArgs, err := ssh.Address(cmd, match.Region, instance, options.publicIp, options.useSSM)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, addressArgs...)
+	if user != "" {
+		address = user + "@" + address
+	}
+
+	args = append(args, address, "--")
+	return append(args, command...), nil
+}
+
+func label(match target.Match) string {
+	name := target.Name(match.Instance)
+	if name == "" {
+		return *match.Instance.InstanceId
+	}
+	return *match.Instance.InstanceId + " " + name
+}
+
+func printSummary(out io.Writer, results []result) {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "#region\t#instance\t#name\t#result")
+	for _, result := range results {
+		status := fmt.Sprintf("exit code %d", result.exitCode)
+		if result.err != nil {
+			status = "error: " + result.err.Error()
+		}
+		name := target.Name(result.match.Instance)
+		if name == "" {
+			name = "<N/A>"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", result.match.Region, *result.match.Instance.InstanceId, name, status)
+	}
+	writer.Flush()
+}
+
+// prefixWriter writes each line with a prefix. Partial lines are buffered until they are
+// completed or the writer is flushed
+type prefixWriter struct {
+	lock   *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			return len(data), nil
+		}
+		if err := w.writeLine(w.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
+
+type failedInstancesErr struct {
+	failed int
+	total  int
+}
+
+func (e *failedInstancesErr) ExitCode() int {
+	return failedInstancesExitCode
+}
+
+func (e *failedInstancesErr) Error() string {
+	return fmt.Sprintf("command failed on %d of %d instances", e.failed, e.total)
+}
@@ -0,0 +1,77 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	out := bytes.Buffer{}
+	lock := sync.Mutex{}
+	first := &prefixWriter{lock: &lock, out: &out, prefix: "[i-1] "}
+	second := &prefixWriter{lock: &lock, out: &out, prefix: "[i-2] "}
+
+	first.Write([]byte("one\ntw"))
+	second.Write([]byte("other\n"))
+	first.Write([]byte("o\nthree"))
+	first.Flush()
+	second.Flush()
+
+	expected := "[i-1] one\n[i-2] other\n[i-1] two\n[i-1] three\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q but got %q", expected, out.String())
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake ssh")
+	}
+	// a fake ssh that never finishes on its own
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	matches := make([]target.Match, 4)
+	for idx := range matches {
+		matches[idx] = target.Match{
+			Region: "us-east-1",
+			Instance: &ec2Types.Instance{
+				InstanceId:       aws.String(fmt.Sprintf("i-%d", idx)),
+				PrivateIpAddress: aws.String(fmt.Sprintf("10.0.0.%d", idx)),
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+
+	// only some instances get to run, the others are still queued when cancelled
+	start := time.Now()
+	results := run(cmd, matches, map[string]string{}, []string{"uptime"}, options{parallelism: 2})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected ssh to be killed on cancellation, but run took %v", elapsed)
+	}
+	for _, result := range results {
+		if result.err == nil && result.exitCode == 0 {
+			t.Errorf("expected %s to fail on cancellation, got %+v", *result.match.Instance.InstanceId, result)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package resolve
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	awst "awstool/aws"
+	"awstool/aws/ec2"
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/pflag"
+)
+
+// ASGTag is set by AWS on every instance launched by an Auto Scaling group, holding the name of
+// the group
+const ASGTag = "aws:autoscaling:groupName"
+
+// Filters select instances. Most of them are passed along to the DescribeInstances API, the
+// ones it does not support (regular expressions, ip ranges and launch time ranges) are applied
+// on the results with Matches. Different filters are ANDed together, while the values of a
+// single filter are ORed
+type Filters struct {
+	instanceId     string
+	tags           []string
+	names          []string
+	asgs           []string
+	states         []string
+	types          []string
+	vpcIds         []string
+	subnetIds      []string
+	zones          []string
+	imageIds       []string
+	hasTags        []string
+	tagRegexps     []string
+	privateIps     []string
+	publicIps      []string
+	launchedAfter  string
+	launchedBefore string
+
+	parsed parsedFilters
+}
+
+type parsedFilters struct {
+	tags           map[string]string
+	tagRegexps     map[string][]*regexp.Regexp
+	privateNets    []*net.IPNet
+	publicNets     []*net.IPNet
+	launchedAfter  time.Time
+	launchedBefore time.Time
+}
+
+// AddFlags registers the flags of all filters. Only --instance-id and --tags have shorthands, so
+// commands are free to use other letters
+func (f *Filters) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(
+		&f.instanceId, "instance-id", "i", "",
+		"Find the instance by its id",
+	)
+
+	flags.StringSliceVarP(
+		&f.tags, "tags", "t", []string{},
+		"Find instances by tag key/value pairs. Pairs are ANDed together, while alternative values for "+
+			"a key can be separated by |. Values accept * and ? wildcards. "+
+			"Eg: --tags Owner:Bruno,Env:dev|staging. Alternatively: --tags Owner:Bruno --tags Env:dev|staging",
+	)
+
+	flags.StringSliceVar(
+		&f.names, "name", []string{},
+		"Find instances by their Name tag, accepting * and ? wildcards. Eg: --name 'web-*'",
+	)
+
+	flags.StringSliceVar(
+		&f.asgs, "asg", []string{},
+		"Find instances launched by these Auto Scaling groups, accepting * and ? wildcards. Eg: --asg 'web-*'",
+	)
+
+	flags.StringSliceVar(
+		&f.states, "state", []string{},
+		"Find instances in these states: "+strings.Join(instanceStates(), ", "),
+	)
+
+	flags.StringSliceVar(
+		&f.types, "type", []string{},
+		"Find instances of these types, accepting * and ? wildcards. Eg: --type 't3.*'",
+	)
+
+	flags.StringSliceVar(
+		&f.vpcIds, "vpc-id", []string{},
+		"Find instances in these VPCs",
+	)
+
+	flags.StringSliceVar(
+		&f.subnetIds, "subnet-id", []string{},
+		"Find instances in these subnets",
+	)
+
+	flags.StringSliceVar(
+		&f.zones, "az", []string{},
+		"Find instances in these availability zones, eg us-east-1a",
+	)
+
+	flags.StringSliceVar(
+		&f.imageIds, "image-id", []string{},
+		"Find instances launched from these AMIs",
+	)
+
+	flags.StringSliceVar(
+		&f.hasTags, "has-tag", []string{},
+		"Find instances that have all of these tag keys, whatever their values",
+	)
+
+	flags.StringArrayVar(
+		&f.tagRegexps, "tag-regex", []string{},
+		"Find instances with a tag value matching a regular expression, as KEY:REGEX. "+
+			"Eg: --tag-regex 'Name:^web-[0-9]+$'. Can be repeated, and all of them must match",
+	)
+
+	flags.StringSliceVar(
+		&f.privateIps, "private-address", []string{},
+		"Find instances by private ip or CIDR range. Eg: --private-address 10.0.1.0/24",
+	)
+
+	flags.StringSliceVar(
+		&f.publicIps, "public-address", []string{},
+		"Find instances by public ip or CIDR range",
+	)
+
+	flags.StringVar(
+		&f.launchedAfter, "launched-after", "",
+		"Find instances launched after a date (eg 2023-01-31 or 2023-01-31T10:00:00Z) or a time ago "+
+			"(eg 12h or 7d)",
+	)
+
+	flags.StringVar(
+		&f.launchedBefore, "launched-before", "",
+		"Find instances launched before a date or a time ago, as in --launched-after",
+	)
+}
+
+// Parse validates the filters. It must be called before using them
+func (f *Filters) Parse() error {
+	var err error
+	f.parsed.tags, err = awstcmd.ParseTags(f.tags)
+	if err != nil {
+		return err
+	}
+
+	validStates := instanceStates()
+	for _, state := range f.states {
+		if !contains(validStates, state) {
+			return fmt.Errorf("invalid state %q, must be one of: %s", state, strings.Join(validStates, ", "))
+		}
+	}
+
+	f.parsed.tagRegexps = map[string][]*regexp.Regexp{}
+	for _, spec := range f.tagRegexps {
+		separatorIdx := strings.Index(spec, ":")
+		if separatorIdx <= 0 {
+			return fmt.Errorf("invalid --tag-regex %q, expected KEY:REGEX", spec)
+		}
+		pattern, err := regexp.Compile(spec[separatorIdx+1:])
+		if err != nil {
+			return fmt.Errorf("invalid --tag-regex %q: %w", spec, err)
+		}
+		key := spec[:separatorIdx]
+		f.parsed.tagRegexps[key] = append(f.parsed.tagRegexps[key], pattern)
+	}
+
+	if f.parsed.privateNets, err = parseNets(f.privateIps); err != nil {
+		return fmt.Errorf("invalid --private-address: %w", err)
+	}
+	if f.parsed.publicNets, err = parseNets(f.publicIps); err != nil {
+		return fmt.Errorf("invalid --public-address: %w", err)
+	}
+
+	now := time.Now()
+	if f.parsed.launchedAfter, err = parseTime(f.launchedAfter, now); err != nil {
+		return fmt.Errorf("invalid --launched-after: %w", err)
+	}
+	if f.parsed.launchedBefore, err = parseTime(f.launchedBefore, now); err != nil {
+		return fmt.Errorf("invalid --launched-before: %w", err)
+	}
+	return nil
+}
+
+// IsEmpty tells if no filters were set, which means all instances are selected
+func (f *Filters) IsEmpty() bool {
+	return f.instanceId == "" && len(f.tags) == 0 && len(f.names) == 0 && len(f.asgs) == 0 &&
+		len(f.states) == 0 && len(f.types) == 0 && len(f.vpcIds) == 0 && len(f.subnetIds) == 0 && len(f.zones) == 0 &&
+		len(f.imageIds) == 0 && len(f.hasTags) == 0 && len(f.tagRegexps) == 0 &&
+		len(f.privateIps) == 0 && len(f.publicIps) == 0 && f.launchedAfter == "" && f.launchedBefore == ""
+}
+
+// HasState tells if instances are being filtered by state, so commands can apply a default
+func (f *Filters) HasState() bool {
+	return len(f.states) > 0
+}
+
+// FetchOptions converts the filters supported by the DescribeInstances API to ec2 fetch options
+func (f *Filters) FetchOptions() []ec2.FetchOption {
+	fetchOpts := []ec2.FetchOption{}
+	if f.instanceId != "" {
+		fetchOpts = append(fetchOpts, ec2.WithInstanceIds(f.instanceId))
+	}
+	fetchOpts = append(fetchOpts, awstcmd.TagFetchOptions(f.parsed.tags)...)
+
+	valueFilters := []struct {
+		name   string
+		values []string
+	}{
+		{"tag:Name", f.names},
+		{"tag:" + ASGTag, f.asgs},
+		{"instance-state-name", f.states},
+		{"instance-type", f.types},
+		{"vpc-id", f.vpcIds},
+		{"subnet-id", f.subnetIds},
+		{"availability-zone", f.zones},
+		{"image-id", f.imageIds},
+	}
+	for _, filter := range valueFilters {
+		if len(filter.values) > 0 {
+			fetchOpts = append(fetchOpts, ec2.WithFilter(filter.name, filter.values...))
+		}
+	}
+	// each key gets its own filter, so they are all required
+	for _, key := range f.hasTags {
+		fetchOpts = append(fetchOpts, ec2.WithFilter("tag-key", key))
+	}
+	// the API only matches exact ips, so ranges are left to Matches
+	if ips, ok := exactIps(f.parsed.privateNets); ok {
+		fetchOpts = append(fetchOpts, ec2.WithFilter("private-ip-address", ips...))
+	}
+	if ips, ok := exactIps(f.parsed.publicNets); ok {
+		fetchOpts = append(fetchOpts, ec2.WithFilter("ip-address", ips...))
+	}
+	return fetchOpts
+}
+
+// Matches applies the filters the DescribeInstances API does not support
+func (f *Filters) Matches(instance *ec2Types.Instance) bool {
+	for key, patterns := range f.parsed.tagRegexps {
+		for _, pattern := range patterns {
+			matched := false
+			for _, tag := range instance.Tags {
+				if aws.ToString(tag.Key) == key && pattern.MatchString(aws.ToString(tag.Value)) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+	}
+	if len(f.parsed.privateNets) > 0 && !containsIp(f.parsed.privateNets, instance.PrivateIpAddress) {
+		return false
+	}
+	if len(f.parsed.publicNets) > 0 && !containsIp(f.parsed.publicNets, instance.PublicIpAddress) {
+		return false
+	}
+	if !f.parsed.launchedAfter.IsZero() &&
+		(instance.LaunchTime == nil || !instance.LaunchTime.After(f.parsed.launchedAfter)) {
+		return false
+	}
+	if !f.parsed.launchedBefore.IsZero() &&
+		(instance.LaunchTime == nil || !instance.LaunchTime.Before(f.parsed.launchedBefore)) {
+		return false
+	}
+	return true
+}
+
+// Filter removes instances not matching the filters from loaded data
+func (f *Filters) Filter(result *awst.AWS) {
+	for _, region := range result.Regions {
+		reservations := []ec2Types.Reservation{}
+		for _, reservation := range region.EC2.Reservations {
+			instances := []ec2Types.Instance{}
+			for idx := range reservation.Instances {
+				if f.Matches(&reservation.Instances[idx]) {
+					instances = append(instances, reservation.Instances[idx])
+				}
+			}
+			if len(instances) > 0 {
+				reservation.Instances = instances
+				reservations = append(reservations, reservation)
+			}
+		}
+		region.EC2.Reservations = reservations
+	}
+}
+
+// FilterMatches removes matches not matching the filters
+func (f *Filters) FilterMatches(matches []target.Match) []target.Match {
+	result := []target.Match{}
+	for _, match := range matches {
+		if f.Matches(match.Instance) {
+			result = append(result, match)
+		}
+	}
+	return result
+}
+
+func instanceStates() []string {
+	values := ec2Types.InstanceStateName("").Values()
+	result := make([]string, len(values))
+	for idx, value := range values {
+		result[idx] = string(value)
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNets parses ips and CIDR ranges. Single ips are turned into ranges with only that ip
+func parseNets(values []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an ip or CIDR range", value)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an ip or CIDR range", value)
+		}
+		result = append(result, ipNet)
+	}
+	return result, nil
+}
+
+// exactIps returns the ips of the ranges if all of them are single ips
+func exactIps(nets []*net.IPNet) ([]string, bool) {
+	if len(nets) == 0 {
+		return nil, false
+	}
+	ips := make([]string, len(nets))
+	for idx, ipNet := range nets {
+		ones, bits := ipNet.Mask.Size()
+		if ones != bits {
+			return nil, false
+		}
+		ips[idx] = ipNet.IP.String()
+	}
+	return ips, true
+}
+
+func containsIp(nets []*net.IPNet, address *string) bool {
+	ip := net.ParseIP(aws.ToString(address))
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime parses a date, with or without time, or a time ago such as 12h or 7d
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if result, err := time.Parse(layout, value); err == nil {
+			return result, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a date nor a time ago", value)
+}
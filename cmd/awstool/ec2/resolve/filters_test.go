@@ -0,0 +1,105 @@
+package resolve
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"awstool/aws/ec2"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/pflag"
+)
+
+func instance(id string, privateIp string, instanceType string, launched time.Time, tags map[string]string) ec2Types.Instance {
+	result := ec2Types.Instance{
+		InstanceId:       aws.String(id),
+		InstanceType:     ec2Types.InstanceType(instanceType),
+		PrivateIpAddress: aws.String(privateIp),
+		LaunchTime:       aws.Time(launched),
+		State:            &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameRunning},
+	}
+	for key, value := range tags {
+		result.Tags = append(result.Tags, ec2Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
+
+func TestFilters(t *testing.T) {
+	now := time.Now()
+	reservations := []ec2Types.Reservation{{Instances: []ec2Types.Instance{
+		instance("i-1", "10.0.1.10", "t3.small", now.Add(-time.Hour), map[string]string{"Name": "web-1", "Env": "prod", ASGTag: "web-asg"}),
+		instance("i-2", "10.0.2.10", "t3.large", now.AddDate(0, 0, -10), map[string]string{"Name": "web-22", "Env": "staging", ASGTag: "web-staging-asg"}),
+		instance("i-3", "10.0.1.20", "m5.large", now.AddDate(0, 0, -30), map[string]string{"Name": "db-1", "Env": "dev", "Backup": "yes"}),
+	}}}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{}, []string{"i-1", "i-2", "i-3"}},
+		{[]string{"--tags", "Env:prod|staging"}, []string{"i-1", "i-2"}},
+		{[]string{"--name", "web-*", "--type", "t3.*"}, []string{"i-1", "i-2"}},
+		{[]string{"--type", "m5.large,t3.small"}, []string{"i-1", "i-3"}},
+		{[]string{"--asg", "web-asg"}, []string{"i-1"}},
+		{[]string{"--asg", "web-*"}, []string{"i-1", "i-2"}},
+		{[]string{"--has-tag", "Backup"}, []string{"i-3"}},
+		{[]string{"--tag-regex", "Name:^web-[0-9]$"}, []string{"i-1"}},
+		{[]string{"--tag-regex", "Name:2$", "--tag-regex", "Name:^web"}, []string{"i-2"}},
+		{[]string{"--private-address", "10.0.1.0/24"}, []string{"i-1", "i-3"}},
+		{[]string{"--private-address", "10.0.2.10"}, []string{"i-2"}},
+		{[]string{"--launched-after", "7d"}, []string{"i-1"}},
+		{[]string{"--launched-before", "2h", "--launched-after", now.AddDate(0, 0, -20).Format("2006-01-02")}, []string{"i-2"}},
+		{[]string{"--state", "stopped"}, []string{}},
+	}
+	for _, test := range tests {
+		filters := Filters{}
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		filters.AddFlags(flags)
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatalf("failed to parse flags %v: %v", test.args, err)
+		}
+		if err := filters.Parse(); err != nil {
+			t.Fatalf("invalid filters %v: %v", test.args, err)
+		}
+
+		filtered, err := ec2.FilterInstances(reservations, filters.FetchOptions()...)
+		if err != nil {
+			t.Fatalf("failed to filter instances with %v: %v", test.args, err)
+		}
+		ids := []string{}
+		for _, reservation := range filtered {
+			for idx := range reservation.Instances {
+				if filters.Matches(&reservation.Instances[idx]) {
+					ids = append(ids, *reservation.Instances[idx].InstanceId)
+				}
+			}
+		}
+		if strings.Join(ids, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("filters %v matched %v, expected %v", test.args, ids, test.expected)
+		}
+	}
+}
+
+func TestFiltersParseErrors(t *testing.T) {
+	invalid := [][]string{
+		{"--state", "sleeping"},
+		{"--tag-regex", "Name"},
+		{"--tag-regex", "Name:("},
+		{"--private-address", "10.0.0"},
+		{"--public-address", "1.2.3.4/99"},
+		{"--launched-after", "yesterday"},
+	}
+	for _, args := range invalid {
+		filters := Filters{}
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		filters.AddFlags(flags)
+		if err := flags.Parse(args); err != nil {
+			t.Fatalf("failed to parse flags %v: %v", args, err)
+		}
+		if err := filters.Parse(); err == nil {
+			t.Errorf("expected filters %v to be invalid", args)
+		}
+	}
+}
@@ -3,29 +3,18 @@ package resolve
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"strings"
-	"text/template"
+	"os"
 
 	awst "awstool/aws"
-	"awstool/aws/ec2"
+	awstcmd "awstool/cmd"
 	"awstool/loader"
+	"awstool/printer"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/spf13/cobra"
 )
 
-type printOptions struct {
-	publicIp  bool
-	privateIp bool
-	tags      []string
-	allTags   bool
-	urlEncode bool
-	header    bool
-	template  *template.Template
-}
-
 func Command(awsCfg **aws.Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:           "resolve",
@@ -33,84 +22,44 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		SilenceErrors: true,
 	}
 
-	var instanceId string
-	var tags []string
-
-	printOptions := printOptions{}
-	var noURLEncode bool
-	var templ string
-
-	cmd.Flags().StringVarP(
-		&instanceId, "instance-id", "i", "",
-		"Find the instance by its id",
-	)
-
-	cmd.Flags().StringSliceVarP(
-		&tags, "tags", "t", []string{},
-		"Find the instance by tag key/value pairs. Values are ANDed togther. "+
-			"Eg: --tags Owner:Bruno,Env:development. Alternatively: --tags Owner:Bruno --tags Env:development",
-	)
-
-	cmd.Flags().StringSliceVarP(
-		&printOptions.tags, "print-tags", "T", []string{},
-		"By default the command only prints the Name tag. Pass a list of tags keys that "+
-			"should be printed instead. See also --print-all-tags",
-	)
-
-	cmd.Flags().BoolVarP(
-		&printOptions.allTags, "print-all-tags", "A", false,
-		"Also prints all tags associated to the instances. Overrides --print-tags",
-	)
+	filters := Filters{}
+	filters.AddFlags(cmd.Flags())
 
-	cmd.Flags().BoolVarP(
-		&printOptions.publicIp, "public", "u", false,
-		"Only print the public ip of the instance, if it has one",
-	)
+	printOptions := printer.Options{}
+	printOptions.AddFlags(cmd.Flags(), "'{{.Region}} {{.Instance.InstanceId}}'")
+	printOptions.AddTagFlags(cmd.Flags())
 
-	cmd.Flags().BoolVarP(
-		&printOptions.privateIp, "private", "r", false,
-		"Only print the private ip of the instance, if it has one",
-	)
+	var publicIp bool
+	var privateIp bool
 
 	cmd.Flags().BoolVarP(
-		&noURLEncode, "no-url-encode", "E", false,
-		"By default when printing tags their values are URL encoded to avoid whitespacing issues. "+
-			"Use this flag to avoid such mechanism",
+		&publicIp, "public", "u", false,
+		"Only print the public ip of the instance, if it has one. Same as --columns publicIp, "+
+			"skipping instances without a public ip",
 	)
 
 	cmd.Flags().BoolVarP(
-		&printOptions.header, "header", "H", false,
-		"Also print a header on the first line, which will name the columns being printed",
-	)
-
-	cmd.Flags().StringVar(
-		&templ, "template", "",
-		"Print using a golang template instead. Template syntax is defined at https://pkg.go.dev/text/template. "+
-			"A simple template for printing region and instance id: '{{.Region}} {{.Instance.InstanceId}}'. "+
-			"The struct passed to the template engine can be checked by reading the source code "+
-			"for this command. Instead you can also inspect and navigate structs available for a template engine "+
-			"by using the template '{{printf \"%#+v\" .}}' and navigating from that point forward, for instance with "+
-			"'{{printf \"%#+v\" .Instance}}' and so on",
+		&privateIp, "private", "r", false,
+		"Only print the private ip of the instance, if it has one. Same as --columns privateIp, "+
+			"skipping instances without a private ip",
 	)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		parsedTags, err := parseTags(tags)
-		if err != nil {
+		if err := filters.Parse(); err != nil {
 			return err
 		}
 
-		if printOptions.publicIp && printOptions.privateIp {
+		if publicIp && privateIp {
 			return fmt.Errorf("cannot have both private and public ip print options enabled")
 		}
+		if publicIp {
+			printOptions.Columns = []string{"publicIp"}
+		} else if privateIp {
+			printOptions.Columns = []string{"privateIp"}
+		}
 
-		printOptions.urlEncode = !noURLEncode
-
-		if templ != "" {
-			var err error
-			printOptions.template, err = template.New("user_input").Parse(templ)
-			if err != nil {
-				return fmt.Errorf("invalid template %q: %w", templ, err)
-			}
+		if err := printOptions.Validate(&table); err != nil {
+			return err
 		}
 
 		// We silence usage here instead of setting in the command struct declaration because it is
@@ -119,182 +68,106 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		// See more at https://github.com/spf13/cobra/issues/340
 		cmd.SilenceUsage = true
 
-		resolution, err := resolve(cmd.Context(), **awsCfg, instanceId, parsedTags)
+		resolution, err := resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, &filters)
 		if err != nil {
 			return fmt.Errorf("failed while fetching instances: %w", err)
 		}
-		printInstances(resolution, printOptions)
-		return nil
+		rows := []interface{}{}
+		for _, data := range instances(resolution) {
+			if (publicIp && data.Instance.PublicIpAddress == nil) || (privateIp && data.Instance.PrivateIpAddress == nil) {
+				continue
+			}
+			rows = append(rows, data)
+		}
+		return printOptions.Print(os.Stdout, &table, rows)
 	}
 
 	return &cmd
 }
 
-func resolve(ctx context.Context, cfg aws.Config, instanceId string, tags map[string]string) (*awst.AWS, error) {
-	fetchOpts := []ec2.FetchOption{}
-	if instanceId != "" {
-		fetchOpts = append(fetchOpts, ec2.WithInstanceIds(instanceId))
-	}
-	for k, v := range tags {
-		fetchOpts = append(fetchOpts, ec2.WithTag(k, v))
-	}
-	result, err := loader.LoadAWS(
+func resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, filters *Filters) (*awst.AWS, error) {
+	result, err := load(
 		ctx, cfg,
 		loader.WithServices("ec2"),
-		loader.WithEC2FetchOptions(fetchOpts...),
+		loader.WithEC2FetchOptions(filters.FetchOptions()...),
 	)
 	if err != nil {
 		return nil, err
 	}
+	filters.Filter(result)
 	return result, nil
 }
 
-func printInstances(aws *awst.AWS, printOptions printOptions) {
-	printHeader(printOptions)
-	for _, region := range aws.Regions {
-		for _, reservation := range region.EC2.Reservations {
-			for _, instance := range reservation.Instances {
-				printInstance(region.Region, reservation, instance, printOptions)
-			}
-		}
-	}
-}
-
-func printHeader(printOptions printOptions) {
-	if !printOptions.header || printOptions.template != nil {
-		return
-	}
-	if printOptions.privateIp {
-		fmt.Println("#privateIp")
-	} else if printOptions.publicIp {
-		fmt.Println("#privateIp")
-	} else {
-		fmt.Print("#region #instanceid #privateIp #publicIp ")
-		if printOptions.allTags || len(printOptions.tags) > 0 {
-			fmt.Println("#tags")
-		} else {
-			fmt.Println("#name")
-		}
-	}
-}
-
 type templateData struct {
 	Region      string
 	Reservation *ec2Types.Reservation
 	Instance    *ec2Types.Instance
 }
 
-func printInstance(region string, reservation ec2Types.Reservation, instance ec2Types.Instance, printOptions printOptions) {
-	if printOptions.template != nil {
-		buf := strings.Builder{}
-		data := templateData{
-			Region:      region,
-			Reservation: &reservation,
-			Instance:    &instance,
-		}
-		if err := printOptions.template.Execute(&buf, data); err != nil {
-			fmt.Printf("template execution error: %v\n", err)
-		}
-		fmt.Println(buf.String())
-		return
-	}
-
-	if printOptions.privateIp {
-		if instance.PrivateIpAddress != nil {
-			fmt.Println(safeString(instance.PrivateIpAddress))
-		}
-		return
-	}
-
-	if printOptions.publicIp {
-		if instance.PublicIpAddress != nil {
-			fmt.Println(safeString(instance.PublicIpAddress))
+func instances(aws *awst.AWS) []templateData {
+	result := []templateData{}
+	for _, region := range aws.Regions {
+		for reservationIdx := range region.EC2.Reservations {
+			reservation := &region.EC2.Reservations[reservationIdx]
+			for instanceIdx := range reservation.Instances {
+				result = append(result, templateData{
+					Region:      region.Region,
+					Reservation: reservation,
+					Instance:    &reservation.Instances[instanceIdx],
+				})
+			}
 		}
-		return
 	}
-
-	fmt.Printf(
-		"%s %s %s %s %s\n",
-		region,
-		safeString(instance.InstanceId),
-		safeString(instance.PrivateIpAddress),
-		safeString(instance.PublicIpAddress),
-		tagsString(&instance, printOptions),
-	)
+	return result
 }
 
-func safeString(s *string) string {
-	if s == nil || *s == "" {
-		return "<N/A>"
-	}
-	return *s
+func instanceOf(row interface{}) *ec2Types.Instance {
+	return row.(templateData).Instance
 }
 
-func parseTags(tags []string) (map[string]string, error) {
-	parsedTags := map[string]string{}
-	if len(tags) == 0 {
-		return parsedTags, nil
-	}
-	for _, kvpair := range tags {
-		kvpair = strings.TrimSpace(kvpair)
-		separatorIdx := strings.Index(kvpair, ":")
-		if separatorIdx == -1 {
-			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: missing \":\" separator", tags, kvpair)
-		}
-		key := kvpair[0:separatorIdx]
-		value := kvpair[separatorIdx+1:]
-		if len(key) == 0 {
-			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: no key", tags, kvpair)
-		}
-		if len(value) == 0 {
-			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: no value", tags, kvpair)
-		}
-		parsedTags[key] = value
-	}
-	return parsedTags, nil
-}
-
-func tagsString(instance *ec2Types.Instance, printOptions printOptions) string {
-	// if no tags were passed and print-all-tags is not enabled, just return the name
-	if len(printOptions.tags) == 0 && !printOptions.allTags {
-		name := ""
-		for _, tag := range instance.Tags {
-			if *tag.Key == "Name" {
-				name = *tag.Value
-				break
+var table = printer.Table{
+	Columns: []printer.Column{
+		{Name: "region", Value: func(row interface{}) interface{} { return row.(templateData).Region }},
+		{Name: "id", Value: func(row interface{}) interface{} { return instanceOf(row).InstanceId }},
+		{Name: "privateIp", Value: func(row interface{}) interface{} { return instanceOf(row).PrivateIpAddress }},
+		{Name: "publicIp", Value: func(row interface{}) interface{} { return instanceOf(row).PublicIpAddress }},
+		{Name: "name", Value: func(row interface{}) interface{} { return tagValue(instanceOf(row).Tags, "Name") }},
+		{Name: "state", Wide: true, Value: func(row interface{}) interface{} {
+			if instanceOf(row).State == nil {
+				return nil
 			}
-		}
-		if printOptions.urlEncode {
-			name = url.PathEscape(name)
-		}
-		return safeString(&name)
-	}
-
-	result := []string{}
-	appendTag := func(tag ec2Types.Tag) {
-		value := *tag.Value
-		if printOptions.urlEncode {
-			value = url.PathEscape(value)
-		}
-		result = append(result, *tag.Key+":"+value)
-	}
-
-	if printOptions.allTags {
-		for _, tag := range instance.Tags {
-			appendTag(tag)
-		}
-
-	} else {
-		tagMap := map[string]ec2Types.Tag{}
-		for _, tag := range instance.Tags {
-			tagMap[*tag.Key] = tag
-		}
-		for _, tagToFind := range printOptions.tags {
-			if tag, ok := tagMap[tagToFind]; ok {
-				appendTag(tag)
+			return instanceOf(row).State.Name
+		}},
+		{Name: "type", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).InstanceType }},
+		{Name: "az", Wide: true, Value: func(row interface{}) interface{} {
+			if instanceOf(row).Placement == nil {
+				return nil
 			}
+			return instanceOf(row).Placement.AvailabilityZone
+		}},
+		{Name: "vpc", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).VpcId }},
+		{Name: "subnet", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).SubnetId }},
+		{Name: "image", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).ImageId }},
+		{Name: "keyName", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).KeyName }},
+		{Name: "launchTime", Wide: true, Value: func(row interface{}) interface{} { return instanceOf(row).LaunchTime }},
+		{Name: "asg", Wide: true, Value: func(row interface{}) interface{} { return tagValue(instanceOf(row).Tags, ASGTag) }},
+	},
+	Tags: func(row interface{}) []printer.Tag {
+		tags := instanceOf(row).Tags
+		result := make([]printer.Tag, len(tags))
+		for idx, tag := range tags {
+			result[idx] = printer.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)}
+		}
+		return result
+	},
+	SortBy: []string{"region", "id"},
+}
+
+func tagValue(tags []ec2Types.Tag, key string) printer.TagValue {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return printer.TagValue(aws.ToString(tag.Value))
 		}
 	}
-
-	return strings.Join(result, ",")
+	return ""
 }
@@ -0,0 +1,301 @@
+package scp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/ssh"
+	"awstool/cmd/awstool/ec2/target"
+	"awstool/executor"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const failedInstancesExitCode = 2
+
+type options struct {
+	recursive   bool
+	publicIp    bool
+	useSSM      bool
+	all         bool
+	parallelism int
+}
+
+// endpoint is a source or destination of a copy. Remote endpoints have a target
+type endpoint struct {
+	target *target.Target
+	path   string
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "scp [flags] SOURCE... DESTINATION",
+		Short: "copies files to and from instances",
+		Long: "Copies files to and from instances with scp. Remote paths are written as [USER@]TARGET:PATH, " +
+			"where TARGET is resolved in the same way as ec2 ssh: an instance id, an ip, tags or a Name tag. " +
+			"Either the sources or the destination can be remote, but not both. When uploading with --all, " +
+			"files are copied to every instance matching the destination target in parallel",
+		Example: "  awstool ec2 scp web-1:/var/log/app.log .\n" +
+			"  awstool ec2 scp -r ./config ubuntu@i-0123456789abcdef0:/tmp/\n" +
+			"  awstool ec2 scp --all app.conf Role:api:/etc/app/",
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.MinimumNArgs(2)
+
+	options := options{}
+
+	cmd.Flags().BoolVarP(
+		&options.recursive, "recursive", "r", false,
+		"Copy directories recursively",
+	)
+
+	cmd.Flags().BoolVarP(
+		&options.publicIp, "public-ip", "P", false,
+		"Connect through the public ip of the instances instead of their private ip",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.useSSM, "ssm", false,
+		"Connect through AWS Systems Manager sessions, as in ec2 ssh --ssm",
+	)
+
+	cmd.Flags().BoolVarP(
+		&options.all, "all", "a", false,
+		"When uploading, copy to every instance matching the destination instead of picking one",
+	)
+
+	cmd.Flags().IntVarP(
+		&options.parallelism, "parallelism", "n", 10,
+		"How many instances to copy to at the same time with --all",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		endpoints := make([]endpoint, len(args))
+		for idx, arg := range args {
+			var err error
+			endpoints[idx], err = parseEndpoint(arg)
+			if err != nil {
+				return err
+			}
+		}
+		sources, destination := endpoints[:len(endpoints)-1], endpoints[len(endpoints)-1]
+
+		remote, err := remoteTarget(sources, destination)
+		if err != nil {
+			return err
+		}
+		if options.all && destination.target == nil {
+			return fmt.Errorf("--all can only be used when uploading, as downloads from many instances would overwrite each other")
+		}
+		if options.useSSM && options.publicIp {
+			return fmt.Errorf("cannot use both --ssm and --public-ip")
+		}
+		if options.parallelism <= 0 {
+			return fmt.Errorf("--parallelism must be positive")
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		var matches []target.Match
+		if options.all {
+			matches, err = target.Find(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, *remote)
+			if err != nil {
+				return fmt.Errorf("could not resolve instances %s: %w", remote.Query, err)
+			}
+			if len(matches) == 0 {
+				return fmt.Errorf("could not find any instance matching %q", remote.Query)
+			}
+		} else {
+			match, err := target.Resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, *remote)
+			if err != nil {
+				return fmt.Errorf("could not resolve instance %s: %w", remote.Query, err)
+			}
+			matches = []target.Match{*match}
+		}
+
+		users, err := ssh.DiscoverUsers(cmd, **awsCfg, matches, remote.User)
+		if err != nil {
+			return err
+		}
+
+		if !options.all {
+			match := matches[0]
+			args, err := scpArgs(cmd, match, users[*match.Instance.InstanceId], sources, destination, options)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "> Copying with %s in %s\n", *match.Instance.InstanceId, match.Region)
+			return run(cmd.Context(), args, os.Stdout, os.Stderr)
+		}
+		return copyToAll(cmd, matches, users, sources, destination, options)
+	}
+
+	return &cmd
+}
+
+// parseEndpoint parses an scp argument. As in scp, arguments with a colon before any slash are
+// remote, and since tag targets hold colons themselves the path starts after the last colon
+// before the first slash, eg Role:api:/etc/app
+func parseEndpoint(arg string) (endpoint, error) {
+	beforeSlash := arg
+	if idx := strings.Index(arg, "/"); idx != -1 {
+		beforeSlash = arg[:idx]
+	}
+	idx := strings.LastIndex(beforeSlash, ":")
+	if idx == -1 {
+		return endpoint{path: arg}, nil
+	}
+	remote, err := target.Parse(arg[:idx])
+	if err != nil {
+		return endpoint{}, err
+	}
+	return endpoint{target: &remote, path: arg[idx+1:]}, nil
+}
+
+// remoteTarget checks the copy goes in a single direction and returns the remote target
+func remoteTarget(sources []endpoint, destination endpoint) (*target.Target, error) {
+	if destination.target != nil {
+		for _, source := range sources {
+			if source.target != nil {
+				return nil, fmt.Errorf("copying between remote paths is not supported")
+			}
+		}
+		return destination.target, nil
+	}
+	var remote *target.Target
+	for _, source := range sources {
+		if source.target == nil {
+			return nil, fmt.Errorf("either all sources or the destination must be remote, as in [USER@]TARGET:PATH")
+		}
+		if remote != nil && *remote != *source.target {
+			return nil, fmt.Errorf("all remote sources must be on the same instance")
+		}
+		remote = source.target
+	}
+	return remote, nil
+}
+
+func scpArgs(cmd *cobra.Command, match target.Match, user string, sources []endpoint, destination endpoint, options options) ([]string, error) {
+	address, addressArgs, err := ssh.Address(cmd, match.Region, match.Instance, options.publicIp, options.useSSM)
+	if err != nil {
+		return nil, err
+	}
+	if user != "" {
+		address = user + "@" + address
+	}
+
+	args := addressArgs
+	if options.recursive {
+		args = append(args, "-r")
+	}
+	// sources share their backing array with the destination, and this is called for many
+	// instances in parallel, so appending to them in place is not safe
+	endpoints := append(append([]endpoint{}, sources...), destination)
+	for _, endpoint := range endpoints {
+		if endpoint.target == nil {
+			args = append(args, endpoint.path)
+		} else {
+			args = append(args, address+":"+endpoint.path)
+		}
+	}
+	return args, nil
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	log.Debugf("Running scp %s", strings.Join(args, " "))
+	execution := exec.CommandContext(ctx, "scp", args...)
+	execution.Stdin = os.Stdin
+	execution.Stdout = stdout
+	execution.Stderr = stderr
+	err := execution.Run()
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("scp finished with exit code %d", exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to launch scp: %w", err)
+	}
+	return nil
+}
+
+// copyToAll uploads to every instance in parallel, printing a summary of how it went for each
+func copyToAll(cmd *cobra.Command, matches []target.Match, users map[string]string, sources []endpoint, destination endpoint, options options) error {
+	ctx := cmd.Context()
+	executor := executor.NewExecutor(options.parallelism)
+	errs := make([]error, len(matches))
+
+	fmt.Fprintf(os.Stderr, "> Copying to %d instances\n", len(matches))
+	for idx := range matches {
+		idx := idx
+		match := matches[idx]
+		executor.Launch(ctx, func() {
+			args, err := scpArgs(cmd, match, users[*match.Instance.InstanceId], sources, destination, options)
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			// there is no one to answer prompts, and progress bars of many copies would mix up
+			args = append([]string{"-q", "-o", "BatchMode=yes"}, args...)
+			output := bytes.Buffer{}
+			if err := run(ctx, args, nil, &output); err != nil {
+				if message := strings.TrimSpace(output.String()); message != "" {
+					err = fmt.Errorf("%w: %s", err, strings.ReplaceAll(message, "\n", " "))
+				}
+				errs[idx] = err
+			}
+		})
+	}
+	if err := executor.Wait(ctx); err != nil {
+		return err
+	}
+
+	failed := 0
+	writer := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "#region\t#instance\t#name\t#result")
+	for idx, match := range matches {
+		status := "ok"
+		if errs[idx] != nil {
+			status = "error: " + errs[idx].Error()
+			failed++
+		}
+		name := target.Name(match.Instance)
+		if name == "" {
+			name = "<N/A>"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", match.Region, *match.Instance.InstanceId, name, status)
+	}
+	writer.Flush()
+
+	if failed > 0 {
+		return &failedInstancesErr{failed: failed, total: len(matches)}
+	}
+	return nil
+}
+
+type failedInstancesErr struct {
+	failed int
+	total  int
+}
+
+func (e *failedInstancesErr) ExitCode() int {
+	return failedInstancesExitCode
+}
+
+func (e *failedInstancesErr) Error() string {
+	return fmt.Sprintf("copy failed on %d of %d instances", e.failed, e.total)
+}
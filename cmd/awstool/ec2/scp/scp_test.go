@@ -0,0 +1,79 @@
+package scp
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected endpoint
+	}{
+		{"./local:file", endpoint{path: "./local:file"}},
+		{"/var/log/app.log", endpoint{path: "/var/log/app.log"}},
+		{"file.txt", endpoint{path: "file.txt"}},
+		{"web-1:/var/log/app.log", endpoint{target: &target.Target{Query: "web-1"}, path: "/var/log/app.log"}},
+		{"ubuntu@i-0123456789abcdef0:", endpoint{target: &target.Target{User: "ubuntu", Query: "i-0123456789abcdef0"}}},
+		{"Role:api,Env:prod:/etc/app/", endpoint{target: &target.Target{Query: "Role:api,Env:prod"}, path: "/etc/app/"}},
+		{"Role:api:notes.txt", endpoint{target: &target.Target{Query: "Role:api"}, path: "notes.txt"}},
+	}
+	for _, test := range tests {
+		result, err := parseEndpoint(test.arg)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", test.arg, err)
+			continue
+		}
+		if result.path != test.expected.path {
+			t.Errorf("parsed %q with path %q, expected %q", test.arg, result.path, test.expected.path)
+		}
+		if (result.target == nil) != (test.expected.target == nil) ||
+			(result.target != nil && *result.target != *test.expected.target) {
+			t.Errorf("parsed %q with target %+v, expected %+v", test.arg, result.target, test.expected.target)
+		}
+	}
+}
+
+func TestScpArgsConcurrently(t *testing.T) {
+	// sources and destination are split from the same slice, as when parsing the command args
+	remote := &target.Target{Query: "Role:api"}
+	endpoints := []endpoint{{path: "a.conf"}, {path: "b.conf"}, {target: remote, path: "/etc/app/"}}
+	sources, destination := endpoints[:2], endpoints[2]
+
+	var wg sync.WaitGroup
+	results := make([][]string, 20)
+	errs := make([]error, len(results))
+	for idx := range results {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			match := target.Match{
+				Region:   "us-east-1",
+				Instance: &ec2Types.Instance{InstanceId: aws.String("i-1"), PrivateIpAddress: aws.String(fmt.Sprintf("10.0.0.%d", idx))},
+			}
+			results[idx], errs[idx] = scpArgs(&cobra.Command{}, match, "ubuntu", sources, destination, options{recursive: true})
+		}(idx)
+	}
+	wg.Wait()
+
+	for idx, args := range results {
+		if errs[idx] != nil {
+			t.Fatal(errs[idx])
+		}
+		expected := []string{"-r", "a.conf", "b.conf", fmt.Sprintf("ubuntu@10.0.0.%d:/etc/app/", idx)}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("expected args %v, got %v", expected, args)
+		}
+	}
+	if len(endpoints) != 3 || endpoints[2].target != remote {
+		t.Errorf("expected the parsed endpoints to be left untouched, got %+v", endpoints)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"strings"
 
 	"awstool/aws/ec2"
-	"awstool/loader"
+	"awstool/aws/ec2instanceconnect"
+	"awstool/aws/ssm"
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/target"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -17,21 +20,23 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type identifier struct {
-	user     string
-	instance string
-}
-
 func Command(awsCfg **aws.Config) *cobra.Command {
 	cmd := cobra.Command{
-		Use:           "ssh",
-		Short:         "connects to an instance via ssh",
+		Use:   "ssh [USER@]TARGET",
+		Short: "connects to an instance via ssh",
+		Long: "Connects to an instance via ssh. TARGET can be an instance id, a private or public ip, " +
+			"tag key/value pairs as in ec2 resolve --tags (eg Env:prod,Role:web) or the value of the Name " +
+			"tag, which accepts * and ? wildcards. Targets with a : are searched both as tags and as a " +
+			"Name. All regions are searched, and when multiple instances match a numbered list is shown " +
+			"to pick one from",
 		SilenceErrors: true,
 	}
 
 	cmd.Args = cobra.ExactArgs(1)
 
 	var publicIp bool
+	var useSSM bool
+	var instanceConnect bool
 
 	cmd.Flags().BoolVarP(
 		&publicIp, "public-ip", "P", false,
@@ -39,25 +44,56 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 			"Set this to use the public ip instead",
 	)
 
+	cmd.Flags().BoolVar(
+		&useSSM, "ssm", false,
+		"Connect through an AWS Systems Manager session instead of connecting to an ip of the instance. "+
+			"Works for instances in private subnets without a bastion or an open ssh port, as long as they "+
+			"run the ssm agent. Requires the session-manager-plugin to be installed",
+	)
+
+	cmd.Flags().BoolVar(
+		&instanceConnect, "instance-connect", false,
+		"Push a freshly generated ssh key to the instance with EC2 Instance Connect and use it to connect, "+
+			"instead of relying on keys already set up. The key is only valid for 60 seconds and is deleted "+
+			"once ssh exits. Requires a user, either passed in or discovered, and the instance to run the "+
+			"Instance Connect agent. Can be combined with --ssm",
+	)
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		identifier, err := parseIdentifier(args[0])
+		identifier, err := target.Parse(args[0])
 		if err != nil {
 			return err
 		}
 
+		if useSSM && publicIp {
+			return fmt.Errorf("cannot use both --ssm and --public-ip")
+		}
+		if useSSM {
+			if err := ssm.CheckPlugin(); err != nil {
+				return err
+			}
+		}
+
 		// We silence usage here instead of setting in the command struct declaration because it is
 		// only at this point forward that we want to not display the usage when an error occurs,
 		// as it will be an execution error, not a parsing/usage error
 		// See more at https://github.com/spf13/cobra/issues/340
 		cmd.SilenceUsage = true
 
-		region, instance, err := resolve(cmd.Context(), **awsCfg, identifier.instance)
+		match, err := target.Resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, identifier)
 		if err != nil {
-			return fmt.Errorf("could not resolve instance with id %s: %w", identifier.instance, err)
+			return fmt.Errorf("could not resolve instance %s: %w", identifier.Query, err)
 		}
+		region, instance := match.Region, match.Instance
 
-		if identifier.user == "" {
-			user, err := discoverUser(cmd.Context(), **awsCfg, *instance.ImageId)
+		if identifier.User == "" && awstcmd.FromDump(cmd) != "" {
+			// user discovery needs to describe the instance image, which is not part of dumps
+			log.Warnf(
+				"Cannot discover user for image %s when working from a dump, proceeding with ssh defaults",
+				*instance.ImageId,
+			)
+		} else if identifier.User == "" {
+			user, err := DiscoverUser(cmd.Context(), **awsCfg, *instance.ImageId)
 			if err != nil {
 				return fmt.Errorf("failed to discover user for image %s: %w", *instance.ImageId, err)
 			}
@@ -67,12 +103,32 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 					*instance.ImageId,
 				)
 			}
-			identifier.user = user
+			identifier.User = user
 		} else {
-			log.Debugf("User %s passed in, so not trying to discover the user automatically", identifier.user)
+			log.Debugf("User %s passed in, so not trying to discover the user automatically", identifier.User)
 		}
 
-		if err = ssh(cmd.Context(), identifier.user, region, instance, publicIp); err != nil {
+		var sshArgs []string
+		if instanceConnect {
+			if identifier.User == "" {
+				return fmt.Errorf("--instance-connect requires a user, pass one in with user@%s", identifier.Query)
+			}
+			cfg := (*awsCfg).Copy()
+			cfg.Region = region
+			keyPath, cleanup, err := ec2instanceconnect.PushEphemeralKey(cmd.Context(), cfg, instance, identifier.User)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			sshArgs = append(sshArgs, "-i", keyPath, "-o", "IdentitiesOnly=yes")
+		}
+		address, addressArgs, err := Address(cmd, region, instance, publicIp, useSSM)
+		if err != nil {
+			return err
+		}
+		sshArgs = append(sshArgs, addressArgs...)
+
+		if err = ssh(cmd.Context(), identifier.User, region, instance, address, sshArgs...); err != nil {
 			exitErr := &exec.ExitError{}
 			if errors.As(err, &exitErr) {
 				return fmt.Errorf("ssh finished with exit code %d", exitErr.ExitCode())
@@ -85,43 +141,31 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 	return &cmd
 }
 
-func resolve(ctx context.Context, cfg aws.Config, instanceId string) (string, *ec2Types.Instance, error) {
-	result, err := loader.LoadAWS(
-		ctx, cfg,
-		loader.WithServices("ec2"),
-		loader.WithEC2FetchOptions(ec2.WithInstanceIds(instanceId)),
-	)
-	if err != nil {
-		return "", nil, err
-	}
-	instances := []ec2Types.Instance{}
-	instanceRegion := map[string]string{}
-	for _, region := range result.Regions {
-		for _, reservation := range region.EC2.Reservations {
-			for _, instance := range reservation.Instances {
-				instances = append(instances, instance)
-				instanceRegion[*instance.InstanceId] = region.Region
-			}
+// Address returns where ssh should connect to reach the instance: its private ip by default, its
+// public ip or its id when tunneling through ssm, along with the ssh args needed for that
+func Address(cmd *cobra.Command, region string, instance *ec2Types.Instance, publicIp bool, useSSM bool) (string, []string, error) {
+	switch {
+	case useSSM:
+		proxyCommand, err := SSMProxyCommandLine(cmd, region)
+		if err != nil {
+			return "", nil, err
 		}
-	}
-	if len(instances) == 0 {
-		return "", nil, fmt.Errorf("could not find instance %q", instanceId)
-	}
-	if len(instances) > 1 {
-		instancesMsg := make([]string, len(instances))
-		for idx, instance := range instances {
-			instancesMsg[idx] = *instance.InstanceId + " in " + instanceRegion[*instance.InstanceId]
+		return *instance.InstanceId, []string{"-o", "ProxyCommand=" + proxyCommand}, nil
+	case publicIp:
+		if instance.PublicIpAddress == nil {
+			return "", nil, fmt.Errorf("instance %s has no public ip", *instance.InstanceId)
 		}
-		return "", nil, fmt.Errorf("found multiple instances with id %q: %v. This is unusual and should not happen", instanceId, instancesMsg)
+		return *instance.PublicIpAddress, nil, nil
+	default:
+		if instance.PrivateIpAddress == nil {
+			return "", nil, fmt.Errorf("instance %s has no private ip", *instance.InstanceId)
+		}
+		return *instance.PrivateIpAddress, nil, nil
 	}
-	return instanceRegion[*instances[0].InstanceId], &instances[0], nil
 }
 
-func ssh(ctx context.Context, user string, region string, instance *ec2Types.Instance, usePublicIp bool) error {
-	address := *instance.PrivateIpAddress
-	if usePublicIp {
-		address = *instance.PublicIpAddress
-	}
+// ssh connects to the instance at the given address, passing any extra args to ssh
+func ssh(ctx context.Context, user string, region string, instance *ec2Types.Instance, address string, args ...string) error {
 	userMsg := "user " + user
 	identifier := user + "@" + address
 	if user == "" {
@@ -138,7 +182,7 @@ func ssh(ctx context.Context, user string, region string, instance *ec2Types.Ins
 		identifier,
 	)
 
-	execution := exec.CommandContext(ctx, "ssh", identifier)
+	execution := exec.CommandContext(ctx, "ssh", append(args, identifier)...)
 	execution.Stdin = os.Stdin
 	execution.Stderr = os.Stderr
 	execution.Stdout = os.Stdout
@@ -149,7 +193,45 @@ func ssh(ctx context.Context, user string, region string, instance *ec2Types.Ins
 	return execution.Wait()
 }
 
-func discoverUser(ctx context.Context, cfg aws.Config, imageId string) (string, error) {
+// DiscoverUsers finds the user to log in with for each instance, keyed by instance id. When a
+// user is passed in it is used for all instances, otherwise users are discovered once per image
+func DiscoverUsers(cmd *cobra.Command, cfg aws.Config, matches []target.Match, user string) (map[string]string, error) {
+	result := map[string]string{}
+	if user != "" {
+		for _, match := range matches {
+			result[*match.Instance.InstanceId] = user
+		}
+		return result, nil
+	}
+	if awstcmd.FromDump(cmd) != "" {
+		// user discovery needs to describe the instance images, which are not part of dumps
+		log.Warnf("Cannot discover users when working from a dump, proceeding with ssh defaults")
+		return result, nil
+	}
+
+	imageUsers := map[string]string{}
+	for _, match := range matches {
+		imageId := aws.ToString(match.Instance.ImageId)
+		imageUser, ok := imageUsers[imageId]
+		if !ok {
+			var err error
+			imageUser, err = DiscoverUser(cmd.Context(), cfg, imageId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to discover user for image %s: %w", imageId, err)
+			}
+			if imageUser == "" {
+				log.Warnf("Could not discover user for image %s, proceeding with ssh defaults", imageId)
+			}
+			imageUsers[imageId] = imageUser
+		}
+		result[*match.Instance.InstanceId] = imageUser
+	}
+	return result, nil
+}
+
+// DiscoverUser guesses the default user of an image from its location, eg ubuntu for Ubuntu
+// images. An empty user is returned when it cannot be guessed
+func DiscoverUser(ctx context.Context, cfg aws.Config, imageId string) (string, error) {
 	image, err := ec2.GetImage(ctx, cfg, imageId)
 	if err != nil {
 		return "", err
@@ -196,16 +278,3 @@ func discoverUser(ctx context.Context, cfg aws.Config, imageId string) (string,
 	}
 	return "", nil
 }
-
-func parseIdentifier(idStr string) (identifier, error) {
-	idx := strings.Index(idStr, "@")
-	if idx == 0 {
-		return identifier{}, fmt.Errorf("invalid identifier %q: user must value a value or not be set", idStr)
-	}
-	result := identifier{instance: idStr}
-	if idx > 0 {
-		result.user = idStr[:idx]
-		result.instance = idStr[idx+1:]
-	}
-	return result, nil
-}
@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	awst "awstool/aws"
+	"awstool/aws/ssm"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// SSMProxyCommand is used by ssh as a ProxyCommand when connecting with --ssm. It tunnels the
+// ssh connection through a session manager session, so it is not meant to be called directly
+func SSMProxyCommand(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:           "ssm-proxy INSTANCE_ID PORT",
+		Short:         "tunnels stdin/stdout to a port of an instance through a session manager session",
+		Hidden:        true,
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.ExactArgs(2)
+
+	var region string
+	cmd.Flags().StringVar(&region, "region", "", "Region of the instance")
+	cmd.MarkFlagRequired("region")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		cfg := **awsCfg
+		cfg.Region = region
+		return ssm.StartSession(
+			cmd.Context(), cfg, args[0], ssm.SSHDocument,
+			map[string][]string{"portNumber": {args[1]}},
+		)
+	}
+
+	return &cmd
+}
+
+// credentialFlags maps the root flags holding static credentials to the environment variables
+// that can be used instead of them
+var credentialFlags = map[string]string{
+	"access-key-id":     awst.AccessKeyIdEnv,
+	"secret-access-key": awst.SecretAccessKeyEnv,
+	"session-token":     awst.SessionTokenEnv,
+}
+
+// CredentialFlagsSet tells whether static credentials were passed as root flags
+func CredentialFlagsSet(cmd *cobra.Command) bool {
+	for name := range credentialFlags {
+		if flag := cmd.Root().PersistentFlags().Lookup(name); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// SSMProxyCommandLine builds the ssh ProxyCommand that runs this same program with ssm-proxy. Root
+// flags that were set (eg --profile) are passed along, so the session uses the same config. Static
+// credentials are never put in the command line, where they would show in ps and ssh -v output:
+// they are exported as environment variables instead, which ssh passes on to ProxyCommand
+func SSMProxyCommandLine(cmd *cobra.Command, region string) (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the path of this program: %w", err)
+	}
+	if CredentialFlagsSet(cmd) {
+		// all of them are exported, so none is mixed up with values already in the environment
+		for name, env := range credentialFlags {
+			value := ""
+			if flag := cmd.Root().PersistentFlags().Lookup(name); flag != nil {
+				value = flag.Value.String()
+			}
+			if err := os.Setenv(env, value); err != nil {
+				return "", fmt.Errorf("failed to export %s: %w", env, err)
+			}
+		}
+	}
+	args := []string{executable}
+	cmd.Root().PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		// offline mode and recording only apply to the current command
+		if !flag.Changed || flag.Name == "from-dump" || flag.Name == "record-http" {
+			return
+		}
+		if _, ok := credentialFlags[flag.Name]; ok {
+			return
+		}
+		if flag.Value.Type() == "stringToString" {
+			args = append(args, "--"+flag.Name+"="+strings.Trim(flag.Value.String(), "[]"))
+			return
+		}
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	})
+	args = append(args, "ec2", "ssm-proxy", "--region", region, "%h", "%p")
+
+	quoted := make([]string, len(args))
+	for idx, arg := range args {
+		quoted[idx] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// shellQuote quotes arguments for the shell ssh runs ProxyCommand with. ssh tokens like %h are
+// left untouched, as ssh expands them before running the command
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,55 @@
+package ssh
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSSMProxyCommandLine(t *testing.T) {
+	// t.Setenv restores the environment once the test is done
+	t.Setenv(awst.AccessKeyIdEnv, "")
+	t.Setenv(awst.SecretAccessKeyEnv, "")
+	t.Setenv(awst.SessionTokenEnv, "stale-token")
+
+	root := &cobra.Command{Use: "awstool"}
+	for _, name := range []string{"profile", "access-key-id", "secret-access-key", "session-token"} {
+		root.PersistentFlags().String(name, "", "")
+	}
+	sub := &cobra.Command{Use: "ssh", Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(sub)
+	root.SetArgs([]string{"ssh", "--profile", "dev", "--access-key-id", "AKID", "--secret-access-key", "s3cr3t"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := SSMProxyCommandLine(sub, "eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(line, " --profile=dev ec2 ssm-proxy --region eu-west-1 %h %p") {
+		t.Errorf("unexpected command line %q", line)
+	}
+	for _, secret := range []string{"AKID", "s3cr3t", "access-key", "secret-access"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("expected credentials to be left out of the command line, got %q", line)
+		}
+	}
+
+	// credentials are passed to ssm-proxy through the environment, including the empty session
+	// token so a stale one is not picked up
+	expected := map[string]string{
+		awst.AccessKeyIdEnv:     "AKID",
+		awst.SecretAccessKeyEnv: "s3cr3t",
+		awst.SessionTokenEnv:    "",
+	}
+	for env, value := range expected {
+		if got := os.Getenv(env); got != value {
+			t.Errorf("expected %s to be %q, got %q", env, value, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package sshconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	awst "awstool/aws"
+	"awstool/aws/ec2"
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/resolve"
+	"awstool/cmd/awstool/ec2/ssh"
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	beginMarker = "# BEGIN awstool ec2 ssh-config"
+	endMarker   = "# END awstool ec2 ssh-config"
+)
+
+type options struct {
+	user        string
+	publicIp    bool
+	useSSM      bool
+	proxyJump   string
+	aliasPrefix string
+}
+
+// host is a Host block of the generated config
+type host struct {
+	aliases      []string
+	hostName     string
+	user         string
+	proxyJump    string
+	proxyCommand string
+	comment      string
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ssh-config",
+		Short: "generates ssh config Host blocks for ec2 instances",
+		Long: "Generates ssh config Host blocks for ec2 instances, so plain ssh, scp, rsync and IDE remote " +
+			"tooling can connect to them by Name tag or instance id. Instances with duplicate names are only " +
+			"aliased by their id. By default the config is printed, with --file the generated section of that " +
+			"file is replaced, leaving the rest of it untouched, so it can be regenerated any time",
+		Example: "  awstool ec2 ssh-config --tags Env:production --file ~/.ssh/config.d/aws\n" +
+			"  # and in ~/.ssh/config: Include config.d/aws",
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.NoArgs
+
+	filters := resolve.Filters{}
+	var file string
+	options := options{}
+
+	filters.AddFlags(cmd.Flags())
+
+	cmd.Flags().StringVarP(
+		&file, "file", "f", "",
+		"Update the generated section of this file instead of printing the config. The file is created "+
+			"if it does not exist",
+	)
+
+	cmd.Flags().StringVarP(
+		&options.user, "user", "l", "",
+		"Log in with this user. By default the user is discovered from the image of each instance",
+	)
+
+	cmd.Flags().BoolVarP(
+		&options.publicIp, "public-ip", "P", false,
+		"Connect through the public ip of the instances instead of their private ip. "+
+			"Instances without a public ip are skipped",
+	)
+
+	cmd.Flags().StringVarP(
+		&options.proxyJump, "proxy-jump", "J", "",
+		"Connect through this bastion host, as in ssh -J",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.useSSM, "ssm", false,
+		"Connect through AWS Systems Manager sessions, as in ec2 ssh --ssm. Credentials are taken from "+
+			"the profile or the environment when connecting, as they are never written to the ssh config",
+	)
+
+	cmd.Flags().StringVar(
+		&options.aliasPrefix, "alias-prefix", "",
+		"Prefix host aliases with this, eg aws- to connect with ssh aws-web-1",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := filters.Parse(); err != nil {
+			return err
+		}
+		if options.useSSM && (options.publicIp || options.proxyJump != "") {
+			return fmt.Errorf("--ssm cannot be used with --public-ip or --proxy-jump")
+		}
+		// the generated config is used long after this command exits, so credentials passed as
+		// flags would have to be written to it in plain text
+		if options.useSSM && ssh.CredentialFlagsSet(cmd) {
+			return fmt.Errorf(
+				"--ssm cannot be used with --access-key-id, --secret-access-key or --session-token, as they " +
+					"would be written to the ssh config: use a profile or export them as " +
+					awst.AccessKeyIdEnv + ", " + awst.SecretAccessKeyEnv + " and " + awst.SessionTokenEnv + " instead",
+			)
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		fetchOpts := filters.FetchOptions()
+		if !filters.HasState() {
+			// terminated instances linger for a while, and their names would clash with their replacements
+			fetchOpts = append(fetchOpts, ec2.WithFilter("instance-state-name", "pending", "running", "stopping", "stopped"))
+		}
+		matches, err := target.FindInstances(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, fetchOpts...)
+		if err != nil {
+			return fmt.Errorf("failed while fetching instances: %w", err)
+		}
+		matches = filters.FilterMatches(matches)
+
+		users, err := ssh.DiscoverUsers(cmd, **awsCfg, matches, options.user)
+		if err != nil {
+			return err
+		}
+
+		hosts, err := buildHosts(cmd, matches, users, options)
+		if err != nil {
+			return err
+		}
+		config := render(hosts)
+
+		if file == "" {
+			fmt.Print(config)
+			return nil
+		}
+		return updateFile(file, config)
+	}
+
+	return &cmd
+}
+
+func buildHosts(cmd *cobra.Command, matches []target.Match, users map[string]string, options options) ([]host, error) {
+	names := map[string]int{}
+	for _, match := range matches {
+		if name := alias(target.Name(match.Instance)); name != "" {
+			names[name]++
+		}
+	}
+
+	hosts := []host{}
+	for _, match := range matches {
+		instance := match.Instance
+		instanceId := aws.ToString(instance.InstanceId)
+		name := alias(target.Name(instance))
+
+		h := host{
+			user:      users[instanceId],
+			proxyJump: options.proxyJump,
+			comment:   fmt.Sprintf("%s in %s", instanceId, match.Region),
+		}
+		if name != "" {
+			h.comment = fmt.Sprintf("%s (%s) in %s", instanceId, target.Name(instance), match.Region)
+			if names[name] > 1 {
+				log.Warnf("Name %q is used by %d instances, only aliasing %s by its id", name, names[name], instanceId)
+			} else {
+				h.aliases = append(h.aliases, options.aliasPrefix+name)
+			}
+		}
+		h.aliases = append(h.aliases, options.aliasPrefix+instanceId)
+
+		switch {
+		case options.useSSM:
+			proxyCommand, err := ssh.SSMProxyCommandLine(cmd, match.Region)
+			if err != nil {
+				return nil, err
+			}
+			h.hostName = instanceId
+			h.proxyCommand = proxyCommand
+		case options.publicIp:
+			if instance.PublicIpAddress == nil {
+				log.Warnf("Instance %s has no public ip, skipping it", instanceId)
+				continue
+			}
+			h.hostName = *instance.PublicIpAddress
+		default:
+			if instance.PrivateIpAddress == nil {
+				log.Warnf("Instance %s has no private ip, skipping it", instanceId)
+				continue
+			}
+			h.hostName = *instance.PrivateIpAddress
+		}
+		hosts = append(hosts, h)
+	}
+
+	// the output should only change when instances change, so it is sorted by alias instead of
+	// the order instances are found in
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return hosts[i].aliases[0] < hosts[j].aliases[0]
+	})
+	return hosts, nil
+}
+
+// alias turns a Name tag into a valid ssh Host alias: no whitespace, control characters, quotes
+// or wildcards
+func alias(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			return '-'
+		case r == '"' || r == '\'' || r == '*' || r == '?' || r == '!' || r == ',':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+}
+
+// sanitize replaces control characters with spaces. Tag values and image locations can contain
+// newlines, which would otherwise let whoever controls them add directives to the ssh config
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+}
+
+func render(hosts []host) string {
+	builder := strings.Builder{}
+	builder.WriteString(beginMarker + "\n")
+	builder.WriteString("# Generated by awstool ec2 ssh-config, changes to this section will be overwritten\n")
+	for _, h := range hosts {
+		builder.WriteString("\n# " + sanitize(h.comment) + "\n")
+		builder.WriteString("Host " + sanitize(strings.Join(h.aliases, " ")) + "\n")
+		builder.WriteString("    HostName " + sanitize(h.hostName) + "\n")
+		if h.user != "" {
+			builder.WriteString("    User " + sanitize(h.user) + "\n")
+		}
+		if h.proxyJump != "" {
+			builder.WriteString("    ProxyJump " + sanitize(h.proxyJump) + "\n")
+		}
+		if h.proxyCommand != "" {
+			builder.WriteString("    ProxyCommand " + sanitize(h.proxyCommand) + "\n")
+		}
+	}
+	builder.WriteString(endMarker + "\n")
+	return builder.String()
+}
+
+// updateFile replaces the generated section of the file with the new config, or appends it if
+// the file has none. The file is only written when its content changes
+func updateFile(path string, config string) error {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	updated, err := replaceSection(current, config)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	if bytes.Equal(current, updated) {
+		log.Infof("%s is already up to date", path)
+		return nil
+	}
+
+	mode := fs.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path, updated, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Infof("Updated %s", path)
+	return nil
+}
+
+func replaceSection(content []byte, section string) ([]byte, error) {
+	text := string(content)
+	begin := strings.Index(text, beginMarker)
+	if begin == -1 {
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		if text != "" {
+			text += "\n"
+		}
+		return []byte(text + section), nil
+	}
+	end := strings.Index(text[begin:], endMarker)
+	if end == -1 {
+		return nil, fmt.Errorf("found %q but not %q, fix the file by hand", beginMarker, endMarker)
+	}
+	end += begin + len(endMarker)
+	if end < len(text) && text[end] == '\n' {
+		end++
+	}
+	return []byte(text[:begin] + section + text[end:]), nil
+}
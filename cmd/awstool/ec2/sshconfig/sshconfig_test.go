@@ -0,0 +1,79 @@
+package sshconfig
+
+import (
+	"strings"
+	"testing"
+
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+)
+
+func TestReplaceSection(t *testing.T) {
+	section := beginMarker + "\nHost web\n" + endMarker + "\n"
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"empty file", "", section},
+		{"no section", "Host foo\n    HostName foo", "Host foo\n    HostName foo\n\n" + section},
+		{
+			"existing section",
+			"Host foo\n\n" + beginMarker + "\nHost old\n" + endMarker + "\nHost bar\n",
+			"Host foo\n\n" + section + "Host bar\n",
+		},
+	}
+	for _, test := range tests {
+		result, err := replaceSection([]byte(test.content), section)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if string(result) != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.name, test.expected, string(result))
+		}
+		// updating again with the same section must not change anything
+		again, err := replaceSection(result, section)
+		if err != nil || string(again) != string(result) {
+			t.Errorf("%s: update is not idempotent, got %q", test.name, string(again))
+		}
+	}
+
+	if _, err := replaceSection([]byte(beginMarker+"\nHost old\n"), section); err == nil {
+		t.Errorf("expected an error for a section with no end marker")
+	}
+}
+
+func TestAlias(t *testing.T) {
+	if result := alias(" web server *1 "); result != "web-server-1" {
+		t.Errorf("unexpected alias %q", result)
+	}
+}
+
+func TestRenderNameWithNewline(t *testing.T) {
+	instance := &ec2Types.Instance{
+		InstanceId:       aws.String("i-1"),
+		PrivateIpAddress: aws.String("10.0.0.1"),
+		Tags:             []ec2Types.Tag{{Key: aws.String("Name"), Value: aws.String("web\nProxyCommand touch /tmp/pwned\r\n")}},
+	}
+	matches := []target.Match{{Region: "us-east-1", Instance: instance}}
+	hosts, err := buildHosts(&cobra.Command{}, matches, map[string]string{"i-1": "ubuntu\nProxyCommand id"}, options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := render(hosts)
+
+	for _, line := range strings.Split(config, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "ProxyCommand") {
+			t.Errorf("tag values must not be able to add directives, got line %q in\n%s", line, config)
+		}
+	}
+	if !strings.Contains(config, "\n# i-1 (web ProxyCommand touch /tmp/pwned  ) in us-east-1\n") {
+		t.Errorf("expected the Name to be kept in the comment on a single line, got\n%s", config)
+	}
+	if !strings.Contains(config, "\nHost web-ProxyCommand-touch-/tmp/pwned i-1\n") {
+		t.Errorf("unexpected aliases in\n%s", config)
+	}
+}
@@ -0,0 +1,240 @@
+// Package target finds the instance that commands like ec2 ssh should act on, from an instance
+// id, a Name tag, tag key/value pairs or an ip
+package target
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"awstool/aws/ec2"
+	awstcmd "awstool/cmd"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Target is what the user asked to act on, eg ubuntu@i-0123456789abcdef0
+type Target struct {
+	User  string
+	Query string
+}
+
+// Match is an instance matching a target
+type Match struct {
+	Region   string
+	Instance *ec2Types.Instance
+}
+
+var instanceIdRegexp = regexp.MustCompile(`^i-[0-9a-f]{8,17}$`)
+
+// Parse parses [user@]target, where target is one of:
+//   - an instance id, eg i-0123456789abcdef0
+//   - a private or public ip, eg 10.0.1.12
+//   - tag key/value pairs with the same syntax as ec2 resolve --tags, eg Env:prod|staging,Role:web.
+//     As Name tags can contain : too, these are also searched as a Name
+//   - a Name tag, eg web-1. Wildcards * and ? can be used, eg web-*
+func Parse(arg string) (Target, error) {
+	idx := strings.Index(arg, "@")
+	if idx == 0 {
+		return Target{}, fmt.Errorf("invalid target %q: user must have a value or not be set", arg)
+	}
+	result := Target{Query: arg}
+	if idx > 0 {
+		result.User = arg[:idx]
+		result.Query = arg[idx+1:]
+	}
+	if result.Query == "" {
+		return Target{}, fmt.Errorf("invalid target %q: missing instance", arg)
+	}
+	return result, nil
+}
+
+// activeStates filters out terminated instances, which linger for a while and would be ambiguous
+// with their replacements
+var activeStates = ec2.WithFilter("instance-state-name", "pending", "running", "stopping", "stopped")
+
+// FetchOptions converts the target query to the filters used to find matching instances. The
+// filters of a search are ANDed together, so targets that can be read in more than one way, eg
+// an ip that can be either private or public, get a search for each. Instances matching any of
+// them match the target
+func (t Target) FetchOptions() ([][]ec2.FetchOption, error) {
+	if instanceIdRegexp.MatchString(t.Query) {
+		return [][]ec2.FetchOption{{ec2.WithInstanceIds(t.Query)}}, nil
+	}
+
+	if net.ParseIP(t.Query) != nil {
+		// VPCs are not limited to private ranges, so the ip range tells nothing about which one it is
+		return [][]ec2.FetchOption{
+			{activeStates, ec2.WithFilter("private-ip-address", t.Query)},
+			{activeStates, ec2.WithFilter("ip-address", t.Query)},
+		}, nil
+	}
+	byName := []ec2.FetchOption{activeStates, ec2.WithTag("Name", t.Query)}
+	if strings.Contains(t.Query, ":") {
+		tags, err := awstcmd.ParseTags(strings.Split(t.Query, ","))
+		if err != nil {
+			// not valid as tags, eg web:, so it can only be a Name
+			return [][]ec2.FetchOption{byName}, nil
+		}
+		return [][]ec2.FetchOption{append([]ec2.FetchOption{activeStates}, awstcmd.TagFetchOptions(tags)...), byName}, nil
+	}
+	return [][]ec2.FetchOption{byName}, nil
+}
+
+// Find searches all regions for instances matching the target, sorted by region and id
+func Find(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, target Target) ([]Match, error) {
+	searches, err := target.FetchOptions()
+	if err != nil {
+		return nil, err
+	}
+	if len(searches) == 1 {
+		return FindInstances(ctx, load, cfg, searches[0]...)
+	}
+
+	// the API cannot OR searches together, and loading once per search would read a dump from
+	// stdin twice, so all active instances are loaded once and each search is matched locally
+	candidates, err := FindInstances(ctx, load, cfg, activeStates)
+	if err != nil {
+		return nil, err
+	}
+	result := []Match{}
+	for _, candidate := range candidates {
+		reservations := []ec2Types.Reservation{{Instances: []ec2Types.Instance{*candidate.Instance}}}
+		for _, options := range searches {
+			matched, err := ec2.FilterInstances(reservations, options...)
+			if err != nil {
+				return nil, err
+			}
+			if len(matched) > 0 {
+				result = append(result, candidate)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// FindInstances searches all regions for instances matching the fetch options, sorted by region
+// and id
+func FindInstances(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, options ...ec2.FetchOption) ([]Match, error) {
+	result, err := load(
+		ctx, cfg,
+		loader.WithServices("ec2"),
+		loader.WithEC2FetchOptions(options...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	matches := []Match{}
+	for _, region := range result.Regions {
+		for _, reservation := range region.EC2.Reservations {
+			for idx := range reservation.Instances {
+				matches = append(matches, Match{Region: region.Region, Instance: &reservation.Instances[idx]})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Region != matches[j].Region {
+			return matches[i].Region < matches[j].Region
+		}
+		return aws.ToString(matches[i].Instance.InstanceId) < aws.ToString(matches[j].Instance.InstanceId)
+	})
+	return matches, nil
+}
+
+// Resolve finds the single instance matching the target. When multiple instances match, the
+// user picks one from a numbered list if running on a terminal, otherwise an error listing them
+// is returned
+func Resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, target Target) (*Match, error) {
+	matches, err := Find(ctx, load, cfg, target)
+	if err != nil {
+		return nil, err
+	}
+	return Pick(target, matches, isTerminal(os.Stdin) && isTerminal(os.Stderr), os.Stdin, os.Stderr)
+}
+
+// Pick chooses one of the matches, prompting on in and out when interactive
+func Pick(target Target, matches []Match, interactive bool, in io.Reader, out io.Writer) (*Match, error) {
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("could not find any instance matching %q", target.Query)
+	case len(matches) == 1:
+		return &matches[0], nil
+	case !interactive:
+		lines := make([]string, len(matches))
+		for idx, match := range matches {
+			lines[idx] = "  " + Describe(match)
+		}
+		return nil, fmt.Errorf(
+			"found %d instances matching %q, use a more specific target:\n%s",
+			len(matches), target.Query, strings.Join(lines, "\n"),
+		)
+	}
+
+	fmt.Fprintf(out, "Found %d instances matching %q:\n", len(matches), target.Query)
+	for idx, match := range matches {
+		fmt.Fprintf(out, "%3d) %s\n", idx+1, Describe(match))
+	}
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Pick an instance [1-%d]: ", len(matches))
+		line, err := reader.ReadString('\n')
+		choice, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && choice >= 1 && choice <= len(matches) {
+			return &matches[choice-1], nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("no instance picked")
+		}
+		fmt.Fprintf(out, "Invalid choice %q\n", strings.TrimSpace(line))
+	}
+}
+
+// Describe summarizes an instance in a single line
+func Describe(match Match) string {
+	instance := match.Instance
+	state := ""
+	if instance.State != nil {
+		state = string(instance.State.Name)
+	}
+	return fmt.Sprintf(
+		"%s %s %s %s %s %s",
+		match.Region,
+		aws.ToString(instance.InstanceId),
+		orNA(aws.ToString(instance.PrivateIpAddress)),
+		orNA(aws.ToString(instance.PublicIpAddress)),
+		orNA(state),
+		orNA(Name(instance)),
+	)
+}
+
+// Name returns the Name tag of the instance, or an empty string if it has none
+func Name(instance *ec2Types.Instance) string {
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) == "Name" {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+func orNA(s string) string {
+	if s == "" {
+		return "<N/A>"
+	}
+	return s
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,177 @@
+package target
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	awst "awstool/aws"
+	"awstool/aws/ec2"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func instance(id string, privateIp string, publicIp string, state ec2Types.InstanceStateName, tags map[string]string) ec2Types.Instance {
+	result := ec2Types.Instance{
+		InstanceId:       aws.String(id),
+		PrivateIpAddress: aws.String(privateIp),
+		State:            &ec2Types.InstanceState{Name: state},
+	}
+	if publicIp != "" {
+		result.PublicIpAddress = aws.String(publicIp)
+	}
+	for key, value := range tags {
+		result.Tags = append(result.Tags, ec2Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
+
+func TestFetchOptions(t *testing.T) {
+	reservations := []ec2Types.Reservation{{Instances: []ec2Types.Instance{
+		instance("i-0000000000000001", "10.0.0.1", "54.1.1.1", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "web-1", "Env": "prod"}),
+		instance("i-0000000000000002", "10.0.0.2", "", ec2Types.InstanceStateNameStopped, map[string]string{"Name": "web-2", "Env": "dev"}),
+		instance("i-0000000000000003", "10.0.0.3", "", ec2Types.InstanceStateNameTerminated, map[string]string{"Name": "web-1", "Env": "prod"}),
+		instance("i-0000000000000004", "10.0.0.4", "", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "db-1", "Env": "prod"}),
+		instance("i-0000000000000005", "100.64.0.5", "", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "app:blue"}),
+	}}}
+
+	tests := []struct {
+		target   string
+		expected []string
+	}{
+		{"i-0000000000000003", []string{"i-0000000000000003"}},
+		{"ubuntu@web-1", []string{"i-0000000000000001"}},
+		{"web-*", []string{"i-0000000000000001", "i-0000000000000002"}},
+		{"Env:prod", []string{"i-0000000000000001", "i-0000000000000004"}},
+		{"Env:prod,Name:db-*", []string{"i-0000000000000004"}},
+		{"10.0.0.2", []string{"i-0000000000000002"}},
+		{"54.1.1.1", []string{"i-0000000000000001"}},
+		// shared address space and other non RFC1918 ranges can be used by VPCs too
+		{"100.64.0.5", []string{"i-0000000000000005"}},
+		{"app:blue", []string{"i-0000000000000005"}},
+		{"app:", []string{}},
+		{"nothing", []string{}},
+	}
+	for _, test := range tests {
+		target, err := Parse(test.target)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", test.target, err)
+		}
+		searches, err := target.FetchOptions()
+		if err != nil {
+			t.Fatalf("failed to build fetch options for %q: %v", test.target, err)
+		}
+		ids := []string{}
+		for _, options := range searches {
+			filtered, err := ec2.FilterInstances(reservations, options...)
+			if err != nil {
+				t.Fatalf("failed to filter instances for %q: %v", test.target, err)
+			}
+			for _, reservation := range filtered {
+				for _, instance := range reservation.Instances {
+					ids = append(ids, *instance.InstanceId)
+				}
+			}
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("target %q matched %v, expected %v", test.target, ids, test.expected)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dump := awst.New()
+	region := awst.NewRegion("us-east-1")
+	region.EC2.Reservations = []ec2Types.Reservation{{Instances: []ec2Types.Instance{
+		instance("i-0000000000000001", "10.0.0.1", "54.1.1.1", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "web-1", "Env": "prod"}),
+		instance("i-0000000000000002", "100.64.0.2", "", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "Env:prod"}),
+		instance("i-0000000000000003", "54.1.1.1", "", ec2Types.InstanceStateNameTerminated, map[string]string{"Env": "prod"}),
+	}}}
+	dump.Regions["us-east-1"] = region
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target   string
+		expected []string
+	}{
+		{"54.1.1.1", []string{"i-0000000000000001"}},
+		{"100.64.0.2", []string{"i-0000000000000002"}},
+		{"Env:prod", []string{"i-0000000000000001", "i-0000000000000002"}},
+		{"web-*", []string{"i-0000000000000001"}},
+	}
+	for _, test := range tests {
+		// as with --from-dump -, the dump can only be read once
+		stdin := bytes.NewReader(data)
+		calls := 0
+		load := func(ctx context.Context, cfg aws.Config, options ...loader.Option) (*awst.AWS, error) {
+			calls++
+			if calls > 1 {
+				return nil, fmt.Errorf("loaded %d times", calls)
+			}
+			return loader.LoadDump(stdin, options...)
+		}
+
+		matches, err := Find(context.Background(), load, aws.Config{}, Target{Query: test.target})
+		if err != nil {
+			t.Errorf("target %q: %v", test.target, err)
+			continue
+		}
+		ids := []string{}
+		for _, match := range matches {
+			ids = append(ids, *match.Instance.InstanceId)
+		}
+		if strings.Join(ids, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("target %q matched %v, expected %v", test.target, ids, test.expected)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	first := instance("i-1", "10.0.0.1", "", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "web"})
+	second := instance("i-2", "10.0.0.2", "", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "web"})
+	matches := []Match{{Region: "us-east-1", Instance: &first}, {Region: "eu-west-1", Instance: &second}}
+	target := Target{Query: "web"}
+
+	if _, err := Pick(target, nil, true, nil, nil); err == nil {
+		t.Errorf("expected an error when nothing matches")
+	}
+
+	_, err := Pick(target, matches, false, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "us-east-1 i-1") || !strings.Contains(err.Error(), "eu-west-1 i-2") {
+		t.Errorf("expected an error listing the matches, got %v", err)
+	}
+
+	out := bytes.Buffer{}
+	match, err := Pick(target, matches, true, strings.NewReader("9\n2\n"), &out)
+	if err != nil {
+		t.Fatalf("failed to pick: %v", err)
+	}
+	if *match.Instance.InstanceId != "i-2" {
+		t.Errorf("picked %s, expected i-2", *match.Instance.InstanceId)
+	}
+	if !strings.Contains(out.String(), `Invalid choice "9"`) {
+		t.Errorf("expected invalid choices to be reported, got %q", out.String())
+	}
+
+	if _, err := Pick(target, matches, true, strings.NewReader(""), &out); err == nil {
+		t.Errorf("expected an error when no choice is made")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, invalid := range []string{"@i-1", "ubuntu@"} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
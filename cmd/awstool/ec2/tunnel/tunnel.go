@@ -0,0 +1,266 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"awstool/aws/ec2instanceconnect"
+	"awstool/aws/ssm"
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ec2/ssh"
+	"awstool/cmd/awstool/ec2/target"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// reconnection delays are variables only so tests can shorten them
+var (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	// connections that stayed up this long are considered healthy, so reconnecting after they
+	// drop starts over from the minimum delay
+	stableConnection = time.Minute
+)
+
+// forward is a local port forwarded to a host and port reachable from the jump instance
+type forward struct {
+	localPort  int
+	remoteHost string
+	remotePort int
+}
+
+func (f forward) String() string {
+	return fmt.Sprintf("localhost:%d -> %s:%d", f.localPort, f.remoteHost, f.remotePort)
+}
+
+func (f forward) sshArg() string {
+	return fmt.Sprintf("%d:%s:%d", f.localPort, f.remoteHost, f.remotePort)
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "tunnel [USER@]TARGET -L [LOCAL_PORT:]HOST:PORT ...",
+		Short: "forwards local ports to hosts reachable from an instance",
+		Long: "Forwards local ports through a jump instance to hosts reachable from it, eg databases or " +
+			"elasticsearch domains in a VPC. TARGET is resolved in the same way as ec2 ssh. The tunnel is " +
+			"kept alive and reconnected when it drops, until interrupted",
+		Example: "  awstool ec2 tunnel Role:bastion -L 5432:mydb.abc123.us-east-1.rds.amazonaws.com:5432\n" +
+			"  awstool ec2 tunnel --ssm i-0123456789abcdef0 -L redis.internal:6379 -L 9200:vpc-logs.es.internal:443",
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	var forwardSpecs []string
+	var publicIp bool
+	var useSSM bool
+	var instanceConnect bool
+	var noReconnect bool
+
+	cmd.Flags().StringArrayVarP(
+		&forwardSpecs, "forward", "L", []string{},
+		"Forward a local port to HOST:PORT, as seen from the instance. The local port defaults to PORT. "+
+			"Can be repeated",
+	)
+
+	cmd.Flags().BoolVarP(
+		&publicIp, "public-ip", "P", false,
+		"Connect through the public ip of the instance instead of its private ip",
+	)
+
+	cmd.Flags().BoolVar(
+		&useSSM, "ssm", false,
+		"Connect through an AWS Systems Manager session, as in ec2 ssh --ssm",
+	)
+
+	cmd.Flags().BoolVar(
+		&instanceConnect, "instance-connect", false,
+		"Push a freshly generated ssh key with EC2 Instance Connect on every connection, as in ec2 ssh --instance-connect",
+	)
+
+	cmd.Flags().BoolVar(
+		&noReconnect, "no-reconnect", false,
+		"Exit when the tunnel drops instead of reconnecting",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		identifier, err := target.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		if len(forwardSpecs) == 0 {
+			return fmt.Errorf("at least one --forward is required")
+		}
+		forwards := make([]forward, len(forwardSpecs))
+		for idx, spec := range forwardSpecs {
+			forwards[idx], err = parseForward(spec)
+			if err != nil {
+				return err
+			}
+		}
+		if useSSM && publicIp {
+			return fmt.Errorf("cannot use both --ssm and --public-ip")
+		}
+		if useSSM {
+			if err := ssm.CheckPlugin(); err != nil {
+				return err
+			}
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		match, err := target.Resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, identifier)
+		if err != nil {
+			return fmt.Errorf("could not resolve instance %s: %w", identifier.Query, err)
+		}
+		users, err := ssh.DiscoverUsers(cmd, **awsCfg, []target.Match{*match}, identifier.User)
+		if err != nil {
+			return err
+		}
+		user := users[*match.Instance.InstanceId]
+		if instanceConnect && user == "" {
+			return fmt.Errorf("--instance-connect requires a user, pass one in with user@%s", identifier.Query)
+		}
+
+		address, addressArgs, err := ssh.Address(cmd, match.Region, match.Instance, publicIp, useSSM)
+		if err != nil {
+			return err
+		}
+		if user != "" {
+			address = user + "@" + address
+		}
+		sshArgs := []string{
+			"-N",
+			// fail instead of running without some of the forwards, eg when a local port is taken
+			"-o", "ExitOnForwardFailure=yes",
+			// detect dead connections, so they get reconnected
+			"-o", "ServerAliveInterval=15",
+			"-o", "ServerAliveCountMax=3",
+		}
+		sshArgs = append(sshArgs, addressArgs...)
+		for _, forward := range forwards {
+			sshArgs = append(sshArgs, "-L", forward.sshArg())
+		}
+
+		for _, forward := range forwards {
+			fmt.Fprintf(os.Stderr, "> Forwarding %s through %s in %s\n", forward, *match.Instance.InstanceId, match.Region)
+		}
+
+		connect := func(ctx context.Context) error {
+			args := sshArgs
+			if instanceConnect {
+				cfg := (*awsCfg).Copy()
+				cfg.Region = match.Region
+				keyPath, cleanup, err := ec2instanceconnect.PushEphemeralKey(ctx, cfg, match.Instance, user)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				args = append([]string{"-i", keyPath, "-o", "IdentitiesOnly=yes"}, args...)
+			}
+			return run(ctx, append(args, address))
+		}
+
+		if noReconnect {
+			err := connect(cmd.Context())
+			if cmd.Context().Err() != nil {
+				return nil
+			}
+			return err
+		}
+		keepAlive(cmd.Context(), connect)
+		return nil
+	}
+
+	return &cmd
+}
+
+// keepAlive connects again every time the connection drops, waiting longer between attempts
+// that fail in a row, until the context is cancelled
+func keepAlive(ctx context.Context, connect func(context.Context) error) {
+	delay := minReconnectDelay
+	for {
+		startedAt := time.Now()
+		err := connect(ctx)
+		if ctx.Err() != nil {
+			log.Debugf("Tunnel closed as requested")
+			return
+		}
+		if time.Since(startedAt) >= stableConnection {
+			delay = minReconnectDelay
+		}
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		log.Warnf("Tunnel dropped (%v), reconnecting in %v", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	log.Debugf("Running ssh %s", strings.Join(args, " "))
+	execution := exec.CommandContext(ctx, "ssh", args...)
+	// stdin is kept so ssh can still ask about unknown host keys
+	execution.Stdin = os.Stdin
+	execution.Stdout = os.Stdout
+	execution.Stderr = os.Stderr
+	err := execution.Run()
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("ssh finished with exit code %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// parseForward parses [LOCAL_PORT:]HOST:PORT
+func parseForward(spec string) (forward, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return forward{}, fmt.Errorf("invalid forward %q, expected [LOCAL_PORT:]HOST:PORT", spec)
+	}
+	result := forward{remoteHost: parts[len(parts)-2]}
+	if result.remoteHost == "" {
+		return forward{}, fmt.Errorf("invalid forward %q: missing host", spec)
+	}
+	var err error
+	result.remotePort, err = parsePort(parts[len(parts)-1])
+	if err != nil {
+		return forward{}, fmt.Errorf("invalid forward %q: %w", spec, err)
+	}
+	result.localPort = result.remotePort
+	if len(parts) == 3 {
+		result.localPort, err = parsePort(parts[0])
+		if err != nil {
+			return forward{}, fmt.Errorf("invalid forward %q: %w", spec, err)
+		}
+	}
+	return result, nil
+}
+
+func parsePort(port string) (int, error) {
+	result, err := strconv.Atoi(port)
+	if err != nil || result < 1 || result > 65535 {
+		return 0, fmt.Errorf("invalid port %q", port)
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseForward(t *testing.T) {
+	valid := map[string]forward{
+		"db.internal:5432":       {localPort: 5432, remoteHost: "db.internal", remotePort: 5432},
+		"15432:db.internal:5432": {localPort: 15432, remoteHost: "db.internal", remotePort: 5432},
+	}
+	for spec, expected := range valid {
+		result, err := parseForward(spec)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", spec, err)
+		} else if result != expected {
+			t.Errorf("parsed %q as %+v, expected %+v", spec, result, expected)
+		}
+	}
+
+	for _, spec := range []string{"5432", ":5432", "db:0", "db:http", "x:db:5432", "1:2:db:5432"} {
+		if _, err := parseForward(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
+
+// setDelays shortens the reconnection delays for the duration of a test
+func setDelays(t *testing.T, min time.Duration, max time.Duration, stable time.Duration) {
+	previousMin, previousMax, previousStable := minReconnectDelay, maxReconnectDelay, stableConnection
+	minReconnectDelay, maxReconnectDelay, stableConnection = min, max, stable
+	t.Cleanup(func() {
+		minReconnectDelay, maxReconnectDelay, stableConnection = previousMin, previousMax, previousStable
+	})
+}
+
+// flakyConnect fails the first failures attempts, then stays connected until cancelled. The
+// time of every attempt is sent to attempts
+func flakyConnect(failures int, attempts chan<- time.Time) func(context.Context) error {
+	count := 0
+	return func(ctx context.Context) error {
+		attempts <- time.Now()
+		count++
+		if count <= failures {
+			return errors.New("connection refused")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+}
+
+// runKeepAlive runs keepAlive until the connection attempt after the failures, returning the
+// time of each attempt
+func runKeepAlive(t *testing.T, failures int) []time.Time {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	attempts := make(chan time.Time, failures+1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keepAlive(ctx, flakyConnect(failures, attempts))
+	}()
+
+	times := []time.Time{}
+	for len(times) < failures+1 {
+		select {
+		case at := <-attempts:
+			times = append(times, at)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d connection attempts, got %d", failures+1, len(times))
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected keepAlive to return promptly once cancelled")
+	}
+	return times
+}
+
+func TestKeepAliveBackoff(t *testing.T) {
+	setDelays(t, 20*time.Millisecond, 80*time.Millisecond, time.Hour)
+
+	times := runKeepAlive(t, 4)
+	expected := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond, 80 * time.Millisecond}
+	for idx, delay := range expected {
+		if waited := times[idx+1].Sub(times[idx]); waited < delay {
+			t.Errorf("attempt #%d: expected to wait at least %v, waited %v", idx+2, delay, waited)
+		}
+	}
+}
+
+func TestKeepAliveResetsDelayAfterStableConnection(t *testing.T) {
+	// every connection counts as stable, so the delay never grows
+	setDelays(t, 20*time.Millisecond, time.Minute, 0)
+
+	times := runKeepAlive(t, 6)
+	for idx := 1; idx < len(times); idx++ {
+		if waited := times[idx].Sub(times[idx-1]); waited > 200*time.Millisecond {
+			t.Errorf("attempt #%d: expected the delay to be reset, waited %v", idx+1, waited)
+		}
+	}
+}
+
+func TestKeepAliveCancelledWhileWaiting(t *testing.T) {
+	setDelays(t, time.Hour, time.Hour, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := make(chan time.Time, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keepAlive(ctx, flakyConnect(1, attempts))
+	}()
+
+	<-attempts
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected keepAlive to stop waiting to reconnect once cancelled")
+	}
+	if len(attempts) != 0 {
+		t.Errorf("expected no reconnection after cancellation")
+	}
+}
@@ -0,0 +1,19 @@
+package ecs
+
+import (
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/ecs/resolve"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:           "ecs",
+		Short:         "Elastic Container Service related subcommands",
+		SilenceErrors: true,
+	}
+	awstcmd.AddSubCommand(&cmd, resolve.Command(awsCfg))
+	return &cmd
+}
@@ -0,0 +1,282 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	awst "awstool/aws"
+	"awstool/aws/ecs"
+	awstcmd "awstool/cmd"
+	"awstool/common"
+	"awstool/loader"
+	"awstool/printer"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+type filters struct {
+	clusters []string
+	services []string
+	tags     map[string]string
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "resolve",
+		Short: "resolves ecs services to their tasks and the ec2 instances they run on",
+		Long: "Resolves ecs services to their running tasks and, for tasks with the EC2 launch type, to the ec2 " +
+			"instances they run on. One line is printed per task, and services without running tasks are " +
+			"printed on a line of their own",
+		SilenceErrors: true,
+	}
+
+	filters := filters{}
+	var tags []string
+
+	cmd.Flags().StringSliceVar(
+		&filters.clusters, "cluster", []string{},
+		"Find services of clusters by their name, accepting * and ? wildcards",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&filters.services, "service", []string{},
+		"Find services by their name, accepting * and ? wildcards. Eg: --service 'orders-*'",
+	)
+
+	cmd.Flags().StringSliceVarP(
+		&tags, "tags", "t", []string{},
+		"Find services by tags, in the KEY:VALUE format. Alternative values can be separated by |, "+
+			"eg Env:dev|staging. All tags must match",
+	)
+
+	printOptions := printer.Options{}
+	printOptions.AddFlags(
+		cmd.Flags(),
+		"'{{.Service.ServiceName}} {{if .Instance}}{{.Instance.InstanceId}}{{end}}'",
+	)
+	printOptions.AddTagFlags(cmd.Flags())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var err error
+		filters.tags, err = awstcmd.ParseTags(tags)
+		if err != nil {
+			return err
+		}
+		if err := printOptions.Validate(&table); err != nil {
+			return err
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		resolution, err := resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg)
+		if err != nil {
+			return fmt.Errorf("failed while fetching services: %w", err)
+		}
+		return printOptions.Print(os.Stdout, &table, rows(resolution, filters))
+	}
+
+	return &cmd
+}
+
+func resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config) (*awst.AWS, error) {
+	result, err := load(
+		ctx, cfg,
+		loader.WithServices("ecs", "ec2"),
+		loader.WithECSFetchOptions(ecs.WithWorkloadsOnly()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f filters) matches(clusterName string, service *ecsTypes.Service) bool {
+	if !common.MatchesAny(f.clusters, clusterName) {
+		return false
+	}
+	if !common.MatchesAny(f.services, aws.ToString(service.ServiceName)) {
+		return false
+	}
+	for key, values := range f.tags {
+		value, ok := tagValue(service.Tags, key)
+		if !ok || !common.MatchesAny(strings.Split(values, "|"), value) {
+			return false
+		}
+	}
+	return true
+}
+
+// templateData holds a service along with one of its tasks, if any, and the instance the task
+// runs on, if it has the EC2 launch type
+type templateData struct {
+	Region   string
+	Cluster  string
+	Service  *ecsTypes.Service
+	Task     *ecsTypes.Task
+	Instance *ec2Types.Instance
+}
+
+// rows maps each service matching the filters to its tasks, which are found by their group, and
+// each task to its instance through the container instance it was placed on
+func rows(data *awst.AWS, filters filters) []interface{} {
+	result := []interface{}{}
+	for _, region := range data.Regions {
+		instances := map[string]*ec2Types.Instance{}
+		for _, reservation := range region.EC2.Reservations {
+			for idx := range reservation.Instances {
+				instance := &reservation.Instances[idx]
+				instances[aws.ToString(instance.InstanceId)] = instance
+			}
+		}
+
+		for clusterName, cluster := range region.ECS.Clusters {
+			containerInstances := map[string]string{}
+			for _, containerInstance := range cluster.ContainerInstances {
+				containerInstances[aws.ToString(containerInstance.ContainerInstanceArn)] = aws.ToString(containerInstance.Ec2InstanceId)
+			}
+			tasks := map[string][]*ecsTypes.Task{}
+			for idx := range cluster.Tasks {
+				task := &cluster.Tasks[idx]
+				tasks[aws.ToString(task.Group)] = append(tasks[aws.ToString(task.Group)], task)
+			}
+
+			for idx := range cluster.Services {
+				service := &cluster.Services[idx]
+				if !filters.matches(clusterName, service) {
+					continue
+				}
+				serviceTasks := tasks["service:"+aws.ToString(service.ServiceName)]
+				if len(serviceTasks) == 0 {
+					result = append(result, templateData{Region: region.Region, Cluster: clusterName, Service: service})
+					continue
+				}
+				for _, task := range serviceTasks {
+					row := templateData{Region: region.Region, Cluster: clusterName, Service: service, Task: task}
+					if instanceId, ok := containerInstances[aws.ToString(task.ContainerInstanceArn)]; ok {
+						row.Instance = instances[instanceId]
+					}
+					result = append(result, row)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func tagValue(tags []ecsTypes.Tag, key string) (string, bool) {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value), true
+		}
+	}
+	return "", false
+}
+
+// lastSegment returns the part of an ARN after its last slash, eg the id of a task
+func lastSegment(arn *string) interface{} {
+	if arn == nil {
+		return nil
+	}
+	value := *arn
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+// taskColumn builds a column reading a value from the task of a row, empty for services without
+// running tasks
+func taskColumn(name string, wide bool, value func(*ecsTypes.Task) interface{}) printer.Column {
+	return printer.Column{
+		Name: name,
+		Wide: wide,
+		Value: func(row interface{}) interface{} {
+			task := row.(templateData).Task
+			if task == nil {
+				return nil
+			}
+			return value(task)
+		},
+	}
+}
+
+// instanceColumn is the same as taskColumn, but for the instance of the task
+func instanceColumn(name string, wide bool, value func(*ec2Types.Instance) interface{}) printer.Column {
+	return printer.Column{
+		Name: name,
+		Wide: wide,
+		Value: func(row interface{}) interface{} {
+			instance := row.(templateData).Instance
+			if instance == nil {
+				return nil
+			}
+			return value(instance)
+		},
+	}
+}
+
+func serviceOf(row interface{}) *ecsTypes.Service {
+	return row.(templateData).Service
+}
+
+// taskPrivateIp is the address of the network interface of tasks using the awsvpc network mode,
+// which includes all fargate tasks. Other tasks use the address of their instance
+func taskPrivateIp(row interface{}) interface{} {
+	data := row.(templateData)
+	if data.Task != nil {
+		for _, attachment := range data.Task.Attachments {
+			for _, detail := range attachment.Details {
+				if aws.ToString(detail.Name) == "privateIPv4Address" {
+					return detail.Value
+				}
+			}
+		}
+	}
+	if data.Instance != nil {
+		return data.Instance.PrivateIpAddress
+	}
+	return nil
+}
+
+var table = printer.Table{
+	Columns: []printer.Column{
+		{Name: "region", Value: func(row interface{}) interface{} { return row.(templateData).Region }},
+		{Name: "cluster", Value: func(row interface{}) interface{} { return row.(templateData).Cluster }},
+		{Name: "service", Value: func(row interface{}) interface{} { return serviceOf(row).ServiceName }},
+		taskColumn("task", false, func(t *ecsTypes.Task) interface{} { return lastSegment(t.TaskArn) }),
+		taskColumn("status", false, func(t *ecsTypes.Task) interface{} { return t.LastStatus }),
+		instanceColumn("instance", false, func(i *ec2Types.Instance) interface{} { return i.InstanceId }),
+		{Name: "privateIp", Value: taskPrivateIp},
+		{Name: "launchType", Wide: true, Value: func(row interface{}) interface{} { return serviceOf(row).LaunchType }},
+		{Name: "desired", Wide: true, Value: func(row interface{}) interface{} { return serviceOf(row).DesiredCount }},
+		{Name: "running", Wide: true, Value: func(row interface{}) interface{} { return serviceOf(row).RunningCount }},
+		taskColumn("taskDefinition", true, func(t *ecsTypes.Task) interface{} { return lastSegment(t.TaskDefinitionArn) }),
+		taskColumn("health", true, func(t *ecsTypes.Task) interface{} { return t.HealthStatus }),
+		taskColumn("az", true, func(t *ecsTypes.Task) interface{} { return t.AvailabilityZone }),
+		taskColumn("startedAt", true, func(t *ecsTypes.Task) interface{} { return t.StartedAt }),
+		instanceColumn("instanceType", true, func(i *ec2Types.Instance) interface{} { return i.InstanceType }),
+		instanceColumn("instanceName", true, func(i *ec2Types.Instance) interface{} {
+			for _, tag := range i.Tags {
+				if aws.ToString(tag.Key) == "Name" {
+					return printer.TagValue(aws.ToString(tag.Value))
+				}
+			}
+			return nil
+		}),
+	},
+	Tags: func(row interface{}) []printer.Tag {
+		tags := serviceOf(row).Tags
+		result := make([]printer.Tag, len(tags))
+		for idx, tag := range tags {
+			result[idx] = printer.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)}
+		}
+		return result
+	},
+	SortBy: []string{"region", "cluster", "service", "task"},
+}
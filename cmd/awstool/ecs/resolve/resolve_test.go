@@ -0,0 +1,92 @@
+package resolve
+
+import (
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestRows(t *testing.T) {
+	region := awst.NewRegion("us-east-1")
+	region.EC2.Reservations = []ec2Types.Reservation{
+		{Instances: []ec2Types.Instance{{InstanceId: aws.String("i-1")}}},
+	}
+	region.ECS.Clusters["main"] = &awst.ECSCluster{
+		Services: []ecsTypes.Service{
+			{ServiceName: aws.String("api"), Tags: []ecsTypes.Tag{{Key: aws.String("Env"), Value: aws.String("prod")}}},
+			{ServiceName: aws.String("worker")},
+			{ServiceName: aws.String("idle")},
+		},
+		Tasks: []ecsTypes.Task{
+			{TaskArn: aws.String("arn:task/main/1"), Group: aws.String("service:api"), ContainerInstanceArn: aws.String("arn:ci/1")},
+			{TaskArn: aws.String("arn:task/main/2"), Group: aws.String("service:api")},
+			{TaskArn: aws.String("arn:task/main/3"), Group: aws.String("service:worker")},
+			{TaskArn: aws.String("arn:task/main/4"), Group: aws.String("family:batch")},
+		},
+		ContainerInstances: []ecsTypes.ContainerInstance{
+			{ContainerInstanceArn: aws.String("arn:ci/1"), Ec2InstanceId: aws.String("i-1")},
+		},
+	}
+	data := awst.New()
+	data.Regions["us-east-1"] = region
+
+	tests := []struct {
+		name     string
+		filters  filters
+		expected map[string]string
+	}{
+		{
+			name:    "all services",
+			filters: filters{},
+			expected: map[string]string{
+				"api/1":    "i-1",
+				"api/2":    "",
+				"worker/3": "",
+				"idle/":    "",
+			},
+		},
+		{
+			name:     "service pattern",
+			filters:  filters{services: []string{"w*"}},
+			expected: map[string]string{"worker/3": ""},
+		},
+		{
+			name:     "tags",
+			filters:  filters{tags: map[string]string{"Env": "staging|prod"}},
+			expected: map[string]string{"api/1": "i-1", "api/2": ""},
+		},
+		{
+			name:     "other cluster",
+			filters:  filters{clusters: []string{"batch-*"}},
+			expected: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := map[string]string{}
+			for _, row := range rows(&data, test.filters) {
+				data := row.(templateData)
+				key := aws.ToString(data.Service.ServiceName) + "/"
+				if data.Task != nil {
+					key += lastSegment(data.Task.TaskArn).(string)
+				}
+				result[key] = ""
+				if data.Instance != nil {
+					result[key] = aws.ToString(data.Instance.InstanceId)
+				}
+			}
+			if len(result) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+			for key, instance := range test.expected {
+				if got, ok := result[key]; !ok || got != instance {
+					t.Errorf("expected %v, got %v", test.expected, result)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package request
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,10 +20,16 @@ import (
 	"awstool/loader"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/elasticsearchservice"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// signingName is the service name used when signing requests to domains with SigV4. Both
+// Elasticsearch and OpenSearch domains use the same name
+const signingName = "es"
+
 type printOptions struct {
 	pretty bool
 }
@@ -52,6 +60,7 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 	var headers []string
 	var jsonBody bool
 	var noStatusCheck bool
+	var noSign bool
 
 	cmd.Flags().BoolVarP(
 		&printOptions.pretty, "pretty", "P", false,
@@ -78,6 +87,14 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 			"this check",
 	)
 
+	cmd.Flags().BoolVarP(
+		&noSign, "no-sign", "N", false,
+		"By default requests are signed with AWS Signature Version 4 using the same credentials "+
+			"used to resolve the domain, which is required by domains protected by IAM based access "+
+			"policies. Use this flag to send unsigned requests instead, eg for domains behind basic "+
+			"auth or a proxy",
+	)
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		region := args[0]
 		domainName := args[1]
@@ -111,8 +128,22 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		if err != nil {
 			return err
 		}
+		// emulators usually serve domains over plain http, so we follow the scheme of the
+		// overridden service endpoint, if any
+		if override, ok := awst.OverriddenEndpoint(**awsCfg, elasticsearchservice.ServiceID); ok {
+			if overrideURL, err := url.Parse(override); err == nil {
+				setEndpointScheme(domain, overrideURL.Scheme)
+			}
+		}
+
+		var signingCfg *aws.Config
+		if !noSign {
+			cfg := **awsCfg
+			cfg.Region = region
+			signingCfg = &cfg
+		}
 
-		resp, err := request(cmd.Context(), domain, method, path, headerMap, data, printOptions)
+		resp, err := request(cmd.Context(), domain, method, path, headerMap, data, signingCfg, printOptions)
 		if err != nil {
 			return err
 		}
@@ -166,6 +197,8 @@ func resolve(ctx context.Context, cfg aws.Config, region string, domain string)
 	return domains[0], nil
 }
 
+// request sends a request to the domain. If signingCfg is not nil, the request is signed
+// with the credentials and region of that config
 func request(
 	ctx context.Context,
 	domain *awst.ElasticsearchDomain,
@@ -173,6 +206,7 @@ func request(
 	path string,
 	headers map[string]string,
 	data []byte,
+	signingCfg *aws.Config,
 	printOptions printOptions,
 ) (*http.Response, error) {
 	client := http.DefaultClient
@@ -187,7 +221,7 @@ func request(
 	}
 
 	path = sanitizePath(path)
-	urlString := "https://" + *endpoint + "/" + path
+	urlString := endpointURL(*endpoint) + "/" + path
 	url, err := url.Parse(urlString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid generated url: %s: %w", urlString, err)
@@ -207,7 +241,13 @@ func request(
 		req.Header.Add(key, value)
 	}
 
-	printRequest(req, data, printOptions)
+	if signingCfg != nil {
+		if err := signRequest(ctx, *signingCfg, req, data); err != nil {
+			return nil, err
+		}
+	}
+
+	printRequest(os.Stderr, req, data, printOptions)
 
 	start := time.Now()
 	resp, err := client.Do(req)
@@ -217,19 +257,64 @@ func request(
 	return resp, err
 }
 
-func printRequest(req *http.Request, body []byte, printOptions printOptions) {
+// endpointURL prefixes the domain endpoint with https, unless it already has a scheme
+func endpointURL(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	return "https://" + endpoint
+}
+
+func setEndpointScheme(domain *awst.ElasticsearchDomain, scheme string) {
+	if domain.Status.Endpoint != nil {
+		domain.Status.Endpoint = aws.String(scheme + "://" + *domain.Status.Endpoint)
+	}
+	for key, endpoint := range domain.Status.Endpoints {
+		domain.Status.Endpoints[key] = scheme + "://" + endpoint
+	}
+}
+
+func signRequest(ctx context.Context, cfg aws.Config, req *http.Request, body []byte) error {
+	if cfg.Credentials == nil {
+		return fmt.Errorf("cannot sign request: no aws credentials available")
+	}
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot sign request: failed to retrieve aws credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	payloadHashHex := hex.EncodeToString(payloadHash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHashHex)
+	signer := v4.NewSigner()
+	if err := signer.SignHTTP(ctx, credentials, req, payloadHashHex, signingName, cfg.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
+
+// redactedHeaders hold credentials, eg the signature and the session token of signed requests,
+// so their values are not printed
+var redactedHeaders = map[string]struct{}{
+	"Authorization":        {},
+	"X-Amz-Security-Token": {},
+}
+
+func printRequest(out io.Writer, req *http.Request, body []byte, printOptions printOptions) {
 	if !log.IsLevelEnabled(log.InfoLevel) {
 		return
 	}
-	fmt.Fprintf(os.Stderr, "> %s %s\n", req.Method, req.URL.Path)
-	fmt.Fprintf(os.Stderr, "> Host: %s\n", req.URL.Host)
+	fmt.Fprintf(out, "> %s %s\n", req.Method, req.URL.Path)
+	fmt.Fprintf(out, "> Host: %s\n", req.URL.Host)
 	for key, values := range req.Header {
 		for _, value := range values {
-			fmt.Fprintf(os.Stderr, "> %s: %s\n", key, value)
+			if _, ok := redactedHeaders[http.CanonicalHeaderKey(key)]; ok {
+				value = "<redacted>"
+			}
+			fmt.Fprintf(out, "> %s: %s\n", key, value)
 		}
 	}
-	fmt.Fprint(os.Stderr, ">\n")
-	fmt.Fprintf(os.Stderr, "* sending %d bytes body\n", len(body))
+	fmt.Fprint(out, ">\n")
+	fmt.Fprintf(out, "* sending %d bytes body\n", len(body))
 }
 
 func printResponse(resp *http.Response, reqTime time.Time, printOptions printOptions) error {
@@ -0,0 +1,194 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	esTypes "github.com/aws/aws-sdk-go-v2/service/elasticsearchservice/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var testCredentials = aws.Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	SessionToken:    "session-token",
+}
+
+// verifySignature recomputes the signature of a received request using the headers the client
+// declared as signed, the same way AWS does, and reports whether it matches the received one
+func verifySignature(t *testing.T, r *http.Request, body []byte, region string) bool {
+	authorization := r.Header.Get("Authorization")
+	prefix := "AWS4-HMAC-SHA256 Credential=" + testCredentials.AccessKeyID + "/"
+	if !strings.HasPrefix(authorization, prefix) {
+		t.Errorf("unexpected authorization header %q", authorization)
+		return false
+	}
+	scope := "/" + region + "/" + signingName + "/aws4_request"
+	if !strings.Contains(authorization, scope) {
+		t.Errorf("authorization header %q does not have the expected scope %q", authorization, scope)
+		return false
+	}
+	if r.Header.Get("X-Amz-Security-Token") != testCredentials.SessionToken {
+		t.Errorf("session token not passed in the request")
+		return false
+	}
+
+	signingTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		t.Errorf("invalid X-Amz-Date header: %v", err)
+		return false
+	}
+
+	signedHeaders := []string{}
+	for _, part := range strings.Split(authorization, ", ") {
+		if strings.HasPrefix(part, "SignedHeaders=") {
+			signedHeaders = strings.Split(strings.TrimPrefix(part, "SignedHeaders="), ";")
+		}
+	}
+
+	expected, err := http.NewRequest(r.Method, "https://"+r.Host+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		t.Errorf("failed to rebuild request: %v", err)
+		return false
+	}
+	for _, header := range signedHeaders {
+		if header == "host" {
+			continue
+		}
+		expected.Header[http.CanonicalHeaderKey(header)] = r.Header.Values(header)
+	}
+	err = v4.NewSigner().SignHTTP(
+		context.Background(), testCredentials, expected,
+		r.Header.Get("X-Amz-Content-Sha256"), signingName, region, signingTime,
+	)
+	if err != nil {
+		t.Errorf("failed to sign expected request: %v", err)
+		return false
+	}
+	if expected.Header.Get("Authorization") != authorization {
+		t.Errorf(
+			"signature mismatch. Expected authorization header %q but got %q",
+			expected.Header.Get("Authorization"), authorization,
+		)
+		return false
+	}
+	return true
+}
+
+func stubDomain(t *testing.T, handler http.HandlerFunc) *awst.ElasticsearchDomain {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	defaultClient := http.DefaultClient
+	http.DefaultClient = server.Client()
+	t.Cleanup(func() { http.DefaultClient = defaultClient })
+
+	endpoint := strings.TrimPrefix(server.URL, "https://")
+	domainName := "test-domain"
+	return &awst.ElasticsearchDomain{
+		Status: &esTypes.ElasticsearchDomainStatus{
+			DomainName: &domainName,
+			Endpoint:   &endpoint,
+		},
+	}
+}
+
+func TestSignedRequest(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	region := "us-west-2"
+	data := []byte(`{"query":{"match_all":{}}}`)
+
+	domain := stubDomain(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		if !bytes.Equal(body, data) {
+			t.Errorf("expected body %q but got %q", data, body)
+		}
+		if !verifySignature(t, r, body, region) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := aws.Config{
+		Region:      region,
+		Credentials: credentials.StaticCredentialsProvider{Value: testCredentials},
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, err := request(
+		context.Background(), domain, "POST", "/index/_search?size=1", headers, data, &cfg, printOptions{},
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200 but got %d", resp.StatusCode)
+	}
+}
+
+func TestUnsignedRequest(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+
+	domain := stubDomain(t, func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			t.Errorf("expected no authorization header but got %q", authorization)
+		}
+		if r.Header.Get("X-Amz-Date") != "" {
+			t.Errorf("expected no X-Amz-Date header")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	resp, err := request(
+		context.Background(), domain, "GET", "/_cluster/health", map[string]string{}, nil, nil, printOptions{},
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200 but got %d", resp.StatusCode)
+	}
+}
+
+func TestPrintRequestRedactsCredentials(t *testing.T) {
+	log.SetLevel(log.InfoLevel)
+	defer log.SetLevel(log.WarnLevel)
+
+	req, err := http.NewRequest("GET", "https://search.example.com/_cat/indices", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := aws.Config{
+		Region:      "us-west-2",
+		Credentials: credentials.StaticCredentialsProvider{Value: testCredentials},
+	}
+	if err := signRequest(context.Background(), cfg, req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	printRequest(&out, req, nil, printOptions{})
+	printed := out.String()
+	if strings.Contains(printed, "Signature=") || strings.Contains(printed, testCredentials.SessionToken) {
+		t.Errorf("expected credentials to be redacted, got:\n%s", printed)
+	}
+	for _, header := range []string{"Authorization", "X-Amz-Security-Token"} {
+		if !strings.Contains(printed, "> "+header+": <redacted>\n") {
+			t.Errorf("expected %s to be printed redacted, got:\n%s", header, printed)
+		}
+	}
+}
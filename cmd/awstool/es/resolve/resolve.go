@@ -3,28 +3,18 @@ package resolve
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"sort"
-	"strings"
-	"text/template"
+	"os"
 
 	awst "awstool/aws"
 	"awstool/aws/elasticsearch"
+	awstcmd "awstool/cmd"
 	"awstool/loader"
+	"awstool/printer"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	esTypes "github.com/aws/aws-sdk-go-v2/service/elasticsearchservice/types"
 	"github.com/spf13/cobra"
 )
 
-type printOptions struct {
-	header    bool
-	tags      []string
-	allTags   bool
-	urlEncode bool
-	template  *template.Template
-}
-
 func Command(awsCfg **aws.Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:           "resolve",
@@ -34,53 +24,18 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 
 	var domain string
 
-	printOptions := printOptions{}
-	var templ string
-	var noURLEncode bool
-
 	cmd.Flags().StringVarP(
 		&domain, "domain", "d", "",
 		"Find the domain by its name",
 	)
 
-	cmd.Flags().StringSliceVarP(
-		&printOptions.tags, "print-tags", "T", []string{},
-		"Pass a list of tags keys that should be printed. See also --print-all-tags",
-	)
-
-	cmd.Flags().BoolVarP(
-		&printOptions.allTags, "print-all-tags", "A", false,
-		"Also prints all tags associated to the domains. Overrides --print-tags",
-	)
-
-	cmd.Flags().BoolVarP(
-		&printOptions.header, "header", "H", false,
-		"Also print a header on the first line, which will name the columns being printed",
-	)
-
-	cmd.Flags().BoolVarP(
-		&noURLEncode, "no-url-encode", "E", false,
-		"By default when printing tags their values are URL encoded to avoid whitespacing issues. "+
-			"Use this flag to avoid such mechanism",
-	)
-
-	cmd.Flags().StringVar(
-		&templ, "template", "",
-		"Print using a golang template instead. Template syntax is defined at https://pkg.go.dev/text/template. "+
-			"A simple template for printing region and instance id: '{{.Region}} {{.Instance.InstanceId}}'. "+
-			"The struct passed to the template engine can be checked by reading the source code "+
-			"for this command. Instead you can also inspect and navigate structs available for a template engine "+
-			"by using the template '{{printf \"%#+v\" .}}' and navigating from that point forward, for instance with "+
-			"'{{printf \"%#+v\" .Instance}}' and so on",
-	)
+	printOptions := printer.Options{}
+	printOptions.AddFlags(cmd.Flags(), "'{{.Region}} {{.Domain.Status.DomainName}}'")
+	printOptions.AddTagFlags(cmd.Flags())
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if templ != "" {
-			var err error
-			printOptions.template, err = template.New("user_input").Parse(templ)
-			if err != nil {
-				return fmt.Errorf("invalid template %q: %w", templ, err)
-			}
+		if err := printOptions.Validate(&table); err != nil {
+			return err
 		}
 
 		// We silence usage here instead of setting in the command struct declaration because it is
@@ -89,24 +44,28 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		// See more at https://github.com/spf13/cobra/issues/340
 		cmd.SilenceUsage = true
 
-		resolution, err := resolve(cmd.Context(), **awsCfg, domain)
+		resolution, err := resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, domain)
 		if err != nil {
-			return fmt.Errorf("failed while fetching instances: %w", err)
+			return fmt.Errorf("failed while fetching domains: %w", err)
+		}
+		rows := []interface{}{}
+		for _, region := range resolution.Regions {
+			for _, domain := range region.Elasticsearch.Domains {
+				rows = append(rows, templateData{Region: region.Region, Domain: domain})
+			}
 		}
-		printOptions.urlEncode = !noURLEncode
-		printDomains(resolution, printOptions)
-		return nil
+		return printOptions.Print(os.Stdout, &table, rows)
 	}
 
 	return &cmd
 }
 
-func resolve(ctx context.Context, cfg aws.Config, domain string) (*awst.AWS, error) {
+func resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, domain string) (*awst.AWS, error) {
 	fetchOpts := []elasticsearch.FetchOption{}
 	if domain != "" {
 		fetchOpts = append(fetchOpts, elasticsearch.WithDomains(domain))
 	}
-	result, err := loader.LoadAWS(
+	result, err := load(
 		ctx, cfg,
 		loader.WithServices("elasticsearch"),
 		loader.WithESFetchOptions(fetchOpts...),
@@ -117,127 +76,85 @@ func resolve(ctx context.Context, cfg aws.Config, domain string) (*awst.AWS, err
 	return result, nil
 }
 
-type printDomainInput struct {
-	region string
-	domain *awst.ElasticsearchDomain
-}
-
-func printDomains(aws *awst.AWS, printOptions printOptions) {
-	printHeader(printOptions)
-
-	inputs := []printDomainInput{}
-	for _, region := range aws.Regions {
-		for _, domain := range region.Elasticsearch.Domains {
-			inputs = append(inputs, printDomainInput{
-				region: region.Region,
-				domain: domain,
-			})
-		}
-	}
-
-	sort.SliceStable(inputs, func(i, j int) bool {
-		comparison := strings.Compare(inputs[i].region, inputs[j].region)
-		if comparison == 0 {
-			comparison = strings.Compare(
-				*inputs[i].domain.Status.DomainName,
-				*inputs[j].domain.Status.DomainName,
-			)
-		}
-		return comparison < 0
-	})
-
-	for _, input := range inputs {
-		printDomain(input.region, input.domain, printOptions)
-	}
-}
-
-func printHeader(printOptions printOptions) {
-	if !printOptions.header || printOptions.template != nil {
-		return
-	}
-	fmt.Print("#region #domain #version #endpoints #instance_count #instance_type")
-	if printOptions.allTags || len(printOptions.tags) > 0 {
-		fmt.Print(" #tags")
-	}
-	fmt.Println()
-}
-
 type templateData struct {
 	Region string
 	Domain *awst.ElasticsearchDomain
 }
 
-func printDomain(region string, domain *awst.ElasticsearchDomain, printOptions printOptions) {
-	if printOptions.template != nil {
-		buf := strings.Builder{}
-		data := templateData{
-			Region: region,
-			Domain: domain,
-		}
-		if err := printOptions.template.Execute(&buf, data); err != nil {
-			fmt.Printf("template execution error: %v\n", err)
-		}
-		fmt.Println(buf.String())
-		return
-	}
+func domainOf(row interface{}) *awst.ElasticsearchDomain {
+	return row.(templateData).Domain
+}
 
-	fmt.Printf(
-		"%s %s %s %s %d %s",
-		region,
-		*domain.Status.DomainName,
-		*domain.Config.ElasticsearchVersion.Options,
-		strings.Join(endpoints(domain.Status), ","),
-		*domain.Status.ElasticsearchClusterConfig.InstanceCount,
-		domain.Status.ElasticsearchClusterConfig.InstanceType,
-	)
-	tagsStr := tagsString(domain, printOptions)
-	if tagsStr != "" {
-		fmt.Printf(" %s", tagsStr)
-	}
-	fmt.Println()
+var table = printer.Table{
+	Columns: []printer.Column{
+		{Name: "region", Value: func(row interface{}) interface{} { return row.(templateData).Region }},
+		{Name: "domain", Value: func(row interface{}) interface{} { return domainOf(row).Status.DomainName }},
+		{Name: "version", Value: func(row interface{}) interface{} {
+			config := domainOf(row).Config
+			if config == nil || config.ElasticsearchVersion == nil {
+				return domainOf(row).Status.ElasticsearchVersion
+			}
+			return config.ElasticsearchVersion.Options
+		}},
+		{Name: "endpoints", Value: func(row interface{}) interface{} { return endpoints(domainOf(row)) }},
+		{Name: "instanceCount", Value: func(row interface{}) interface{} {
+			if domainOf(row).Status.ElasticsearchClusterConfig == nil {
+				return nil
+			}
+			return domainOf(row).Status.ElasticsearchClusterConfig.InstanceCount
+		}},
+		{Name: "instanceType", Value: func(row interface{}) interface{} {
+			if domainOf(row).Status.ElasticsearchClusterConfig == nil {
+				return nil
+			}
+			return domainOf(row).Status.ElasticsearchClusterConfig.InstanceType
+		}},
+		{Name: "arn", Wide: true, Value: func(row interface{}) interface{} { return domainOf(row).Status.ARN }},
+		{Name: "vpc", Wide: true, Value: func(row interface{}) interface{} {
+			if domainOf(row).Status.VPCOptions == nil {
+				return nil
+			}
+			return domainOf(row).Status.VPCOptions.VPCId
+		}},
+		{Name: "dedicatedMasterType", Wide: true, Value: func(row interface{}) interface{} {
+			config := domainOf(row).Status.ElasticsearchClusterConfig
+			if config == nil || !aws.ToBool(config.DedicatedMasterEnabled) {
+				return nil
+			}
+			return config.DedicatedMasterType
+		}},
+		{Name: "dedicatedMasterCount", Wide: true, Value: func(row interface{}) interface{} {
+			config := domainOf(row).Status.ElasticsearchClusterConfig
+			if config == nil || !aws.ToBool(config.DedicatedMasterEnabled) {
+				return nil
+			}
+			return config.DedicatedMasterCount
+		}},
+		{Name: "volumeSize", Wide: true, Value: func(row interface{}) interface{} {
+			if domainOf(row).Status.EBSOptions == nil {
+				return nil
+			}
+			return domainOf(row).Status.EBSOptions.VolumeSize
+		}},
+	},
+	Tags: func(row interface{}) []printer.Tag {
+		tags := domainOf(row).Tags
+		result := make([]printer.Tag, len(tags))
+		for idx, tag := range tags {
+			result[idx] = printer.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)}
+		}
+		return result
+	},
+	SortBy: []string{"region", "domain"},
 }
 
-func endpoints(domain *esTypes.ElasticsearchDomainStatus) []string {
+func endpoints(domain *awst.ElasticsearchDomain) []string {
 	result := []string{}
-	if domain.Endpoint != nil {
-		result = append(result, *domain.Endpoint)
+	if domain.Status.Endpoint != nil {
+		result = append(result, *domain.Status.Endpoint)
 	}
-	for _, value := range domain.Endpoints {
+	for _, value := range domain.Status.Endpoints {
 		result = append(result, value)
 	}
 	return result
 }
-
-func tagsString(domain *awst.ElasticsearchDomain, printOptions printOptions) string {
-	if !printOptions.allTags && len(printOptions.tags) == 0 {
-		return ""
-	}
-
-	result := []string{}
-	appendTag := func(tag esTypes.Tag) {
-		value := *tag.Value
-		if printOptions.urlEncode {
-			value = url.PathEscape(value)
-		}
-		result = append(result, *tag.Key+":"+value)
-	}
-
-	if printOptions.allTags {
-		for _, tag := range domain.Tags {
-			appendTag(tag)
-		}
-
-	} else {
-		tagMap := map[string]esTypes.Tag{}
-		for _, tag := range domain.Tags {
-			tagMap[*tag.Key] = tag
-		}
-		for _, tagToFind := range printOptions.tags {
-			if tag, ok := tagMap[tagToFind]; ok {
-				appendTag(tag)
-			}
-		}
-	}
-
-	return strings.Join(result, ",")
-}
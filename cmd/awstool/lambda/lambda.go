@@ -0,0 +1,19 @@
+package lambda
+
+import (
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/lambda/resolve"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:           "lambda",
+		Short:         "Lambda related subcommands",
+		SilenceErrors: true,
+	}
+	awstcmd.AddSubCommand(&cmd, resolve.Command(awsCfg))
+	return &cmd
+}
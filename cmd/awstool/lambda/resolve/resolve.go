@@ -0,0 +1,182 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	awst "awstool/aws"
+	"awstool/aws/lambda"
+	awstcmd "awstool/cmd"
+	"awstool/common"
+	"awstool/loader"
+	"awstool/printer"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+type filters struct {
+	names    []string
+	runtimes []string
+	tags     map[string]string
+}
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:           "resolve",
+		Short:         "resolves lambda functions by a given set of inputs",
+		SilenceErrors: true,
+	}
+
+	filters := filters{}
+	var tags []string
+
+	cmd.Flags().StringSliceVar(
+		&filters.names, "name", []string{},
+		"Find functions by their name, accepting * and ? wildcards. Eg: --name 'orders-*'",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&filters.runtimes, "runtime", []string{},
+		"Find functions running on one of these runtimes, accepting * and ? wildcards. Eg: --runtime 'python3.*'",
+	)
+
+	cmd.Flags().StringSliceVarP(
+		&tags, "tags", "t", []string{},
+		"Find functions by tags, in the KEY:VALUE format. Alternative values can be separated by |, "+
+			"eg Env:dev|staging. All tags must match",
+	)
+
+	printOptions := printer.Options{}
+	printOptions.AddFlags(cmd.Flags(), "'{{.Region}} {{.Function.Configuration.FunctionName}}'")
+	printOptions.AddTagFlags(cmd.Flags())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var err error
+		filters.tags, err = awstcmd.ParseTags(tags)
+		if err != nil {
+			return err
+		}
+		if err := printOptions.Validate(&table); err != nil {
+			return err
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		resolution, err := resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg)
+		if err != nil {
+			return fmt.Errorf("failed while fetching functions: %w", err)
+		}
+		rows := []interface{}{}
+		for _, region := range resolution.Regions {
+			for _, function := range region.Lambda.Functions {
+				if filters.matches(function) {
+					rows = append(rows, templateData{Region: region.Region, Function: function})
+				}
+			}
+		}
+		return printOptions.Print(os.Stdout, &table, rows)
+	}
+
+	return &cmd
+}
+
+func resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config) (*awst.AWS, error) {
+	result, err := load(
+		ctx, cfg,
+		loader.WithServices("lambda"),
+		loader.WithLambdaFetchOptions(lambda.WithFunctionsOnly()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f filters) matches(function *awst.LambdaFunction) bool {
+	if function.Configuration == nil {
+		return false
+	}
+	if !common.MatchesAny(f.names, aws.ToString(function.Configuration.FunctionName)) {
+		return false
+	}
+	if !common.MatchesAny(f.runtimes, string(function.Configuration.Runtime)) {
+		return false
+	}
+	for key, values := range f.tags {
+		value, ok := function.Tags[key]
+		if !ok || !common.MatchesAny(strings.Split(values, "|"), value) {
+			return false
+		}
+	}
+	return true
+}
+
+type templateData struct {
+	Region   string
+	Function *awst.LambdaFunction
+}
+
+func function(row interface{}) *awst.LambdaFunction {
+	return row.(templateData).Function
+}
+
+var table = printer.Table{
+	Columns: []printer.Column{
+		{Name: "region", Value: func(row interface{}) interface{} { return row.(templateData).Region }},
+		{Name: "name", Value: func(row interface{}) interface{} { return function(row).Configuration.FunctionName }},
+		{Name: "runtime", Value: func(row interface{}) interface{} { return function(row).Configuration.Runtime }},
+		{Name: "memory", Value: func(row interface{}) interface{} { return function(row).Configuration.MemorySize }},
+		{Name: "timeout", Value: func(row interface{}) interface{} { return function(row).Configuration.Timeout }},
+		{Name: "lastModified", Value: func(row interface{}) interface{} { return function(row).Configuration.LastModified }},
+		{Name: "handler", Wide: true, Value: func(row interface{}) interface{} { return function(row).Configuration.Handler }},
+		{Name: "role", Wide: true, Value: func(row interface{}) interface{} { return function(row).Configuration.Role }},
+		{Name: "vpc", Wide: true, Value: func(row interface{}) interface{} {
+			if function(row).Configuration.VpcConfig == nil {
+				return nil
+			}
+			return function(row).Configuration.VpcConfig.VpcId
+		}},
+		{Name: "layers", Wide: true, Value: func(row interface{}) interface{} {
+			layers := []string{}
+			for _, layer := range function(row).Configuration.Layers {
+				layers = append(layers, aws.ToString(layer.Arn))
+			}
+			return layers
+		}},
+		{Name: "envVars", Wide: true, Value: func(row interface{}) interface{} {
+			environment := function(row).Configuration.Environment
+			if environment == nil {
+				return nil
+			}
+			names := make([]string, 0, len(environment.Variables))
+			for name := range environment.Variables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return names
+		}},
+		{Name: "arn", Wide: true, Value: func(row interface{}) interface{} { return function(row).Configuration.FunctionArn }},
+	},
+	Tags: func(row interface{}) []printer.Tag {
+		tags := function(row).Tags
+		keys := make([]string, 0, len(tags))
+		for key := range tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make([]printer.Tag, len(keys))
+		for idx, key := range keys {
+			result[idx] = printer.Tag{Key: key, Value: tags[key]}
+		}
+		return result
+	},
+	SortBy: []string{"region", "name"},
+}
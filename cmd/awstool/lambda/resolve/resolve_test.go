@@ -0,0 +1,42 @@
+package resolve
+
+import (
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestFiltersMatches(t *testing.T) {
+	function := &awst.LambdaFunction{
+		Configuration: &lambdaTypes.FunctionConfiguration{
+			FunctionName: aws.String("orders-api"),
+			Runtime:      lambdaTypes.RuntimePython39,
+		},
+		Tags: map[string]string{"Env": "prod"},
+	}
+
+	tests := []struct {
+		name     string
+		filters  filters
+		expected bool
+	}{
+		{"no filters", filters{}, true},
+		{"name pattern", filters{names: []string{"cron", "orders-*"}}, true},
+		{"other name", filters{names: []string{"cron"}}, false},
+		{"runtime pattern", filters{runtimes: []string{"python3.*"}}, true},
+		{"other runtime", filters{runtimes: []string{"nodejs*"}}, false},
+		{"tag alternatives", filters{tags: map[string]string{"Env": "staging|prod"}}, true},
+		{"other tag value", filters{tags: map[string]string{"Env": "dev"}}, false},
+		{"missing tag", filters{tags: map[string]string{"Team": "orders"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.filters.matches(function); result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -19,9 +19,12 @@ func main() {
 
 	cmd.KeepLoggingMemoryUsage(ctx, 15*time.Second, log.TraceLevel)
 
-	if err := RootCommand().ExecuteContext(ctx); err != nil {
-		log.Error(err)
-		exitCode := 1
+	if command, err := RootCommand().ExecuteContextC(ctx); err != nil {
+		// errors with no message only signal an exit code, eg diff finding changes
+		if err.Error() != "" {
+			log.Error(err)
+		}
+		exitCode := cmd.FailureExitCode(command)
 		if errWithCode, ok := err.(exitCodeErr); ok {
 			exitCode = errWithCode.ExitCode()
 		}
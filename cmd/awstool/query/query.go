@@ -0,0 +1,155 @@
+package query
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	awstcmd "awstool/cmd"
+	"awstool/loader"
+	"awstool/query"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+	csvOutput   = "csv"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "query QUERY",
+		Short: "searches resources of any loaded service with a query expression",
+		Long: "Searches resources with a query expression, eg:\n\n" +
+			"    awstool query 'ec2.instance where tag.Env = \"prod\" and state = \"running\" select id, privateIp, tag.Name'\n\n" +
+			"The full syntax is:\n\n" +
+			"    TYPE [where CONDITION] [select FIELD, ...] [order by FIELD [asc|desc]] [limit N]\n\n" +
+			"Conditions compare fields with =, !=, <, <=, >, >=, like (wildcards * and ?), ~ (regular expression) " +
+			"and in (\"a\", \"b\"), and can be combined with and, or, not and parentheses. Compare with null to check " +
+			"for missing fields, eg tag.Owner = null.\n\n" +
+			"Fields are region, id, tag.KEY or the path of any field of the resource as seen in a dump, eg " +
+			"Placement.AvailabilityZone or BlockDeviceMappings.0.DeviceName. Field paths are case insensitive. " +
+			"Commonly used fields also have short names, eg privateIp, publicIp, state, type and name for ec2 " +
+			"instances. Use * to select the whole resource.\n\n" +
+			"Only the service holding the queried resource type is loaded. Use --from-dump to query a previous dump instead.\n\n" +
+			"Resource types: " + strings.Join(query.Types(), ", "),
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	var output string
+	var regions []string
+	var excludeRegions []string
+
+	cmd.Flags().StringVarP(
+		&output, "output", "o", tableOutput,
+		"How to print results: table, json or csv",
+	)
+
+	cmd.Flags().StringSliceVarP(
+		&regions, "regions", "r", []string{},
+		"Query only those regions. If not specified, all regions will be queried. See also --exclude-regions",
+	)
+	cmd.Flags().StringSliceVarP(
+		&excludeRegions, "exclude-regions", "R", []string{},
+		"Do not query those regions. This takes precedence over --regions",
+	)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if output != tableOutput && output != jsonOutput && output != csvOutput {
+			return fmt.Errorf("invalid output %q: must be one of %s, %s or %s", output, tableOutput, jsonOutput, csvOutput)
+		}
+
+		q, err := query.Parse(args[0])
+		if err != nil {
+			return err
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		load := awstcmd.Loader(cmd)
+		data, err := load(
+			cmd.Context(), **awsCfg,
+			loader.WithServices(q.Services()...),
+			loader.WithRegions(regions...),
+			loader.WithoutRegions(excludeRegions...),
+		)
+		if err != nil {
+			return fmt.Errorf("failed while loading data: %w", err)
+		}
+
+		result, err := query.Run(q, data)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case jsonOutput:
+			return printJSON(result)
+		case csvOutput:
+			return printCSV(result)
+		default:
+			return printTable(result)
+		}
+	}
+
+	return &cmd
+}
+
+func printTable(result *query.Result) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(result.Fields, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for idx, value := range row {
+			values[idx] = query.String(value)
+			if values[idx] == "" {
+				values[idx] = "-"
+			}
+		}
+		fmt.Fprintln(writer, strings.Join(values, "\t"))
+	}
+	return writer.Flush()
+}
+
+func printCSV(result *query.Result) error {
+	writer := csv.NewWriter(os.Stdout)
+	if err := writer.Write(result.Fields); err != nil {
+		return err
+	}
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for idx, value := range row {
+			values[idx] = query.String(value)
+		}
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func printJSON(result *query.Result) error {
+	rows := make([]map[string]interface{}, len(result.Rows))
+	for idx, row := range result.Rows {
+		rows[idx] = map[string]interface{}{}
+		for fieldIdx, field := range result.Fields {
+			rows[idx][field] = row[fieldIdx]
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
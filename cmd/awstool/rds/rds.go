@@ -0,0 +1,19 @@
+package rds
+
+import (
+	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/rds/resolve"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:           "rds",
+		Short:         "Relational Database Service related subcommands",
+		SilenceErrors: true,
+	}
+	awstcmd.AddSubCommand(&cmd, resolve.Command(awsCfg))
+	return &cmd
+}
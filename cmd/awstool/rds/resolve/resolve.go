@@ -0,0 +1,252 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	awst "awstool/aws"
+	"awstool/aws/rds"
+	awstcmd "awstool/cmd"
+	"awstool/loader"
+	"awstool/printer"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/spf13/cobra"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "resolve",
+		Short: "resolves rds databases by a given set of inputs",
+		Long: "Resolves rds databases by a given set of inputs. Both DB instances and Aurora clusters are " +
+			"printed, as told by the kind column",
+		SilenceErrors: true,
+	}
+
+	var identifiers []string
+	var engines []string
+
+	cmd.Flags().StringSliceVarP(
+		&identifiers, "identifier", "i", []string{},
+		"Find DB instances and clusters by their identifier. Passing a cluster identifier also finds "+
+			"the instances of the cluster",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&engines, "engine", []string{},
+		"Find DB instances and clusters running one of these engines, eg postgres or aurora-mysql",
+	)
+
+	printOptions := printer.Options{}
+	printOptions.AddFlags(
+		cmd.Flags(),
+		"'{{.Region}} {{if .Instance}}{{.Instance.DBInstanceIdentifier}}{{else}}{{.Cluster.DBClusterIdentifier}}{{end}}'",
+	)
+	printOptions.AddTagFlags(cmd.Flags())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := printOptions.Validate(&table); err != nil {
+			return err
+		}
+
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		resolution, err := resolve(cmd.Context(), awstcmd.Loader(cmd), **awsCfg, identifiers)
+		if err != nil {
+			return fmt.Errorf("failed while fetching databases: %w", err)
+		}
+		rows := []interface{}{}
+		for _, data := range databases(resolution) {
+			if len(engines) == 0 || contains(engines, data.engine()) {
+				rows = append(rows, data)
+			}
+		}
+		return printOptions.Print(os.Stdout, &table, rows)
+	}
+
+	return &cmd
+}
+
+func resolve(ctx context.Context, load awstcmd.LoadFunc, cfg aws.Config, identifiers []string) (*awst.AWS, error) {
+	fetchOpts := []rds.FetchOption{rds.WithDatabasesOnly()}
+	if len(identifiers) > 0 {
+		fetchOpts = append(fetchOpts, rds.WithIdentifiers(identifiers...))
+	}
+	result, err := load(
+		ctx, cfg,
+		loader.WithServices("rds"),
+		loader.WithRDSFetchOptions(fetchOpts...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// templateData holds either a DB instance or an Aurora cluster
+type templateData struct {
+	Region   string
+	Instance *rdsTypes.DBInstance
+	Cluster  *rdsTypes.DBCluster
+}
+
+func (d templateData) engine() string {
+	if d.Instance != nil {
+		return aws.ToString(d.Instance.Engine)
+	}
+	return aws.ToString(d.Cluster.Engine)
+}
+
+func databases(aws *awst.AWS) []templateData {
+	result := []templateData{}
+	for _, region := range aws.Regions {
+		for idx := range region.RDS.Instances {
+			result = append(result, templateData{Region: region.Region, Instance: &region.RDS.Instances[idx]})
+		}
+		for idx := range region.RDS.Clusters {
+			result = append(result, templateData{Region: region.Region, Cluster: &region.RDS.Clusters[idx]})
+		}
+	}
+	return result
+}
+
+// column builds a column reading a value from either the instance or the cluster of a row
+func column(name string, wide bool, instance func(*rdsTypes.DBInstance) interface{}, cluster func(*rdsTypes.DBCluster) interface{}) printer.Column {
+	return printer.Column{
+		Name: name,
+		Wide: wide,
+		Value: func(row interface{}) interface{} {
+			data := row.(templateData)
+			if data.Instance != nil {
+				return instance(data.Instance)
+			}
+			return cluster(data.Cluster)
+		},
+	}
+}
+
+var table = printer.Table{
+	Columns: []printer.Column{
+		{Name: "region", Value: func(row interface{}) interface{} { return row.(templateData).Region }},
+		column(
+			"id", false,
+			func(i *rdsTypes.DBInstance) interface{} { return i.DBInstanceIdentifier },
+			func(c *rdsTypes.DBCluster) interface{} { return c.DBClusterIdentifier },
+		),
+		column(
+			"kind", false,
+			func(i *rdsTypes.DBInstance) interface{} { return "instance" },
+			func(c *rdsTypes.DBCluster) interface{} { return "cluster" },
+		),
+		column(
+			"engine", false,
+			func(i *rdsTypes.DBInstance) interface{} { return i.Engine },
+			func(c *rdsTypes.DBCluster) interface{} { return c.Engine },
+		),
+		column(
+			"version", false,
+			func(i *rdsTypes.DBInstance) interface{} { return i.EngineVersion },
+			func(c *rdsTypes.DBCluster) interface{} { return c.EngineVersion },
+		),
+		column(
+			"class", false,
+			func(i *rdsTypes.DBInstance) interface{} { return i.DBInstanceClass },
+			func(c *rdsTypes.DBCluster) interface{} { return c.DBClusterInstanceClass },
+		),
+		column(
+			"multiAZ", false,
+			func(i *rdsTypes.DBInstance) interface{} { return i.MultiAZ },
+			func(c *rdsTypes.DBCluster) interface{} { return c.MultiAZ },
+		),
+		column(
+			"endpoint", false,
+			func(i *rdsTypes.DBInstance) interface{} {
+				if i.Endpoint == nil {
+					return nil
+				}
+				return i.Endpoint.Address
+			},
+			func(c *rdsTypes.DBCluster) interface{} { return c.Endpoint },
+		),
+		column(
+			"status", true,
+			func(i *rdsTypes.DBInstance) interface{} { return i.DBInstanceStatus },
+			func(c *rdsTypes.DBCluster) interface{} { return c.Status },
+		),
+		column(
+			"cluster", true,
+			func(i *rdsTypes.DBInstance) interface{} { return i.DBClusterIdentifier },
+			func(c *rdsTypes.DBCluster) interface{} { return nil },
+		),
+		column(
+			"readerEndpoint", true,
+			func(i *rdsTypes.DBInstance) interface{} { return nil },
+			func(c *rdsTypes.DBCluster) interface{} { return c.ReaderEndpoint },
+		),
+		column(
+			"port", true,
+			func(i *rdsTypes.DBInstance) interface{} {
+				if i.Endpoint == nil {
+					return nil
+				}
+				return i.Endpoint.Port
+			},
+			func(c *rdsTypes.DBCluster) interface{} { return c.Port },
+		),
+		column(
+			"az", true,
+			func(i *rdsTypes.DBInstance) interface{} { return i.AvailabilityZone },
+			func(c *rdsTypes.DBCluster) interface{} { return c.AvailabilityZones },
+		),
+		column(
+			"vpc", true,
+			func(i *rdsTypes.DBInstance) interface{} {
+				if i.DBSubnetGroup == nil {
+					return nil
+				}
+				return i.DBSubnetGroup.VpcId
+			},
+			func(c *rdsTypes.DBCluster) interface{} { return nil },
+		),
+		column(
+			"public", true,
+			func(i *rdsTypes.DBInstance) interface{} { return i.PubliclyAccessible },
+			func(c *rdsTypes.DBCluster) interface{} { return c.PubliclyAccessible },
+		),
+		column(
+			"arn", true,
+			func(i *rdsTypes.DBInstance) interface{} { return i.DBInstanceArn },
+			func(c *rdsTypes.DBCluster) interface{} { return c.DBClusterArn },
+		),
+	},
+	Tags: func(row interface{}) []printer.Tag {
+		data := row.(templateData)
+		tags := []rdsTypes.Tag{}
+		if data.Instance != nil {
+			tags = data.Instance.TagList
+		} else {
+			tags = data.Cluster.TagList
+		}
+		result := make([]printer.Tag, len(tags))
+		for idx, tag := range tags {
+			result[idx] = printer.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)}
+		}
+		return result
+	},
+	SortBy: []string{"region", "id"},
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -6,12 +6,20 @@ import (
 
 	awst "awstool/aws"
 	awstcmd "awstool/cmd"
+	"awstool/cmd/awstool/diff"
 	"awstool/cmd/awstool/dump"
 	"awstool/cmd/awstool/ec2"
+	"awstool/cmd/awstool/ecs"
 	"awstool/cmd/awstool/es"
+	"awstool/cmd/awstool/lambda"
+	"awstool/cmd/awstool/query"
+	"awstool/cmd/awstool/rds"
 	"awstool/cmd/awstool/s3"
+	"awstool/cmd/awstool/sqlite"
+	"awstool/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +27,9 @@ import (
 func RootCommand() *cobra.Command {
 	cfgOptions := awst.NewAWSConfigOptions()
 	var quiet bool
+	var fromDump string
 	var verbosity int
+	var recordHTTP string
 
 	cmd := cobra.Command{
 		Use:           "awstool",
@@ -51,6 +61,61 @@ func RootCommand() *cobra.Command {
 			"See also --max-retries",
 	)
 
+	cmd.PersistentFlags().StringVar(
+		&cfgOptions.EndpointURL, "endpoint-url", "",
+		"Send requests for all services to this endpoint instead of the AWS ones, eg http://localhost:4566 "+
+			"to work against an emulator like LocalStack or moto. See also --service-endpoint-urls. "+
+			"Can also be set with the "+awst.EndpointURLEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().StringToStringVar(
+		&cfgOptions.ServiceEndpointURLs, "service-endpoint-urls", map[string]string{},
+		"Override endpoints of specific services, eg s3=http://localhost:9000,ec2=http://localhost:5000. "+
+			"Services are named as their sdk packages: ec2, s3, iam, sts, organizations, autoscaling, elasticloadbalancing, "+
+			"elasticloadbalancingv2, elasticbeanstalk, elasticsearchservice, lambda, ecs, eks, opsworks and rds. "+
+			"Takes precedence over --endpoint-url. Can also be set with the "+awst.ServiceEndpointURLsEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&cfgOptions.S3PathStyle, "s3-path-style", false,
+		"Put bucket names in the path of s3 requests instead of in the hostname, which most emulators "+
+			"require. Only applies when the s3 endpoint is overridden. "+
+			"Can also be set with the "+awst.S3PathStyleEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&cfgOptions.AccessKeyId, "access-key-id", "",
+		"Use static credentials with this access key id instead of the ones configured for the profile, "+
+			"eg test credentials for an emulator. Requires --secret-access-key. "+
+			"Can also be set with the "+awst.AccessKeyIdEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&cfgOptions.SecretAccessKey, "secret-access-key", "",
+		"Secret access key for the static credentials of --access-key-id. "+
+			"Can also be set with the "+awst.SecretAccessKeyEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&cfgOptions.SessionToken, "session-token", "",
+		"Optional session token for the static credentials of --access-key-id. "+
+			"Can also be set with the "+awst.SessionTokenEnv+" environment variable",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&fromDump, awstcmd.FromDumpFlag, "",
+		"Work on data from a json file previously generated by the dump command instead of fetching "+
+			"it live from the AWS APIs. Pass - to read the dump from stdin. Supported by the resolve, query, "+
+			"ec2 ssh, ec2 exec, ec2 ssh-config, ec2 tunnel and ec2 scp commands",
+	)
+
+	// used to generate fixtures for tests, so it is not listed in the help
+	cmd.PersistentFlags().StringVar(
+		&recordHTTP, "record-http", "",
+		"Record all requests to the AWS APIs and their responses to this fixture file, with credentials scrubbed",
+	)
+	cmd.PersistentFlags().MarkHidden("record-http")
+
 	cmd.PersistentFlags().CountVarP(
 		&verbosity, "verbosity", "v",
 		"Controls loggging verbosity. Can be specified multiple times (eg -vv) or a count can "+
@@ -65,6 +130,9 @@ func RootCommand() *cobra.Command {
 	var awsCfgP *aws.Config
 
 	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := cfgOptions.LoadEnv(); err != nil {
+			return err
+		}
 		if err := cfgOptions.Validate(); err != nil {
 			return err
 		}
@@ -78,6 +146,17 @@ func RootCommand() *cobra.Command {
 
 		log.Debugf("Starting run with the following args: %v", os.Args)
 
+		if recordHTTP != "" {
+			recorder := http.NewRecorder(awshttp.NewBuildableClient())
+			cfgOptions.HTTPClient = recorder
+			// finalizers also run when the command fails, so failed requests are recorded too
+			cobra.OnFinalize(func() {
+				if err := recorder.Save(recordHTTP); err != nil {
+					log.Errorf("Failed to save recorded requests: %v", err)
+				}
+			})
+		}
+
 		awsCfg, err := awst.NewAWSConfig(cmd.Context(), cfgOptions)
 		awsCfgP = &awsCfg
 		if err != nil {
@@ -86,10 +165,16 @@ func RootCommand() *cobra.Command {
 		return nil
 	}
 
+	awstcmd.AddSubCommand(&cmd, diff.Command(&awsCfgP))
 	awstcmd.AddSubCommand(&cmd, dump.Command(&awsCfgP))
 	awstcmd.AddSubCommand(&cmd, ec2.Command(&awsCfgP))
+	awstcmd.AddSubCommand(&cmd, ecs.Command(&awsCfgP))
 	awstcmd.AddSubCommand(&cmd, es.Command(&awsCfgP))
+	awstcmd.AddSubCommand(&cmd, lambda.Command(&awsCfgP))
+	awstcmd.AddSubCommand(&cmd, query.Command(&awsCfgP))
+	awstcmd.AddSubCommand(&cmd, rds.Command(&awsCfgP))
 	awstcmd.AddSubCommand(&cmd, s3.Command(&awsCfgP))
+	awstcmd.AddSubCommand(&cmd, sqlite.Command(&awsCfgP))
 
 	return &cmd
 }
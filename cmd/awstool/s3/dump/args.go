@@ -18,6 +18,7 @@ var maxVersions int = 100
 var oldestVersionStr string
 var oldestVersion time.Time
 var outputDir string
+var parallelism int = 10
 
 var durationPattern = regexp.MustCompile(`` +
 	`^` +
@@ -34,7 +35,15 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 		Short:         "downloads all files from a given s3 bucket",
 		SilenceErrors: true,
 		PreRunE:       parseArgs,
-		RunE:          run,
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+		return run(cmd.Context(), **awsCfg)
 	}
 
 	cmd.PersistentFlags().StringVarP(
@@ -61,7 +70,14 @@ func Command(awsCfg **aws.Config) *cobra.Command {
 	)
 	cmd.PersistentFlags().StringVarP(
 		&outputDir, "output-dir", "o", outputDir,
-		"Local directory to store files. If not specified, files will be downloaded to ./<bucket>",
+		"Local directory to store files. If not specified, files will be downloaded to ./<bucket>. "+
+			"Files already downloaded to this directory by a previous run are not downloaded again, "+
+			"so an interrupted dump can be resumed by running the same command again. "+
+			"When --with-versions is enabled, versions of each key are stored in a <key>.versions directory",
+	)
+	cmd.PersistentFlags().IntVar(
+		&parallelism, "parallelism", parallelism,
+		"How many objects to download in parallel",
 	)
 
 	return &cmd
@@ -74,6 +90,9 @@ func parseArgs(cmd *cobra.Command, args []string) error {
 	if bucket == "" {
 		return errors.New("bucket not specified")
 	}
+	if parallelism < 1 {
+		return fmt.Errorf("parallelism must be higher than zero: %d", parallelism)
+	}
 	if maxVersions < 0 {
 		return fmt.Errorf("max versions cannot be lower than zero: %d", maxVersions)
 	}
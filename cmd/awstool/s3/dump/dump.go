@@ -2,29 +2,279 @@ package dump
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/spf13/cobra"
+	"awstool/aws/s3"
+	"awstool/common"
+	"awstool/executor"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
 )
 
-func run(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	client := s3.New(s3.Options{Region: region})
+const partialSuffix = ".partial"
+const versionsSuffix = ".versions"
+
+type downloader struct {
+	ctx      context.Context
+	cfg      aws.Config
+	executor *executor.Executor
+	manifest *manifest
+	// slots bound how many downloads run or wait to run at once. They are taken before launching
+	// a download, so listing blocks while all are busy instead of parking a goroutine per object
+	slots *semaphore.Weighted
+
+	errorsLock sync.Mutex
+	errors     []error
+
+	downloaded int32
+	skipped    int32
+}
+
+func run(ctx context.Context, cfg aws.Config) error {
+	cfg.Region = region
 	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
 		return err
 	}
+
+	manifest, err := openManifest(outputDir)
+	if err != nil {
+		return err
+	}
+	defer manifest.Close()
+
+	d := newDownloader(ctx, cfg, manifest, parallelism)
+
+	var listErr error
 	if downloadVersions {
-		return downloadVersioned(ctx, client)
+		listErr = downloadVersioned(d)
 	} else {
-		return downloadLatest(ctx, client)
+		listErr = downloadLatest(d)
 	}
-}
 
-func downloadVersioned(ctx context.Context, client *s3.Client) error {
+	// downloads in flight are bound to the same context, so they will return quickly
+	// in case of cancellation. We wait on them regardless to not leave partial state behind
+	<-d.executor.Done()
+
+	if ctx.Err() != nil {
+		log.Warnf(
+			"Interrupted after downloading %d objects. Run the same command again to resume",
+			d.downloaded,
+		)
+		return ctx.Err()
+	}
+
+	log.Infof(
+		"Downloaded %d objects from s3://%s to %s (%d skipped as already downloaded)",
+		d.downloaded, bucket, outputDir, d.skipped,
+	)
+
+	if listErr != nil {
+		d.errors = append(d.errors, listErr)
+	}
+	if len(d.errors) > 0 {
+		return common.NewErrors(d.errors)
+	}
 	return nil
 }
 
-func downloadLatest(ctx context.Context, client *s3.Client) error {
-	return nil
+func downloadLatest(d *downloader) error {
+	return s3.ListAllObjects(d.ctx, d.cfg, bucket, func(objects []s3Types.Object) error {
+		for _, object := range objects {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, "/") && object.Size == 0 {
+				// "folder" placeholder objects have no content to be downloaded, others are
+				// reported by localPath as they cannot be stored as a file
+				continue
+			}
+			path, err := localPath(key)
+			if err != nil {
+				d.addError(err)
+				continue
+			}
+			d.schedule(
+				manifestEntry{Key: key, ETag: aws.ToString(object.ETag), Size: object.Size},
+				path,
+			)
+		}
+		return d.ctx.Err()
+	})
+}
+
+func downloadVersioned(d *downloader) error {
+	// versions of the same key are listed contiguously from the latest to the oldest,
+	// so tracking only the current key is enough to count previous versions
+	currentKey := ""
+	previousVersions := 0
+
+	return s3.ListAllObjectVersions(d.ctx, d.cfg, bucket, func(versions []s3Types.ObjectVersion) error {
+		for _, version := range versions {
+			key := aws.ToString(version.Key)
+			if key != currentKey {
+				currentKey = key
+				previousVersions = 0
+			}
+			if strings.HasSuffix(key, "/") && version.Size == 0 {
+				continue
+			}
+			if !version.IsLatest {
+				previousVersions++
+				if maxVersions > 0 && previousVersions > maxVersions {
+					continue
+				}
+				if !oldestVersion.IsZero() && version.LastModified != nil && version.LastModified.Before(oldestVersion) {
+					continue
+				}
+			}
+			path, err := localVersionPath(key, version)
+			if err != nil {
+				d.addError(err)
+				continue
+			}
+			d.schedule(
+				manifestEntry{
+					Key:       key,
+					VersionId: aws.ToString(version.VersionId),
+					ETag:      aws.ToString(version.ETag),
+					Size:      version.Size,
+				},
+				path,
+			)
+		}
+		return d.ctx.Err()
+	})
+}
+
+func newDownloader(ctx context.Context, cfg aws.Config, manifest *manifest, parallelism int) *downloader {
+	return &downloader{
+		ctx: ctx,
+		cfg: cfg,
+		// concurrency is already bound by the slots, and launched functions must always run to
+		// release theirs
+		executor: executor.NewExecutor(0),
+		manifest: manifest,
+		slots:    semaphore.NewWeighted(int64(parallelism)),
+	}
+}
+
+func (d *downloader) schedule(entry manifestEntry, path string) {
+	if d.manifest.contains(entry) && fileHasSize(path, entry.Size) {
+		log.Debugf("Skipping %s as it was already downloaded to %s", describeEntry(entry), path)
+		atomic.AddInt32(&d.skipped, 1)
+		return
+	}
+	if err := d.slots.Acquire(d.ctx, 1); err != nil {
+		// cancelled, which is reported once listing stops
+		return
+	}
+	d.executor.Launch(d.ctx, func() {
+		defer d.slots.Release(1)
+		if d.ctx.Err() != nil {
+			return
+		}
+		if err := d.download(entry, path); err != nil {
+			if d.ctx.Err() == nil {
+				d.addError(fmt.Errorf("failed to download %s: %w", describeEntry(entry), err))
+			}
+			return
+		}
+		atomic.AddInt32(&d.downloaded, 1)
+	})
+}
+
+func (d *downloader) download(entry manifestEntry, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	// objects are first written to a partial file and only moved to their final location
+	// once fully downloaded, so an interrupted download never looks like a complete one
+	partialPath := path + partialSuffix
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return err
+	}
+
+	var versionId *string
+	if entry.VersionId != "" {
+		versionId = &entry.VersionId
+	}
+	written, err := s3.DownloadObject(d.ctx, d.cfg, bucket, entry.Key, versionId, file)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && written != entry.Size {
+		err = fmt.Errorf("expected %d bytes but downloaded %d", entry.Size, written)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+
+	if err := os.Rename(partialPath, path); err != nil {
+		return err
+	}
+	log.Debugf("Downloaded %s to %s (%d bytes)", describeEntry(entry), path, written)
+	return d.manifest.add(entry)
+}
+
+func (d *downloader) addError(err error) {
+	d.errorsLock.Lock()
+	defer d.errorsLock.Unlock()
+	d.errors = append(d.errors, err)
+}
+
+// localPath maps an object key to a path under the output directory. Keys that would
+// escape the output directory (eg "../file") are rejected, as are keys that are not clean
+// (eg "a//b" or "a/./b"), which would otherwise be written over the file of another key
+func localPath(key string) (string, error) {
+	if path.Clean(key) != key || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("key %q is not a clean path and could collide with another key", key)
+	}
+	local := filepath.Join(outputDir, filepath.FromSlash(key))
+	rel, err := filepath.Rel(outputDir, local)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("key %q cannot be mapped to a file under %s", key, outputDir)
+	}
+	if local == manifestPath(outputDir) || strings.HasSuffix(local, partialSuffix) {
+		return "", fmt.Errorf("key %q clashes with a file used internally by the dump", key)
+	}
+	return local, nil
+}
+
+// localVersionPath maps an object version to a file inside a directory dedicated to all
+// versions of the key, eg versions of "dir/file.txt" are stored as
+// "dir/file.txt.versions/20060102T150405Z_<versionId>", which sorts them chronologically
+func localVersionPath(key string, version s3Types.ObjectVersion) (string, error) {
+	path, err := localPath(key)
+	if err != nil {
+		return "", err
+	}
+	timestamp := "unknown"
+	if version.LastModified != nil {
+		timestamp = version.LastModified.UTC().Format("20060102T150405Z")
+	}
+	versionId := strings.ReplaceAll(aws.ToString(version.VersionId), string(filepath.Separator), "_")
+	return filepath.Join(path+versionsSuffix, timestamp+"_"+versionId), nil
+}
+
+func fileHasSize(path string, size int64) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Size() == size
+}
+
+func describeEntry(entry manifestEntry) string {
+	if entry.VersionId == "" {
+		return fmt.Sprintf("s3://%s/%s", bucket, entry.Key)
+	}
+	return fmt.Sprintf("s3://%s/%s (version %s)", bucket, entry.Key, entry.VersionId)
 }
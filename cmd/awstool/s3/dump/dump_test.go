@@ -0,0 +1,137 @@
+package dump
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestLocalPath(t *testing.T) {
+	outputDir = t.TempDir()
+
+	valid := map[string]string{
+		"a.txt":     "a.txt",
+		"dir/b.txt": filepath.Join("dir", "b.txt"),
+		"x/y/f.go":  filepath.Join("x", "y", "f.go"),
+	}
+	for key, expected := range valid {
+		path, err := localPath(key)
+		if err != nil {
+			t.Errorf("expected key %q to be valid: %v", key, err)
+			continue
+		}
+		if path != filepath.Join(outputDir, expected) {
+			t.Errorf("expected key %q to map to %s, got %s", key, expected, path)
+		}
+	}
+
+	invalid := []string{
+		"../x",
+		"../../etc/passwd",
+		"dir/../../x",
+		"..",
+		".",
+		"/",
+		// keys that are not clean would collide with "a/b"
+		"a//b",
+		"a/./b",
+		"x/../a/b",
+		"/a/b",
+		"a/b/",
+		manifestFileName,
+		"file" + partialSuffix,
+	}
+	for _, key := range invalid {
+		if path, err := localPath(key); err == nil {
+			t.Errorf("expected key %q to be rejected, got %s", key, path)
+		}
+	}
+}
+
+func TestScheduleSkipsDownloaded(t *testing.T) {
+	outputDir = t.TempDir()
+	m, err := openManifest(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// a cancelled context keeps downloads from running, so only skips are counted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := newDownloader(ctx, aws.Config{}, m, 1)
+
+	entry := manifestEntry{Key: "a.txt", ETag: "1", Size: 5}
+	path := filepath.Join(outputDir, "a.txt")
+	if err := m.add(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		entry   manifestEntry
+		skipped int32
+	}{
+		{"downloaded", "hello", entry, 1},
+		{"truncated file", "hel", entry, 0},
+		{"changed object", "hello", manifestEntry{Key: "a.txt", ETag: "2", Size: 5}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			d.skipped = 0
+			d.schedule(test.entry, path)
+			<-d.executor.Done()
+			if d.skipped != test.skipped {
+				t.Errorf("expected %d skipped, got %d", test.skipped, d.skipped)
+			}
+		})
+	}
+}
+
+func TestScheduleBlocksWhenBusy(t *testing.T) {
+	outputDir = t.TempDir()
+	m, err := openManifest(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newDownloader(ctx, aws.Config{}, m, 1)
+
+	// with the only slot taken, scheduling must wait for it instead of queueing the download
+	if err := d.slots.Acquire(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	scheduled := make(chan struct{})
+	go func() {
+		defer close(scheduled)
+		d.schedule(manifestEntry{Key: "a.txt", Size: 5}, filepath.Join(outputDir, "a.txt"))
+	}()
+	select {
+	case <-scheduled:
+		t.Fatal("expected schedule to block while all slots are busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-scheduled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected schedule to return once cancelled")
+	}
+	d.slots.Release(1)
+	<-d.executor.Done()
+	if d.downloaded != 0 || len(d.errors) != 0 {
+		t.Errorf("expected nothing to be downloaded once cancelled, got %d downloads and errors %v", d.downloaded, d.errors)
+	}
+}
@@ -0,0 +1,110 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const manifestFileName = ".awstool-s3-dump.manifest"
+
+// manifestEntry identifies a single downloaded object (or object version). ETag and size are
+// part of the identity so objects that changed since the last run get downloaded again
+type manifestEntry struct {
+	Key       string `json:"key"`
+	VersionId string `json:"versionId,omitempty"`
+	ETag      string `json:"etag"`
+	Size      int64  `json:"size"`
+}
+
+// manifest keeps track of which objects were already fully downloaded to the output
+// directory, so an interrupted dump can be resumed. It is stored as an append only file
+// with one json encoded entry per line, which means that a crash can at most lose the
+// very last entry
+type manifest struct {
+	lock    sync.Mutex
+	file    *os.File
+	entries map[manifestEntry]struct{}
+}
+
+func manifestPath(dir string) string {
+	return filepath.Join(dir, manifestFileName)
+}
+
+func openManifest(dir string) (*manifest, error) {
+	path := manifestPath(dir)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s: %w", path, err)
+	}
+
+	m := manifest{
+		file:    file,
+		entries: map[manifestEntry]struct{}{},
+	}
+
+	reader := bufio.NewReader(file)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] != '\n' {
+			// last line was partially written by a previous run. Terminate it so new
+			// entries do not get appended to it
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to write to manifest %s: %w", path, err)
+			}
+		}
+		if len(line) > 0 {
+			var entry manifestEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				log.Warnf("Ignoring corrupted line %d of manifest %s: %v", lineNum, path, err)
+			} else {
+				m.entries[entry] = struct{}{}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+		}
+	}
+
+	log.Debugf("Loaded %d entries from manifest %s", len(m.entries), path)
+	return &m, nil
+}
+
+func (m *manifest) contains(entry manifestEntry) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, ok := m.entries[entry]
+	return ok
+}
+
+func (m *manifest) add(entry manifestEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, err := m.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write to manifest %s: %w", m.file.Name(), err)
+	}
+	m.entries[entry] = struct{}{}
+	return nil
+}
+
+func (m *manifest) Close() error {
+	return m.file.Close()
+}
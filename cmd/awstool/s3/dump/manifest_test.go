@@ -0,0 +1,71 @@
+package dump
+
+import (
+	"os"
+	"testing"
+)
+
+func TestManifestReopen(t *testing.T) {
+	dir := t.TempDir()
+	entries := []manifestEntry{
+		{Key: "a.txt", ETag: `"1"`, Size: 1},
+		{Key: "dir/b.txt", VersionId: "v1", ETag: `"2"`, Size: 2},
+	}
+
+	m, err := openManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := m.add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.Close()
+
+	m, err = openManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	for _, entry := range entries {
+		if !m.contains(entry) {
+			t.Errorf("expected manifest to contain %+v", entry)
+		}
+	}
+	changed := entries[0]
+	changed.ETag = `"3"`
+	if m.contains(changed) {
+		t.Errorf("expected manifest to not contain changed object %+v", changed)
+	}
+}
+
+func TestManifestPartialLastLine(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"key":"a.txt","etag":"1","size":1}` + "\n" + `{"key":"b.t`
+	if err := os.WriteFile(manifestPath(dir), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := openManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := manifestEntry{Key: "c.txt", ETag: "3", Size: 3}
+	if err := m.add(entry); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	m, err = openManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if !m.contains(manifestEntry{Key: "a.txt", ETag: "1", Size: 1}) {
+		t.Error("expected entries before the partial line to be kept")
+	}
+	if !m.contains(entry) {
+		t.Error("expected entries added after the partial line to be readable")
+	}
+}
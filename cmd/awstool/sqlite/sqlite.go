@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"awstool/loader"
+	"awstool/sqlite"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+func Command(awsCfg **aws.Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "sqlite DUMP_FILE DATABASE_FILE",
+		Short: "converts a dump into a SQLite database for ad-hoc SQL queries",
+		Long: "Converts a json file generated by the dump command into a SQLite database with one table per " +
+			"resource type (eg ec2_instances, ec2_volumes, tags, elb_load_balancers, s3_buckets, es_domains, " +
+			"iam_users, organizations_accounts), so resources can be queried and joined across services with " +
+			"plain SQL. Pass - as DUMP_FILE to read the dump from stdin. An existing database is replaced. " +
+			"Only dumps of a single account are supported: use dump --assume-role --sqlite for multiple accounts",
+		SilenceErrors: true,
+	}
+
+	cmd.Args = cobra.ExactArgs(2)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// We silence usage here instead of setting in the command struct declaration because it is
+		// only at this point forward that we want to not display the usage when an error occurs,
+		// as it will be an execution error, not a parsing/usage error
+		// See more at https://github.com/spf13/cobra/issues/340
+		cmd.SilenceUsage = true
+
+		data, err := loader.LoadDumpFile(args[0])
+		if err != nil {
+			return err
+		}
+		return sqlite.Write(args[1], data)
+	}
+
+	return &cmd
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"strconv"
+
 	"github.com/spf13/cobra"
 )
 
@@ -47,3 +49,24 @@ func AddSubCommand(cmd *cobra.Command, subCmd *cobra.Command) {
 	}
 	cmd.AddCommand(subCmd)
 }
+
+const failureExitCodeAnnotation = "failureExitCode"
+
+// SetFailureExitCode makes the command exit with this code instead of 1 when it fails, including
+// when the setup done by its parent commands fails. Errors carrying their own exit code keep it
+func SetFailureExitCode(cmd *cobra.Command, code int) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[failureExitCodeAnnotation] = strconv.Itoa(code)
+}
+
+// FailureExitCode returns the exit code set with SetFailureExitCode, or 1 when none was set
+func FailureExitCode(cmd *cobra.Command) int {
+	if cmd != nil {
+		if code, err := strconv.Atoi(cmd.Annotations[failureExitCodeAnnotation]); err == nil {
+			return code
+		}
+	}
+	return 1
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+
+	awst "awstool/aws"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+// FromDumpFlag is the name of the root flag that points to a previous dump to be used instead of
+// fetching data live from the AWS APIs
+const FromDumpFlag = "from-dump"
+
+// LoadFunc loads AWS data in the same way loader.LoadAWS does
+type LoadFunc = func(ctx context.Context, cfg aws.Config, options ...loader.Option) (*awst.AWS, error)
+
+// Loader returns the function a command should use to load AWS data. By default data is fetched
+// live with loader.LoadAWS, but if --from-dump was passed then the data is read from that dump
+func Loader(cmd *cobra.Command) LoadFunc {
+	path := FromDump(cmd)
+	if path == "" {
+		return loader.LoadAWS
+	}
+	return func(ctx context.Context, cfg aws.Config, options ...loader.Option) (*awst.AWS, error) {
+		return loader.LoadDumpFile(path, options...)
+	}
+}
+
+// FromDump returns the dump file passed in with --from-dump, or an empty string if the command
+// should work with live data
+func FromDump(cmd *cobra.Command) string {
+	flag := cmd.Flags().Lookup(FromDumpFlag)
+	if flag == nil {
+		return ""
+	}
+	return flag.Value.String()
+}
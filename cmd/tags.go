@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"awstool/aws/ec2"
+)
+
+// ParseTags parses tag key/value pairs in the KEY:VALUE format, as in ec2 resolve --tags
+func ParseTags(tags []string) (map[string]string, error) {
+	parsedTags := map[string]string{}
+	if len(tags) == 0 {
+		return parsedTags, nil
+	}
+	for _, kvpair := range tags {
+		kvpair = strings.TrimSpace(kvpair)
+		separatorIdx := strings.Index(kvpair, ":")
+		if separatorIdx == -1 {
+			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: missing \":\" separator", tags, kvpair)
+		}
+		key := kvpair[0:separatorIdx]
+		value := kvpair[separatorIdx+1:]
+		if len(key) == 0 {
+			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: no key", tags, kvpair)
+		}
+		if len(value) == 0 {
+			return nil, fmt.Errorf("invalid tags specification in %q: cannot parse %q: no value", tags, kvpair)
+		}
+		parsedTags[key] = value
+	}
+	return parsedTags, nil
+}
+
+// TagFetchOptions converts tags parsed with ParseTags to fetch options. Alternative values for
+// a key are separated by |, eg Env:dev|staging
+func TagFetchOptions(tags map[string]string) []ec2.FetchOption {
+	options := []ec2.FetchOption{}
+	for key, value := range tags {
+		options = append(options, ec2.WithFilter("tag:"+key, strings.Split(value, "|")...))
+	}
+	return options
+}
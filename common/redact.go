@@ -0,0 +1,5 @@
+package common
+
+// RedactedValue replaces the values of environment variables in dumps, as they often hold
+// secrets. Only their names are kept
+const RedactedValue = "<redacted>"
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WildcardRegexp compiles a pattern in which * matches any characters and ? matches a single
+// one. Everything else is matched literally
+func WildcardRegexp(pattern string) *regexp.Regexp {
+	builder := strings.Builder{}
+	builder.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
+
+// MatchesAny tells whether the value matches any of the wildcard patterns, or true if there are
+// none
+func MatchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if WildcardRegexp(pattern).MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestWildcardRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matches bool
+	}{
+		{"web-*", "web-1", true},
+		{"web-*", "api-1", false},
+		{"web-?", "web-12", false},
+		{"*/prod", "team/prod", true},
+		{"a.b", "axb", false},
+		{"[ab]", "a", false},
+		{"[ab]", "[ab]", true},
+		{`a\*`, `a\b`, true},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		if matches := WildcardRegexp(test.pattern).MatchString(test.value); matches != test.matches {
+			t.Errorf("expected %q matching %q to be %v", test.pattern, test.value, test.matches)
+		}
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	if !MatchesAny(nil, "anything") {
+		t.Errorf("expected no patterns to match anything")
+	}
+	if !MatchesAny([]string{"api-*", "web-*"}, "web-1") {
+		t.Errorf("expected web-1 to match web-*")
+	}
+	if MatchesAny([]string{"api-*", "web-?"}, "web-12") {
+		t.Errorf("expected web-12 to match neither api-* nor web-?")
+	}
+}
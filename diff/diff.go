@@ -0,0 +1,606 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// Change is a single field level change of a modified resource. Path points to the changed
+// field, eg "State.Name", "Tags.Env" or "BlockDeviceMappings[0].Ebs.Status". Old is nil if
+// the field was added and New is nil if it was removed
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// ResourceDiff describes how a single resource differs between two dumps. Changes is only
+// filled for modified resources. Global resources (eg IAM ones) have an empty region
+type ResourceDiff struct {
+	Type    string
+	Region  string
+	Id      string
+	Status  string
+	Changes []Change
+}
+
+// NotCompared describes data that is missing from one of the dumps, so its resources are left
+// out of the comparison instead of being reported as added or removed. Service is empty when a
+// whole region is missing, and Region is empty when a service was not loaded in any region
+type NotCompared struct {
+	Service string
+	Region  string
+	Reason  string
+}
+
+type Result struct {
+	Resources   []ResourceDiff
+	NotCompared []NotCompared
+}
+
+func (r *Result) HasChanges() bool {
+	return len(r.Resources) > 0
+}
+
+type options struct {
+	ignoreFields []string
+}
+
+type Option func(opts *options)
+
+// WithIgnoredFields ignores changes to fields matching any of the given patterns. Patterns are
+// matched against the change path with path.Match syntax, eg "PasswordLastUsed" or "Tags.*"
+func WithIgnoredFields(patterns ...string) Option {
+	return func(opts *options) {
+		opts.ignoreFields = append(opts.ignoreFields, patterns...)
+	}
+}
+
+func newOptions(fns []Option) options {
+	options := options{}
+	for _, fn := range fns {
+		fn(&options)
+	}
+	return options
+}
+
+// Diff compares two dumps, matching resources by their stable identity (eg instance id or
+// bucket name) and reporting which resources were added, removed or modified
+func Diff(before *awst.AWS, after *awst.AWS, opts ...Option) (*Result, error) {
+	options := newOptions(opts)
+
+	result := Result{Resources: []ResourceDiff{}}
+	scope := newComparedScope(before, after, &result)
+
+	oldResources := collect(before, scope)
+	newResources := collect(after, scope)
+
+	for key, oldResource := range oldResources {
+		newResource, ok := newResources[key]
+		if !ok {
+			result.Resources = append(result.Resources, key.diff(Removed, nil))
+			continue
+		}
+		changes, err := compare(oldResource, newResource, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s %s: %w", key.Type, key.Id, err)
+		}
+		if len(changes) > 0 {
+			result.Resources = append(result.Resources, key.diff(Modified, changes))
+		}
+	}
+	for key := range newResources {
+		if _, ok := oldResources[key]; !ok {
+			result.Resources = append(result.Resources, key.diff(Added, nil))
+		}
+	}
+
+	sort.Slice(result.Resources, func(i, j int) bool {
+		a, b := result.Resources[i], result.Resources[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Id < b.Id
+	})
+	sort.Slice(result.NotCompared, func(i, j int) bool {
+		a, b := result.NotCompared[i], result.NotCompared[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Region < b.Region
+	})
+
+	return &result, nil
+}
+
+// comparedScope tells which resources can be compared, which are those whose region and service
+// were loaded without failures in both dumps
+type comparedScope struct {
+	skippedRegions  map[string]struct{}
+	skippedServices map[string]struct{}
+	failed          map[NotCompared]struct{}
+	// services that failed in any region, which also leaves their global resources uncompared
+	failedServices map[string]struct{}
+}
+
+// newComparedScope finds what is missing from either dump and reports it in the result
+func newComparedScope(before *awst.AWS, after *awst.AWS, result *Result) comparedScope {
+	scope := comparedScope{
+		skippedRegions:  map[string]struct{}{},
+		skippedServices: map[string]struct{}{},
+		failed:          map[NotCompared]struct{}{},
+		failedServices:  map[string]struct{}{},
+	}
+
+	for region := range before.Regions {
+		if _, ok := after.Regions[region]; !ok {
+			scope.skippedRegions[region] = struct{}{}
+			result.NotCompared = append(result.NotCompared, NotCompared{Region: region, Reason: "only in the old dump"})
+		}
+	}
+	for region := range after.Regions {
+		if _, ok := before.Regions[region]; !ok {
+			scope.skippedRegions[region] = struct{}{}
+			result.NotCompared = append(result.NotCompared, NotCompared{Region: region, Reason: "only in the new dump"})
+		}
+	}
+
+	// dumps generated before services were recorded are assumed to have the same services
+	if before.Services != nil && after.Services != nil {
+		oldServices, newServices := toSet(before.Services), toSet(after.Services)
+		for service := range oldServices {
+			if _, ok := newServices[service]; !ok {
+				scope.skippedServices[service] = struct{}{}
+				result.NotCompared = append(result.NotCompared, NotCompared{Service: service, Reason: "only in the old dump"})
+			}
+		}
+		for service := range newServices {
+			if _, ok := oldServices[service]; !ok {
+				scope.skippedServices[service] = struct{}{}
+				result.NotCompared = append(result.NotCompared, NotCompared{Service: service, Reason: "only in the new dump"})
+			}
+		}
+	}
+
+	for _, failures := range []struct {
+		reason   string
+		failures []awst.Failure
+	}{
+		{"failed to load in the old dump", before.Failures},
+		{"failed to load in the new dump", after.Failures},
+	} {
+		for _, failure := range failures.failures {
+			scope.failedServices[failure.Service] = struct{}{}
+			key := NotCompared{Service: failure.Service, Region: failure.Region}
+			if _, ok := scope.failed[key]; ok {
+				continue
+			}
+			scope.failed[key] = struct{}{}
+			result.NotCompared = append(result.NotCompared, NotCompared{
+				Service: failure.Service,
+				Region:  failure.Region,
+				Reason:  failures.reason,
+			})
+		}
+	}
+
+	return scope
+}
+
+func (s comparedScope) includes(key resourceKey) bool {
+	if _, ok := s.skippedRegions[key.Region]; ok {
+		return false
+	}
+	service := resourceService(key.Type)
+	if _, ok := s.skippedServices[service]; ok {
+		return false
+	}
+	// global resources, eg s3 buckets, are listed from every region, so a failure in any of them
+	// may have left some out
+	if key.Region == "" {
+		_, failed := s.failedServices[service]
+		return !failed
+	}
+	_, failed := s.failed[NotCompared{Service: service, Region: key.Region}]
+	return !failed
+}
+
+// resourceService returns the service of the dump command that loads a resource type. Most
+// ec2 resources are loaded by a service of their own, eg ec2.subnet by the subnet service
+func resourceService(resourceType string) string {
+	service, name, _ := strings.Cut(resourceType, ".")
+	if service != "ec2" {
+		return service
+	}
+	switch name {
+	case "instance":
+		return "ec2"
+	case "volume":
+		return "ebs"
+	default:
+		return name
+	}
+}
+
+func toSet(values []string) map[string]struct{} {
+	result := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		result[strings.ToLower(value)] = struct{}{}
+	}
+	return result
+}
+
+type resourceKey struct {
+	Type   string
+	Region string
+	Id     string
+}
+
+func (k resourceKey) diff(status string, changes []Change) ResourceDiff {
+	return ResourceDiff{
+		Type:    k.Type,
+		Region:  k.Region,
+		Id:      k.Id,
+		Status:  status,
+		Changes: changes,
+	}
+}
+
+// collector registers a resource found in a dump under a type and identity. Pass an empty
+// region for global resources
+type collector = func(resourceType string, region string, id string, resource interface{})
+
+// resourceExtractors list, per service, how to find resources in a dump and how they are identified
+var resourceExtractors = []func(*awst.AWS, collector){
+	extractOrganizations,
+	extractIAM,
+	extractEC2,
+	extractELB,
+	extractS3,
+	extractElasticsearch,
+	extractRDS,
+	extractLambda,
+	extractECS,
+	extractEKS,
+	extractAutoScaling,
+}
+
+func collect(dump *awst.AWS, scope comparedScope) map[resourceKey]interface{} {
+	result := map[resourceKey]interface{}{}
+	add := func(resourceType string, region string, id string, resource interface{}) {
+		key := resourceKey{Type: resourceType, Region: region, Id: id}
+		if scope.includes(key) {
+			result[key] = resource
+		}
+	}
+	for _, extract := range resourceExtractors {
+		extract(dump, add)
+	}
+	return result
+}
+
+func extractOrganizations(dump *awst.AWS, add collector) {
+	for _, account := range dump.Accounts {
+		add("organizations.account", "", aws.ToString(account.Id), account)
+	}
+}
+
+func extractIAM(dump *awst.AWS, add collector) {
+	for _, user := range dump.IAM.Users {
+		add("iam.user", "", aws.ToString(user.UserName), user)
+	}
+	for _, role := range dump.IAM.Roles {
+		add("iam.role", "", aws.ToString(role.RoleName), role)
+	}
+	for _, group := range dump.IAM.Groups {
+		add("iam.group", "", aws.ToString(group.GroupName), group)
+	}
+	for _, policy := range dump.IAM.Policies {
+		add("iam.policy", "", aws.ToString(policy.Arn), policy)
+	}
+}
+
+func extractEC2(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for _, reservation := range region.EC2.Reservations {
+			for _, instance := range reservation.Instances {
+				add("ec2.instance", name, aws.ToString(instance.InstanceId), instance)
+			}
+		}
+		for _, volume := range region.EC2.Volumes {
+			add("ec2.volume", name, aws.ToString(volume.VolumeId), volume)
+		}
+		for _, vpc := range region.EC2.Vpcs {
+			add("ec2.vpc", name, aws.ToString(vpc.VpcId), vpc)
+		}
+		for _, subnet := range region.EC2.Subnets {
+			add("ec2.subnet", name, aws.ToString(subnet.SubnetId), subnet)
+		}
+		for _, table := range region.EC2.RouteTables {
+			add("ec2.routetable", name, aws.ToString(table.RouteTableId), table)
+		}
+		for _, group := range region.EC2.SecurityGroups {
+			add("ec2.securitygroup", name, aws.ToString(group.GroupId), group)
+		}
+		for _, acl := range region.EC2.NetworkAcls {
+			add("ec2.networkacl", name, aws.ToString(acl.NetworkAclId), acl)
+		}
+		for _, gateway := range region.EC2.NatGateways {
+			add("ec2.natgateway", name, aws.ToString(gateway.NatGatewayId), gateway)
+		}
+		for _, gateway := range region.EC2.InternetGateways {
+			add("ec2.internetgateway", name, aws.ToString(gateway.InternetGatewayId), gateway)
+		}
+		for _, peering := range region.EC2.VpcPeeringConnections {
+			add("ec2.vpcpeering", name, aws.ToString(peering.VpcPeeringConnectionId), peering)
+		}
+		for _, endpoint := range region.EC2.VpcEndpoints {
+			add("ec2.vpcendpoint", name, aws.ToString(endpoint.VpcEndpointId), endpoint)
+		}
+		for _, eni := range region.EC2.NetworkInterfaces {
+			add("ec2.eni", name, aws.ToString(eni.NetworkInterfaceId), eni)
+		}
+		for _, template := range region.EC2.LaunchTemplates {
+			add("ec2.launchtemplate", name, aws.ToString(template.Template.LaunchTemplateId), template)
+		}
+		for _, address := range region.EC2.Addresses {
+			// addresses of ec2 classic have no allocation id
+			id := aws.ToString(address.AllocationId)
+			if id == "" {
+				id = aws.ToString(address.PublicIp)
+			}
+			add("ec2.eip", name, id, address)
+		}
+	}
+}
+
+func extractELB(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		// classic load balancers have no arn, but their names are unique per region
+		for _, lb := range region.ELB.V1.LoadBalancers {
+			add("elb.v1", name, aws.ToString(lb.LoadBalancerName), lb)
+		}
+		for _, lb := range region.ELB.V2.LoadBalancers {
+			add("elb.v2", name, aws.ToString(lb.LoadBalancerArn), lb)
+		}
+	}
+}
+
+func extractS3(dump *awst.AWS, add collector) {
+	// bucket listing is global, so the same buckets show up in every region
+	for _, region := range dump.Regions {
+		for _, bucket := range region.S3.Buckets {
+			add("s3.bucket", "", aws.ToString(bucket.Name), bucket)
+		}
+	}
+}
+
+func extractElasticsearch(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for domainName, domain := range region.Elasticsearch.Domains {
+			add("elasticsearch.domain", name, domainName, domain)
+		}
+	}
+}
+
+func extractRDS(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for _, instance := range region.RDS.Instances {
+			add("rds.instance", name, aws.ToString(instance.DBInstanceIdentifier), instance)
+		}
+		for _, cluster := range region.RDS.Clusters {
+			add("rds.cluster", name, aws.ToString(cluster.DBClusterIdentifier), cluster)
+		}
+	}
+}
+
+func extractLambda(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for functionName, function := range region.Lambda.Functions {
+			add("lambda.function", name, functionName, function)
+		}
+	}
+}
+
+// extractECS skips tasks, which are replaced on every deployment and would only add noise
+func extractECS(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for clusterName, cluster := range region.ECS.Clusters {
+			if cluster.Cluster != nil {
+				add("ecs.cluster", name, clusterName, cluster.Cluster)
+			}
+			for _, service := range cluster.Services {
+				add("ecs.service", name, clusterName+"/"+aws.ToString(service.ServiceName), service)
+			}
+		}
+		for _, definition := range region.ECS.TaskDefinitions {
+			add("ecs.taskdefinition", name, aws.ToString(definition.Family), definition)
+		}
+	}
+}
+
+func extractEKS(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for clusterName, cluster := range region.EKS.Clusters {
+			if cluster.Cluster != nil {
+				add("eks.cluster", name, clusterName, cluster.Cluster)
+			}
+			for _, nodegroup := range cluster.Nodegroups {
+				add("eks.nodegroup", name, clusterName+"/"+aws.ToString(nodegroup.NodegroupName), nodegroup)
+			}
+			for _, profile := range cluster.FargateProfiles {
+				add("eks.fargateprofile", name, clusterName+"/"+aws.ToString(profile.FargateProfileName), profile)
+			}
+		}
+	}
+}
+
+func extractAutoScaling(dump *awst.AWS, add collector) {
+	for name, region := range dump.Regions {
+		for _, group := range region.AutoScaling.Groups {
+			add("autoscaling.group", name, aws.ToString(group.AutoScalingGroupName), group)
+		}
+		for _, configuration := range region.AutoScaling.LaunchConfigurations {
+			add("autoscaling.launchconfiguration", name, aws.ToString(configuration.LaunchConfigurationName), configuration)
+		}
+		for _, policy := range region.AutoScaling.Policies {
+			id := aws.ToString(policy.AutoScalingGroupName) + "/" + aws.ToString(policy.PolicyName)
+			add("autoscaling.policy", name, id, policy)
+		}
+		for _, action := range region.AutoScaling.ScheduledActions {
+			id := aws.ToString(action.AutoScalingGroupName) + "/" + aws.ToString(action.ScheduledActionName)
+			add("autoscaling.scheduledaction", name, id, action)
+		}
+	}
+}
+
+func compare(before interface{}, after interface{}, options options) ([]Change, error) {
+	oldValue, err := normalize(before)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := normalize(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	compareValues("", oldValue, newValue, &changes)
+
+	result := []Change{}
+	for _, change := range changes {
+		if !ignored(change.Path, options) {
+			result = append(result, change)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// normalize converts a resource to generic json values (maps, slices and scalars), so all
+// resource types can be compared in the same way
+func normalize(resource interface{}) (interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func compareValues(path string, before interface{}, after interface{}, changes *[]Change) {
+	if oldList, ok := before.([]interface{}); ok {
+		if tags, ok := tagsMap(oldList); ok {
+			before = tags
+		}
+	}
+	if newList, ok := after.([]interface{}); ok {
+		if tags, ok := tagsMap(newList); ok {
+			after = tags
+		}
+	}
+
+	switch oldValue := before.(type) {
+	case map[string]interface{}:
+		newValue, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]struct{}{}
+		for key := range oldValue {
+			keys[key] = struct{}{}
+		}
+		for key := range newValue {
+			keys[key] = struct{}{}
+		}
+		for key := range keys {
+			compareValues(joinPath(path, key), oldValue[key], newValue[key], changes)
+		}
+		return
+	case []interface{}:
+		newValue, ok := after.([]interface{})
+		if !ok {
+			break
+		}
+		for idx := 0; idx < len(oldValue) || idx < len(newValue); idx++ {
+			var oldItem, newItem interface{}
+			if idx < len(oldValue) {
+				oldItem = oldValue[idx]
+			}
+			if idx < len(newValue) {
+				newItem = newValue[idx]
+			}
+			compareValues(fmt.Sprintf("%s[%d]", path, idx), oldItem, newItem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, Old: before, New: after})
+	}
+}
+
+// tagsMap converts lists of Key/Value pairs, which is how AWS represents tags, into a map keyed
+// by the tag key, so tags are compared regardless of their ordering
+func tagsMap(list []interface{}) (map[string]interface{}, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	result := map[string]interface{}{}
+	for _, item := range list {
+		tag, ok := item.(map[string]interface{})
+		if !ok || len(tag) != 2 {
+			return nil, false
+		}
+		key, ok := tag["Key"].(string)
+		if !ok {
+			return nil, false
+		}
+		value, ok := tag["Value"]
+		if !ok {
+			return nil, false
+		}
+		result[key] = value
+	}
+	return result, true
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func ignored(changePath string, options options) bool {
+	for _, pattern := range options.ignoreFields {
+		if matched, _ := path.Match(pattern, changePath); matched {
+			return true
+		}
+		if strings.HasPrefix(changePath, pattern+".") || strings.HasPrefix(changePath, pattern+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func instance(id string, state ec2Types.InstanceStateName, tags map[string]string) ec2Types.Instance {
+	result := ec2Types.Instance{
+		InstanceId: aws.String(id),
+		State:      &ec2Types.InstanceState{Name: state},
+	}
+	for key, value := range tags {
+		result.Tags = append(result.Tags, ec2Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
+
+func dump(instances ...ec2Types.Instance) *awst.AWS {
+	result := awst.New()
+	region := awst.NewRegion("us-east-1")
+	region.EC2.Reservations = []ec2Types.Reservation{{Instances: instances}}
+	result.Regions["us-east-1"] = region
+	return &result
+}
+
+func TestDiff(t *testing.T) {
+	before := dump(
+		instance("i-removed", ec2Types.InstanceStateNameRunning, nil),
+		instance("i-same", ec2Types.InstanceStateNameRunning, map[string]string{"Env": "prod", "Name": "a"}),
+		instance("i-changed", ec2Types.InstanceStateNameRunning, map[string]string{"Env": "prod"}),
+	)
+	after := dump(
+		// same tags in a different order should not be reported as a change
+		instance("i-same", ec2Types.InstanceStateNameRunning, map[string]string{"Name": "a", "Env": "prod"}),
+		instance("i-changed", ec2Types.InstanceStateNameStopped, map[string]string{"Env": "dev", "Owner": "me"}),
+		instance("i-added", ec2Types.InstanceStateNameRunning, nil),
+	)
+
+	result, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ResourceDiff{
+		{
+			Type: "ec2.instance", Region: "us-east-1", Id: "i-added", Status: Added,
+		},
+		{
+			Type: "ec2.instance", Region: "us-east-1", Id: "i-changed", Status: Modified,
+			Changes: []Change{
+				{Path: "State.Name", Old: "running", New: "stopped"},
+				{Path: "Tags.Env", Old: "prod", New: "dev"},
+				{Path: "Tags.Owner", Old: nil, New: "me"},
+			},
+		},
+		{
+			Type: "ec2.instance", Region: "us-east-1", Id: "i-removed", Status: Removed,
+		},
+	}
+
+	if len(result.Resources) != len(expected) {
+		t.Fatalf("expected %d resource diffs but got %d: %+v", len(expected), len(result.Resources), result.Resources)
+	}
+	for idx, resource := range result.Resources {
+		exp := expected[idx]
+		if resource.Type != exp.Type || resource.Region != exp.Region || resource.Id != exp.Id || resource.Status != exp.Status {
+			t.Errorf("resource diff #%d: expected %+v but got %+v", idx, exp, resource)
+			continue
+		}
+		if len(resource.Changes) != len(exp.Changes) {
+			t.Errorf("resource diff #%d: expected changes %+v but got %+v", idx, exp.Changes, resource.Changes)
+			continue
+		}
+		for cidx, change := range resource.Changes {
+			if change != exp.Changes[cidx] {
+				t.Errorf("resource diff #%d: expected change %+v but got %+v", idx, exp.Changes[cidx], change)
+			}
+		}
+	}
+}
+
+func TestDiffGlobalResourcesAndIgnoredFields(t *testing.T) {
+	before := awst.New()
+	before.IAM.Users = []iamTypes.User{{UserName: aws.String("bob"), Path: aws.String("/")}}
+	after := awst.New()
+	after.IAM.Users = []iamTypes.User{{UserName: aws.String("bob"), Path: aws.String("/admins/")}}
+
+	// buckets are listed in every region, but should be reported only once
+	for _, regionName := range []string{"us-east-1", "eu-west-1"} {
+		region := awst.NewRegion(regionName)
+		region.S3.Buckets = []s3Types.Bucket{{Name: aws.String("new-bucket")}}
+		after.Regions[regionName] = region
+	}
+
+	result, err := Diff(&before, &after, WithIgnoredFields("Path"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Resources) != 1 {
+		t.Fatalf("expected a single resource diff but got %+v", result.Resources)
+	}
+	resource := result.Resources[0]
+	if resource.Type != "s3.bucket" || resource.Region != "" || resource.Id != "new-bucket" || resource.Status != Added {
+		t.Fatalf("unexpected resource diff %+v", resource)
+	}
+}
+
+func TestDiffNotCompared(t *testing.T) {
+	before := dump(instance("i-1", ec2Types.InstanceStateNameRunning, nil))
+	before.Services = []string{"ec2", "ebs", "iam"}
+	before.IAM.Users = []iamTypes.User{{UserName: aws.String("bob")}}
+	region := before.Regions["us-east-1"]
+	region.EC2.Volumes = []ec2Types.Volume{{VolumeId: aws.String("vol-1")}}
+	before.Regions["us-east-1"] = region
+	region = awst.NewRegion("eu-west-1")
+	region.EC2.Reservations = []ec2Types.Reservation{
+		{Instances: []ec2Types.Instance{instance("i-2", ec2Types.InstanceStateNameRunning, nil)}},
+	}
+	before.Regions["eu-west-1"] = region
+
+	// eu-west-1 and ebs were not dumped, and iam failed to load
+	after := dump(instance("i-1", ec2Types.InstanceStateNameRunning, nil))
+	after.Services = []string{"ec2", "iam"}
+	after.Failures = []awst.Failure{{Service: "iam", Operation: "ListUsers"}, {Service: "iam", Operation: "ListRoles"}}
+
+	result, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HasChanges() {
+		t.Errorf("expected no changes, got %+v", result.Resources)
+	}
+	expected := []NotCompared{
+		{Service: "", Region: "eu-west-1", Reason: "only in the old dump"},
+		{Service: "ebs", Region: "", Reason: "only in the old dump"},
+		{Service: "iam", Region: "", Reason: "failed to load in the new dump"},
+	}
+	if !reflect.DeepEqual(result.NotCompared, expected) {
+		t.Errorf("expected not compared %+v, got %+v", expected, result.NotCompared)
+	}
+
+	// dumps without recorded services still compare all services
+	before.Services = nil
+	result, err = Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Resources) != 1 || result.Resources[0].Id != "vol-1" || result.Resources[0].Status != Removed {
+		t.Errorf("expected only vol-1 to be removed, got %+v", result.Resources)
+	}
+}
+
+func TestDiffGlobalResourcesFailedInARegion(t *testing.T) {
+	before := dump()
+	before.Failures = []awst.Failure{{Service: "s3", Region: "us-east-1", Operation: "ListBuckets"}}
+	after := dump()
+	region := after.Regions["us-east-1"]
+	region.S3.Buckets = []s3Types.Bucket{{Name: aws.String("bucket")}}
+	after.Regions["us-east-1"] = region
+
+	result, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HasChanges() {
+		t.Errorf("expected buckets to not be compared when s3 failed to load, got %+v", result.Resources)
+	}
+	expected := []NotCompared{{Service: "s3", Region: "us-east-1", Reason: "failed to load in the old dump"}}
+	if !reflect.DeepEqual(result.NotCompared, expected) {
+		t.Errorf("expected not compared %+v, got %+v", expected, result.NotCompared)
+	}
+}
+
+func TestResourceService(t *testing.T) {
+	tests := map[string]string{
+		"ec2.instance":          "ec2",
+		"ec2.volume":            "ebs",
+		"ec2.subnet":            "subnet",
+		"ec2.launchtemplate":    "launchtemplate",
+		"elb.v2":                "elb",
+		"autoscaling.group":     "autoscaling",
+		"organizations.account": "organizations",
+	}
+	for resourceType, expected := range tests {
+		if service := resourceService(resourceType); service != expected {
+			t.Errorf("expected service %s for %s, got %s", expected, resourceType, service)
+		}
+	}
+}
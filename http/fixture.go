@@ -0,0 +1,236 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Interaction is a request and its response, as stored in fixture files
+type Interaction struct {
+	Request  RecordedRequest
+	Response RecordedResponse
+}
+
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Body   string `json:",omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int
+	Header     http.Header `json:",omitempty"`
+	Body       string
+}
+
+// Recorder wraps an HTTP client and records every request and response going through it, so
+// they can be saved to a fixture file and replayed later with a Replayer. Request headers are
+// never recorded and secrets are scrubbed from urls and response bodies, so fixtures do not
+// hold credentials
+type Recorder struct {
+	config.HTTPClient
+	lock         sync.Mutex
+	interactions []Interaction
+}
+
+func NewRecorder(client config.HTTPClient) *Recorder {
+	return &Recorder{HTTPClient: client}
+}
+
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Body:   string(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       scrubBody(string(respBody)),
+		},
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.interactions = append(r.interactions, interaction)
+	return resp, nil
+}
+
+// Save writes all interactions recorded so far to a fixture file
+func (r *Recorder) Save(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Replayer is an HTTP client that answers requests with recorded responses instead of sending
+// them. Requests are matched by method, url and body, regardless of headers. When the same
+// request was recorded multiple times, responses are replayed in the order they were recorded
+// and the last one is repeated once they run out
+type Replayer struct {
+	lock      sync.Mutex
+	responses map[string][]RecordedResponse
+	replayed  map[string]int
+}
+
+func NewReplayer(interactions []Interaction) *Replayer {
+	replayer := Replayer{
+		responses: map[string][]RecordedResponse{},
+		replayed:  map[string]int{},
+	}
+	for _, interaction := range interactions {
+		key := requestKey(interaction.Request.Method, interaction.Request.URL, interaction.Request.Body)
+		replayer.responses[key] = append(replayer.responses[key], interaction.Response)
+	}
+	return &replayer
+}
+
+// LoadReplayer creates a Replayer from a fixture file saved by a Recorder
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file %s: %w", path, err)
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture file %s: %w", path, err)
+	}
+	return NewReplayer(interactions), nil
+}
+
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	key := requestKey(req.Method, scrubURL(req.URL), string(body))
+
+	r.lock.Lock()
+	responses := r.responses[key]
+	idx := r.replayed[key]
+	r.replayed[key]++
+	r.lock.Unlock()
+
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded response for request %s", key)
+	}
+	if idx >= len(responses) {
+		idx = len(responses) - 1
+	}
+	recorded := responses[idx]
+
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads the whole body and replaces it with a copy, so it can still be read by others
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// requestKey identifies requests. Form encoded bodies, used by query protocol APIs like EC2 and
+// IAM, are normalized so the ordering of their parameters does not matter
+func requestKey(method string, url string, body string) string {
+	body = strings.TrimSpace(body)
+	if strings.HasPrefix(body, "Action=") {
+		if values, err := parseQuery(body); err == nil {
+			body = values
+		}
+	}
+	if body == "" {
+		return method + " " + url
+	}
+	return method + " " + url + " " + body
+}
+
+func parseQuery(query string) (string, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+	return values.Encode(), nil
+}
+
+// scrubURL removes signing parameters of presigned urls, which hold credentials and change on
+// every request
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "x-amz-") {
+			query.Del(key)
+		}
+	}
+	scrubbed.RawQuery = query.Encode()
+	return scrubbed.String()
+}
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(<(SecretAccessKey|SessionToken)>)[^<]*(</(SecretAccessKey|SessionToken)>)`),
+	regexp.MustCompile(`("(SecretAccessKey|SessionToken|secretAccessKey|sessionToken)"\s*:\s*")[^"]*(")`),
+}
+
+// scrubBody redacts credentials returned by APIs like sts AssumeRole
+func scrubBody(body string) string {
+	for _, pattern := range secretPatterns {
+		body = pattern.ReplaceAllString(body, "${1}REDACTED${3}")
+	}
+	return body
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("<Credentials><SecretAccessKey>secret</SecretAccessKey></Credentials>" + string(body)))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(server.Client())
+	req, _ := http.NewRequest("POST", server.URL+"/?X-Amz-Signature=abc", strings.NewReader("Action=Test&B=2&A=1"))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=secret")
+	if _, err := recorder.Do(req); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// parameters in a different order still match the recorded request
+	req, _ = http.NewRequest("POST", server.URL+"/?X-Amz-Signature=def", strings.NewReader("Action=Test&A=1&B=2"))
+	resp, err := replayer.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	expected := "<Credentials><SecretAccessKey>REDACTED</SecretAccessKey></Credentials>Action=Test&B=2&A=1"
+	if string(body) != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		t.Errorf("expected cookies to not be recorded")
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+"/other", nil)
+	if _, err := replayer.Do(req); err == nil {
+		t.Errorf("expected an error for a request that was not recorded")
+	}
+}
@@ -0,0 +1,142 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	awst "awstool/aws"
+	"awstool/aws/organizations"
+	"awstool/common"
+	"awstool/executor"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// LoadAccounts loads data from every active account of the organization the given config
+// belongs to. For each account the role with the given name is assumed and then all regions and
+// services are loaded with that role, in the same way LoadAWS does. Accounts are loaded a few at a
+// time, see WithAccountParallelism. Failing to load one account does not fail the others: errors
+// are kept in the result of each account instead
+func LoadAccounts(ctx context.Context, cfg aws.Config, roleName string, options ...Option) (*awst.Accounts, error) {
+	opts := newOptions(options)
+	result := awst.NewAccounts()
+
+	org, err := organizations.FetchOrganization(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching organization: %w", err)
+	}
+	result.Organization = org
+
+	accounts, err := organizations.FetchAllAccounts(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching all accounts: %w", err)
+	}
+
+	// organization data is loaded once above. Member accounts are usually not allowed to
+	// describe the organization, so we skip it when loading each account
+	accountOptions := append([]Option{}, options...)
+	accountOptions = append(accountOptions, WithoutServices("organizations"))
+
+	var lock sync.Mutex
+	executor := executor.NewExecutor(opts.accountParallelism)
+	for _, account := range accounts {
+		if !shouldLoadAccount(account, opts) {
+			log.Debugf("Skipping account %s (%s)", aws.ToString(account.Id), aws.ToString(account.Name))
+			continue
+		}
+		accountRef := account
+		loadOptions := accountOptions
+		if opts.recordHandler != nil {
+			loadOptions = append(append([]Option{}, accountOptions...), withAccountRecords(accountRef, opts.recordHandler))
+		}
+		executor.Launch(ctx, func() {
+			accountResult := loadAccount(ctx, cfg, accountRef, roleName, loadOptions...)
+			lock.Lock()
+			defer lock.Unlock()
+			result.Accounts[aws.ToString(accountRef.Id)] = accountResult
+		})
+	}
+
+	if err := executor.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	failed := 0
+	for _, account := range result.Accounts {
+		if len(account.Errors) > 0 {
+			failed++
+		}
+	}
+	log.Infof("Loaded %d accounts (%d with errors)", len(result.Accounts), failed)
+
+	return &result, nil
+}
+
+func loadAccount(ctx context.Context, cfg aws.Config, account orgTypes.Account, roleName string, options ...Option) *awst.Account {
+	accountId := aws.ToString(account.Id)
+	result := awst.Account{Account: account}
+
+	roleArn := fmt.Sprintf("arn:%s:iam::%s:role/%s", partition(account), accountId, roleName)
+	log.Infof("Loading account %s (%s) with role %s", accountId, aws.ToString(account.Name), roleArn)
+
+	data, err := LoadAWS(ctx, awst.AssumeRoleConfig(cfg, roleArn), options...)
+	if err != nil {
+		log.Warnf("Failed to load account %s (%s): %v", accountId, aws.ToString(account.Name), err)
+		var errs common.Errors
+		if errors.As(err, &errs) {
+			for _, e := range errs.Errors {
+				result.Errors = append(result.Errors, e.Error())
+			}
+		} else {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		return &result
+	}
+	result.AWS = data
+	return &result
+}
+
+// withAccountRecords makes records streamed while loading an account carry the account id
+func withAccountRecords(account orgTypes.Account, handler RecordHandler) Option {
+	accountId := aws.ToString(account.Id)
+	return WithRecordHandler(func(records []Record) {
+		for idx := range records {
+			records[idx].Account = accountId
+		}
+		handler(records)
+	})
+}
+
+func shouldLoadAccount(account orgTypes.Account, options options) bool {
+	if account.Status != orgTypes.AccountStatusActive {
+		return false
+	}
+	id := aws.ToString(account.Id)
+	name := strings.ToLower(aws.ToString(account.Name))
+	_, idExcluded := options.excludeAccounts[id]
+	_, nameExcluded := options.excludeAccounts[name]
+	if idExcluded || nameExcluded {
+		return false
+	}
+	// if no explicit inclusions were done then we want all accounts
+	if len(options.includeAccounts) == 0 {
+		return true
+	}
+	_, idIncluded := options.includeAccounts[id]
+	_, nameIncluded := options.includeAccounts[name]
+	return idIncluded || nameIncluded
+}
+
+// partition extracts the partition (eg aws, aws-cn, aws-us-gov) from the account arn
+func partition(account orgTypes.Account) string {
+	parts := strings.SplitN(aws.ToString(account.Arn), ":", 3)
+	if len(parts) < 3 || parts[1] == "" {
+		return "aws"
+	}
+	return parts[1]
+}
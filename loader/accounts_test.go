@@ -0,0 +1,64 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+func TestShouldLoadAccount(t *testing.T) {
+	active := orgTypes.Account{
+		Id:     aws.String("111111111111"),
+		Name:   aws.String("Production"),
+		Status: orgTypes.AccountStatusActive,
+	}
+	suspended := orgTypes.Account{
+		Id:     aws.String("222222222222"),
+		Name:   aws.String("Old"),
+		Status: orgTypes.AccountStatusSuspended,
+	}
+
+	tests := []struct {
+		name     string
+		account  orgTypes.Account
+		options  []Option
+		expected bool
+	}{
+		{"active", active, nil, true},
+		{"suspended", suspended, nil, false},
+		{"suspended even if included", suspended, []Option{WithAccounts("222222222222")}, false},
+		{"included by id", active, []Option{WithAccounts("111111111111")}, true},
+		{"included by name ignoring case", active, []Option{WithAccounts("PRODUCTION")}, true},
+		{"not included", active, []Option{WithAccounts("333333333333")}, false},
+		{"excluded by id", active, []Option{WithoutAccounts("111111111111")}, false},
+		{"excluded by name", active, []Option{WithoutAccounts("production")}, false},
+		{"exclusion wins", active, []Option{WithAccounts("production"), WithoutAccounts("111111111111")}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := shouldLoadAccount(test.account, newOptions(test.options)); result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		arn      *string
+		expected string
+	}{
+		{aws.String("arn:aws:organizations::111111111111:account/o-1/111111111111"), "aws"},
+		{aws.String("arn:aws-cn:organizations::111111111111:account/o-1/111111111111"), "aws-cn"},
+		{aws.String("arn:aws-us-gov:organizations::111111111111:account/o-1/111111111111"), "aws-us-gov"},
+		{aws.String("arn::organizations"), "aws"},
+		{aws.String("invalid"), "aws"},
+		{nil, "aws"},
+	}
+	for _, test := range tests {
+		if result := partition(orgTypes.Account{Arn: test.arn}); result != test.expected {
+			t.Errorf("expected partition %q for %q, got %q", test.expected, aws.ToString(test.arn), result)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	awst "awstool/aws"
+	"awstool/aws/ec2"
+	"awstool/aws/elasticsearch"
+	"awstool/aws/rds"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LoadDumpFile is the same as LoadDump, but reads the dump from the given file. Passing "-"
+// reads the dump from stdin
+func LoadDumpFile(path string, options ...Option) (*awst.AWS, error) {
+	if path == "-" {
+		return LoadDump(os.Stdin, options...)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump file %s: %w", path, err)
+	}
+	defer file.Close()
+	return LoadDump(file, options...)
+}
+
+// LoadDump reads a json document previously generated by the dump command instead of fetching
+// data from the AWS APIs. Region options and ec2/elasticsearch/rds fetch options are applied to the
+// loaded data in the same way LoadAWS applies them when fetching. Services are not filtered, as
+// the data for all dumped services is already at hand
+func LoadDump(r io.Reader, options ...Option) (*awst.AWS, error) {
+	opts := newOptions(options)
+
+	log.Debug("Loading data from dump")
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump: %w", err)
+	}
+	if isMultiAccountDump(data) {
+		return nil, fmt.Errorf("dump holds multiple accounts, as generated by dump --assume-role, " +
+			"which is not supported: dump a single account instead")
+	}
+	result := awst.New()
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode dump: %w", err)
+	}
+
+	for name, region := range result.Regions {
+		if !shouldLoadRegion(name, opts) {
+			delete(result.Regions, name)
+			continue
+		}
+
+		if len(opts.ec2FetchOptions) > 0 {
+			reservations, err := ec2.FilterInstances(region.EC2.Reservations, opts.ec2FetchOptions...)
+			if err != nil {
+				return nil, err
+			}
+			region.EC2.Reservations = reservations
+		}
+
+		if len(opts.esFetchOptions) > 0 {
+			domainNames := make([]string, 0, len(region.Elasticsearch.Domains))
+			for domainName := range region.Elasticsearch.Domains {
+				domainNames = append(domainNames, domainName)
+			}
+			domains := map[string]*awst.ElasticsearchDomain{}
+			for _, domainName := range elasticsearch.FilterDomainNames(domainNames, opts.esFetchOptions...) {
+				domains[domainName] = region.Elasticsearch.Domains[domainName]
+			}
+			region.Elasticsearch.Domains = domains
+		}
+
+		if len(opts.rdsFetchOptions) > 0 {
+			region.RDS.Instances = rds.FilterInstances(region.RDS.Instances, opts.rdsFetchOptions...)
+			region.RDS.Clusters = rds.FilterClusters(region.RDS.Clusters, opts.rdsFetchOptions...)
+		}
+
+		result.Regions[name] = region
+	}
+
+	log.Infof("Loaded data for %d regions from dump", len(result.Regions))
+	return &result, nil
+}
+
+// isMultiAccountDump tells whether the dump has the awst.Accounts shape, where regions are nested
+// in each account instead of being at the top level. Decoding it as awst.AWS would silently yield
+// no regions at all
+func isMultiAccountDump(data []byte) bool {
+	var probe struct {
+		Regions  json.RawMessage
+		Accounts map[string]struct {
+			AWS json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.Regions != nil {
+		return false
+	}
+	for _, account := range probe.Accounts {
+		if account.AWS != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func shouldLoadRegion(region string, options options) bool {
+	if _, excluded := options.excludeRegions[region]; excluded {
+		return false
+	}
+	if len(options.includeRegions) == 0 {
+		return true
+	}
+	_, included := options.includeRegions[region]
+	return included
+}
@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"awstool/aws/ec2"
+)
+
+const singleAccountDump = `{
+	"Accounts": {"main": {"Id": "111111111111", "Name": "main"}},
+	"Regions": {
+		"us-east-1": {"Region": "us-east-1", "EC2": {"Reservations": [{"Instances": [
+			{"InstanceId": "i-1", "Tags": [{"Key": "Env", "Value": "prod"}]},
+			{"InstanceId": "i-2", "Tags": [{"Key": "Env", "Value": "dev"}]}
+		]}]}},
+		"eu-west-1": {"Region": "eu-west-1", "EC2": {"Reservations": [{"Instances": [{"InstanceId": "i-3"}]}]}}
+	}
+}`
+
+func TestLoadDump(t *testing.T) {
+	result, err := LoadDump(strings.NewReader(singleAccountDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions := []string{}
+	for name := range result.Regions {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions)
+	if !reflect.DeepEqual(regions, []string{"eu-west-1", "us-east-1"}) {
+		t.Fatalf("expected regions eu-west-1 and us-east-1, got %v", regions)
+	}
+	if ids := instanceIds(result.Regions["us-east-1"]); !reflect.DeepEqual(ids, []string{"i-1", "i-2"}) {
+		t.Errorf("expected all us-east-1 instances, got %v", ids)
+	}
+	if result.Accounts["main"].Name == nil {
+		t.Error("expected organization accounts to be loaded")
+	}
+}
+
+func TestLoadDumpOptions(t *testing.T) {
+	result, err := LoadDump(
+		strings.NewReader(singleAccountDump),
+		WithoutRegions("eu-west-1"),
+		WithEC2FetchOptions(ec2.WithTag("Env", "prod")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Regions["eu-west-1"]; ok || len(result.Regions) != 1 {
+		t.Fatalf("expected only us-east-1 to be loaded, got %v", result.Regions)
+	}
+	if ids := instanceIds(result.Regions["us-east-1"]); !reflect.DeepEqual(ids, []string{"i-1"}) {
+		t.Errorf("expected instances to be filtered, got %v", ids)
+	}
+
+	_, err = LoadDump(strings.NewReader(singleAccountDump), WithEC2FetchOptions(ec2.WithFilter("unknown", "x")))
+	if err == nil {
+		t.Error("expected an error for an ec2 filter that can't be applied locally")
+	}
+}
+
+func TestLoadDumpMultipleAccounts(t *testing.T) {
+	dump := `{"Accounts": {"111111111111": {
+		"Account": {"Id": "111111111111"},
+		"AWS": {"Regions": {"us-east-1": {"Region": "us-east-1"}}},
+		"Errors": null
+	}}}`
+	_, err := LoadDump(strings.NewReader(dump))
+	if err == nil || !strings.Contains(err.Error(), "multiple accounts") {
+		t.Errorf("expected a multiple accounts error, got %v", err)
+	}
+
+	// accounts that failed to load have no data, but are still part of a multi account dump
+	dump = `{"Accounts": {"111111111111": {"Account": {"Id": "111111111111"}, "AWS": null, "Errors": ["denied"]}}}`
+	if _, err := LoadDump(strings.NewReader(dump)); err == nil {
+		t.Error("expected an error for a multi account dump with failed accounts")
+	}
+}
+
+func TestLoadDumpInvalid(t *testing.T) {
+	if _, err := LoadDump(strings.NewReader("{")); err == nil {
+		t.Error("expected an error for an invalid dump")
+	}
+}
@@ -0,0 +1,131 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	awst "awstool/aws"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Error classes reported in awst.Failure.ErrorClass
+const (
+	ErrorClassAccessDenied  = "AccessDenied"
+	ErrorClassThrottled     = "Throttled"
+	ErrorClassOptInRequired = "OptInRequired"
+	ErrorClassNotFound      = "NotFound"
+	ErrorClassConnection    = "Connection"
+	ErrorClassCanceled      = "Canceled"
+	ErrorClassOther         = "Other"
+)
+
+var errorCodeClasses = map[string]string{
+	"AccessDenied":                  ErrorClassAccessDenied,
+	"AccessDeniedException":         ErrorClassAccessDenied,
+	"UnauthorizedOperation":         ErrorClassAccessDenied,
+	"UnauthorizedException":         ErrorClassAccessDenied,
+	"AuthorizationError":            ErrorClassAccessDenied,
+	"AuthFailure":                   ErrorClassAccessDenied,
+	"Throttling":                    ErrorClassThrottled,
+	"ThrottlingException":           ErrorClassThrottled,
+	"ThrottledException":            ErrorClassThrottled,
+	"RequestLimitExceeded":          ErrorClassThrottled,
+	"RequestThrottled":              ErrorClassThrottled,
+	"RequestThrottledException":     ErrorClassThrottled,
+	"TooManyRequestsException":      ErrorClassThrottled,
+	"SlowDown":                      ErrorClassThrottled,
+	"OptInRequired":                 ErrorClassOptInRequired,
+	"SubscriptionRequiredException": ErrorClassOptInRequired,
+	"ResourceNotFoundException":     ErrorClassNotFound,
+	"NoSuchEntity":                  ErrorClassNotFound,
+	"NotFoundException":             ErrorClassNotFound,
+}
+
+// FetchError is an error that happened while fetching data for a given service and region.
+// Region is empty for global services
+type FetchError struct {
+	Service string
+	Region  string
+	Err     error
+}
+
+func (e *FetchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Operation returns the name of the AWS API operation that failed, if known
+func (e *FetchError) Operation() string {
+	var opErr *smithy.OperationError
+	if errors.As(e.Err, &opErr) {
+		return opErr.Operation()
+	}
+	return ""
+}
+
+// ErrorCode returns the error code returned by the AWS API, if any
+func (e *FetchError) ErrorCode() string {
+	var apiErr smithy.APIError
+	if errors.As(e.Err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// ErrorClass groups errors into broad classes (see the ErrorClass* constants), so callers can
+// tell, for instance, missing permissions apart from throttling
+func (e *FetchError) ErrorClass() string {
+	if errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded) {
+		return ErrorClassCanceled
+	}
+	if class, ok := errorCodeClasses[e.ErrorCode()]; ok {
+		return class
+	}
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(e.Err, &sendErr) {
+		return ErrorClassConnection
+	}
+	return ErrorClassOther
+}
+
+// errorReporter is used by fetch functions to report errors. Errors get wrapped in a *FetchError
+// identifying the service and region being fetched
+type errorReporter = func(err error)
+
+func newErrorReporter(service string, region string, errorsCh chan<- error) errorReporter {
+	return func(err error) {
+		errorsCh <- &FetchError{Service: service, Region: region, Err: err}
+	}
+}
+
+func toFailures(errs []error) []awst.Failure {
+	failures := make([]awst.Failure, len(errs))
+	for idx, err := range errs {
+		failure := awst.Failure{
+			ErrorClass: ErrorClassOther,
+			Message:    err.Error(),
+		}
+		var fetchErr *FetchError
+		if errors.As(err, &fetchErr) {
+			failure.Service = fetchErr.Service
+			failure.Region = fetchErr.Region
+			failure.Operation = fetchErr.Operation()
+			failure.ErrorCode = fetchErr.ErrorCode()
+			failure.ErrorClass = fetchErr.ErrorClass()
+		}
+		failures[idx] = failure
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		if failures[i].Region != failures[j].Region {
+			return failures[i].Region < failures[j].Region
+		}
+		return failures[i].Service < failures[j].Service
+	})
+	return failures
+}
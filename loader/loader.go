@@ -8,23 +8,31 @@ import (
 	"sync"
 
 	awst "awstool/aws"
+	"awstool/aws/autoscaling"
 	"awstool/aws/ec2"
+	"awstool/aws/ecs"
+	"awstool/aws/eks"
 	"awstool/aws/elasticbeanstalk"
 	"awstool/aws/elasticsearch"
 	"awstool/aws/elb"
 	"awstool/aws/iam"
+	"awstool/aws/lambda"
 	"awstool/aws/opsworks"
 	"awstool/aws/organizations"
+	"awstool/aws/rds"
 	"awstool/aws/region"
 	"awstool/aws/s3"
 	"awstool/common"
 	"awstool/executor"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	log "github.com/sirupsen/logrus"
 )
 
-type globalServiceFetchFunc = func(context.Context, aws.Config, *executor.Executor, chan<- error, *awst.AWS, options)
-type regionalServiceFetchFunc = func(context.Context, aws.Config, *executor.Executor, chan<- error, *awst.Region, options)
+type globalServiceFetchFunc = func(context.Context, aws.Config, *executor.Executor, errorReporter, *awst.AWS, options)
+type regionalServiceFetchFunc = func(context.Context, aws.Config, *executor.Executor, errorReporter, *awst.Region, options)
 
 func globalServicesFetchFunctions() map[string]globalServiceFetchFunc {
 	return map[string]globalServiceFetchFunc{
@@ -37,11 +45,28 @@ func regionalServicesFetchFunctions() map[string]regionalServiceFetchFunc {
 	return map[string]regionalServiceFetchFunc{
 		"ec2":              fetchEC2,
 		"ebs":              fetchEBS,
+		"vpc":              fetchVPCs,
+		"subnet":           fetchSubnets,
+		"routetable":       fetchRouteTables,
+		"securitygroup":    fetchSecurityGroups,
+		"networkacl":       fetchNetworkACLs,
+		"natgateway":       fetchNATGateways,
+		"internetgateway":  fetchInternetGateways,
+		"vpcpeering":       fetchVPCPeeringConnections,
+		"vpcendpoint":      fetchVPCEndpoints,
+		"eni":              fetchNetworkInterfaces,
+		"eip":              fetchAddresses,
+		"launchtemplate":   fetchLaunchTemplates,
+		"autoscaling":      fetchAutoScaling,
 		"elb":              fetchELBs,
 		"s3":               fetchS3,
 		"opsworks":         fetchOpsworks,
 		"elasticbeanstalk": fetchElasticBeanstalk,
 		"elasticsearch":    fetchElasticsearch,
+		"rds":              fetchRDS,
+		"lambda":           fetchLambda,
+		"ecs":              fetchECS,
+		"eks":              fetchEKS,
 	}
 }
 
@@ -86,6 +111,9 @@ func GetRegions(ctx context.Context, cfg aws.Config, options ...Option) ([]strin
 	return result, nil
 }
 
+// LoadAWS loads data for all selected regions and services. By default any failure fails the
+// whole load. When WithPartialResults is passed, failures are instead reported in the Failures
+// field of the result, alongside all data that could be loaded
 func LoadAWS(ctx context.Context, cfg aws.Config, options ...Option) (*awst.AWS, error) {
 	opts := newOptions(options)
 	result := awst.New()
@@ -97,6 +125,8 @@ func LoadAWS(ctx context.Context, cfg aws.Config, options ...Option) (*awst.AWS,
 		return nil, err
 	}
 
+	result.Services = selectedServices(opts)
+
 	executor := executor.NewExecutor(0)
 	for _, region := range regions {
 		regionRef := region
@@ -104,6 +134,12 @@ func LoadAWS(ctx context.Context, cfg aws.Config, options ...Option) (*awst.AWS,
 			regionDump, err := LoadRegion(ctx, cfg, regionRef, options...)
 			if err != nil {
 				errorsCh <- err
+				if !opts.partialResults {
+					return
+				}
+			}
+			if opts.recordHandler != nil {
+				// data was already streamed
 				return
 			}
 			resultLock.Lock()
@@ -113,28 +149,35 @@ func LoadAWS(ctx context.Context, cfg aws.Config, options ...Option) (*awst.AWS,
 	}
 
 	for svc, fn := range globalServicesFetchFunctions() {
-		if shouldFetchService(svc, opts) {
-			fn(ctx, cfg, executor, errorsCh, &result, opts)
+		if !shouldFetchService(svc, opts) {
+			continue
+		}
+		reportError := newErrorReporter(svc, "", errorsCh)
+		if opts.recordHandler == nil {
+			fn(ctx, cfg, executor, reportError, &result, opts)
+			continue
 		}
+		svcRef, fnRef := svc, fn
+		executor.Launch(ctx, func() {
+			streamGlobalService(ctx, cfg, svcRef, fnRef, reportError, opts)
+		})
 	}
 
-	errors := make([]error, 0)
-	consume := true
-	for consume {
-		select {
-		case <-executor.Done():
-			consume = false
-		case err := <-errorsCh:
-			errors = append(errors, err)
-		}
+	errors := consumeErrors(executor, errorsCh)
+	if len(errors) == 0 {
+		return &result, nil
 	}
-	if len(errors) > 0 {
+	if !opts.partialResults {
 		return nil, common.NewErrors(errors)
 	}
-
-	return &result, err
+	result.Failures = toFailures(errors)
+	log.Warnf("Loaded data with %d failures", len(result.Failures))
+	return &result, nil
 }
 
+// LoadRegion loads data for all selected services of a single region. If any service fails, the
+// returned error is a common.Errors holding one *FetchError per failure, and the returned region
+// holds whatever data could be loaded
 func LoadRegion(ctx context.Context, cfg aws.Config, region string, options ...Option) (awst.Region, error) {
 	cfg.Region = region
 
@@ -143,22 +186,21 @@ func LoadRegion(ctx context.Context, cfg aws.Config, region string, options ...O
 	errorsCh := make(chan error)
 	executor := executor.NewExecutor(0)
 	for svc, fn := range regionalServicesFetchFunctions() {
-		if shouldFetchService(svc, opts) {
-			fn(ctx, cfg, executor, errorsCh, &result, opts)
+		if !shouldFetchService(svc, opts) {
+			continue
 		}
-	}
-
-	errors := make([]error, 0)
-	consume := true
-	for consume {
-		select {
-		case <-executor.Done():
-			consume = false
-		case err := <-errorsCh:
-			errors = append(errors, err)
+		reportError := newErrorReporter(svc, region, errorsCh)
+		if opts.recordHandler == nil {
+			fn(ctx, cfg, executor, reportError, &result, opts)
+			continue
 		}
+		svcRef, fnRef := svc, fn
+		executor.Launch(ctx, func() {
+			streamRegionalService(ctx, cfg, svcRef, fnRef, reportError, opts)
+		})
 	}
 
+	errors := consumeErrors(executor, errorsCh)
 	if len(errors) > 0 {
 		return result, common.NewErrors(errors)
 	}
@@ -166,11 +208,29 @@ func LoadRegion(ctx context.Context, cfg aws.Config, region string, options ...O
 	return result, nil
 }
 
-func fetchOrganization(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.AWS, options options) {
+// consumeErrors collects all errors sent while the executor runs, flattening common.Errors
+func consumeErrors(executor *executor.Executor, errorsCh <-chan error) []error {
+	errors := make([]error, 0)
+	done := executor.Done()
+	for {
+		select {
+		case <-done:
+			return errors
+		case err := <-errorsCh:
+			if errs, ok := err.(common.Errors); ok {
+				errors = append(errors, errs.Errors...)
+			} else {
+				errors = append(errors, err)
+			}
+		}
+	}
+}
+
+func fetchOrganization(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.AWS, options options) {
 	executor.Launch(ctx, func() {
 		org, err := organizations.FetchOrganization(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching organization: %w", err)
+			reportError(fmt.Errorf("error while fetching organization: %w", err))
 		}
 		result.Organization = org
 	})
@@ -178,7 +238,7 @@ func fetchOrganization(ctx context.Context, cfg aws.Config, executor *executor.E
 	executor.Launch(ctx, func() {
 		accounts, err := organizations.FetchAllAccounts(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all accounts: %w", err)
+			reportError(fmt.Errorf("error while fetching all accounts: %w", err))
 		}
 		for _, account := range accounts {
 			result.Accounts[*account.Name] = account
@@ -186,11 +246,11 @@ func fetchOrganization(ctx context.Context, cfg aws.Config, executor *executor.E
 	})
 }
 
-func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.AWS, options options) {
+func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.AWS, options options) {
 	usersDoneCh := executor.Launch(ctx, func() {
 		users, err := iam.FetchAllUsers(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all IAM users: %w", err)
+			reportError(fmt.Errorf("error while fetching all IAM users: %w", err))
 		}
 		result.IAM.Users = users
 	})
@@ -198,7 +258,7 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 	executor.Launch(ctx, func() {
 		roles, err := iam.FetchAllRoles(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all IAM roles: %w", err)
+			reportError(fmt.Errorf("error while fetching all IAM roles: %w", err))
 		}
 		result.IAM.Roles = roles
 	})
@@ -206,7 +266,7 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 	executor.Launch(ctx, func() {
 		groups, err := iam.FetchAllGroups(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all IAM groups: %w", err)
+			reportError(fmt.Errorf("error while fetching all IAM groups: %w", err))
 		}
 		result.IAM.Groups = groups
 	})
@@ -214,7 +274,7 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 	executor.Launch(ctx, func() {
 		policies, err := iam.FetchAllPolicies(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all IAM policies: %w", err)
+			reportError(fmt.Errorf("error while fetching all IAM policies: %w", err))
 		}
 		result.IAM.Policies = policies
 	})
@@ -227,7 +287,7 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 			executor.Launch(ctx, func() {
 				accessKeys, err := iam.FetchAllAccessKeys(ctx, cfg, username)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching all IAM access keys for %s: %w", username, err)
+					reportError(fmt.Errorf("error while fetching all IAM access keys for %s: %w", username, err))
 				}
 				lock.Lock()
 				result.IAM.AccessKeys[username] = accessKeys
@@ -244,7 +304,7 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 			executor.Launch(ctx, func() {
 				groups, err := iam.FetchAllUserGroups(ctx, cfg, username)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching all IAM user groups for %s: %w", username, err)
+					reportError(fmt.Errorf("error while fetching all IAM user groups for %s: %w", username, err))
 				}
 				lock.Lock()
 				result.IAM.UserGroups[username] = groups
@@ -254,31 +314,218 @@ func fetchIAM(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 	})
 }
 
-func fetchEC2(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchEC2(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	executor.Launch(ctx, func() {
 		reservations, err := ec2.FetchAllInstances(ctx, cfg, options.ec2FetchOptions...)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all EC2 instances: %w", err)
+			reportError(fmt.Errorf("error while fetching all EC2 instances: %w", err))
 		}
 		result.EC2.Reservations = reservations
 	})
 }
 
-func fetchEBS(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchEBS(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	executor.Launch(ctx, func() {
 		volumes, err := ec2.FetchAllEBSVolumes(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all EBS volumes: %w", err)
+			reportError(fmt.Errorf("error while fetching all EBS volumes: %w", err))
 		}
 		result.EC2.Volumes = volumes
 	})
 }
 
-func fetchS3(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchVPCs(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		vpcs, err := ec2.FetchAllVpcs(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all VPCs: %w", err))
+		}
+		result.EC2.Vpcs = vpcs
+	})
+}
+
+func fetchSubnets(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		subnets, err := ec2.FetchAllSubnets(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all subnets: %w", err))
+		}
+		result.EC2.Subnets = subnets
+	})
+}
+
+func fetchRouteTables(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		routeTables, err := ec2.FetchAllRouteTables(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all route tables: %w", err))
+		}
+		result.EC2.RouteTables = routeTables
+	})
+}
+
+func fetchSecurityGroups(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		securityGroups, err := ec2.FetchAllSecurityGroups(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all security groups: %w", err))
+		}
+		result.EC2.SecurityGroups = securityGroups
+	})
+}
+
+func fetchNetworkACLs(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		networkAcls, err := ec2.FetchAllNetworkAcls(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all network ACLs: %w", err))
+		}
+		result.EC2.NetworkAcls = networkAcls
+	})
+}
+
+func fetchNATGateways(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		natGateways, err := ec2.FetchAllNatGateways(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all NAT gateways: %w", err))
+		}
+		result.EC2.NatGateways = natGateways
+	})
+}
+
+func fetchInternetGateways(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		internetGateways, err := ec2.FetchAllInternetGateways(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all internet gateways: %w", err))
+		}
+		result.EC2.InternetGateways = internetGateways
+	})
+}
+
+func fetchVPCPeeringConnections(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		vpcPeeringConnections, err := ec2.FetchAllVpcPeeringConnections(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all VPC peering connections: %w", err))
+		}
+		result.EC2.VpcPeeringConnections = vpcPeeringConnections
+	})
+}
+
+func fetchVPCEndpoints(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		vpcEndpoints, err := ec2.FetchAllVpcEndpoints(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all VPC endpoints: %w", err))
+		}
+		result.EC2.VpcEndpoints = vpcEndpoints
+	})
+}
+
+func fetchNetworkInterfaces(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		networkInterfaces, err := ec2.FetchAllNetworkInterfaces(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all network interfaces: %w", err))
+		}
+		result.EC2.NetworkInterfaces = networkInterfaces
+	})
+}
+
+func fetchAddresses(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		addresses, err := ec2.FetchAllAddresses(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Elastic IPs: %w", err))
+		}
+		result.EC2.Addresses = addresses
+	})
+}
+
+func fetchLaunchTemplates(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	var templates []ec2Types.LaunchTemplate
+	var versions []ec2Types.LaunchTemplateVersion
+
+	templatesDoneCh := executor.Launch(ctx, func() {
+		var err error
+		templates, err = ec2.FetchAllLaunchTemplates(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all launch templates: %w", err))
+		}
+	})
+
+	versionsDoneCh := executor.Launch(ctx, func() {
+		var err error
+		versions, err = ec2.FetchAllDefaultAndLatestLaunchTemplateVersions(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all launch template versions: %w", err))
+		}
+	})
+
+	executor.Launch(ctx, func() {
+		<-templatesDoneCh
+		<-versionsDoneCh
+		launchTemplates := make([]awst.LaunchTemplate, len(templates))
+		for idx, template := range templates {
+			launchTemplates[idx].Template = template
+			for vIdx := range versions {
+				version := &versions[vIdx]
+				if aws.ToString(version.LaunchTemplateId) != aws.ToString(template.LaunchTemplateId) {
+					continue
+				}
+				if aws.ToInt64(version.VersionNumber) == aws.ToInt64(template.DefaultVersionNumber) {
+					launchTemplates[idx].DefaultVersion = version
+				}
+				if aws.ToInt64(version.VersionNumber) == aws.ToInt64(template.LatestVersionNumber) {
+					launchTemplates[idx].LatestVersion = version
+				}
+			}
+		}
+		result.EC2.LaunchTemplates = launchTemplates
+	})
+}
+
+func fetchAutoScaling(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		groups, err := autoscaling.FetchAllGroups(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Auto Scaling groups: %w", err))
+		}
+		result.AutoScaling.Groups = groups
+	})
+
+	executor.Launch(ctx, func() {
+		configurations, err := autoscaling.FetchAllLaunchConfigurations(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all launch configurations: %w", err))
+		}
+		result.AutoScaling.LaunchConfigurations = configurations
+	})
+
+	executor.Launch(ctx, func() {
+		policies, err := autoscaling.FetchAllPolicies(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Auto Scaling policies: %w", err))
+		}
+		result.AutoScaling.Policies = policies
+	})
+
+	executor.Launch(ctx, func() {
+		actions, err := autoscaling.FetchAllScheduledActions(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Auto Scaling scheduled actions: %w", err))
+		}
+		result.AutoScaling.ScheduledActions = actions
+	})
+}
+
+func fetchS3(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	bucketsDoneCh := executor.Launch(ctx, func() {
 		buckets, err := s3.FetchAllBuckets(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all EC2 instances: %w", err)
+			reportError(fmt.Errorf("error while fetching all S3 buckets: %w", err))
 		}
 		result.S3.Buckets = buckets
 	})
@@ -291,7 +538,7 @@ func fetchS3(ctx context.Context, cfg aws.Config, executor *executor.Executor, e
 		// 	executor.Launch(ctx, func() {
 		// 		tags, err := s3.FetchBucketTags(ctx, cfg, bucketName)
 		// 		if err != nil {
-		// 			errorsCh <- fmt.Errorf("error while fetching tags for S3 bucket %s: %w", bucketName, err)
+		// 			reportError(fmt.Errorf("error while fetching tags for S3 bucket %s: %w", bucketName, err))
 		// 		}
 		// 		lock.Lock()
 		// 		defer lock.Unlock()
@@ -302,11 +549,11 @@ func fetchS3(ctx context.Context, cfg aws.Config, executor *executor.Executor, e
 
 }
 
-func fetchELBs(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchELBs(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	executor.Launch(ctx, func() {
 		elbs, err := elb.FetchAllV1ELBs(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all ELBs (v1): %w", err)
+			reportError(fmt.Errorf("error while fetching all ELBs (v1): %w", err))
 		}
 		result.ELB.V1.LoadBalancers = elbs
 	})
@@ -314,17 +561,17 @@ func fetchELBs(ctx context.Context, cfg aws.Config, executor *executor.Executor,
 	executor.Launch(ctx, func() {
 		elbs, err := elb.FetchAllV2ELBs(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all ELBs (v2): %w", err)
+			reportError(fmt.Errorf("error while fetching all ELBs (v2): %w", err))
 		}
 		result.ELB.V2.LoadBalancers = elbs
 	})
 }
 
-func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	stacksDoneCh := executor.Launch(ctx, func() {
 		stacks, err := opsworks.FetchAllStacks(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all Opsworks stacks: %w", err)
+			reportError(fmt.Errorf("error while fetching all Opsworks stacks: %w", err))
 		}
 		result.Opsworks.Stacks = stacks
 	})
@@ -340,7 +587,7 @@ func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Execu
 			executor.Launch(ctx, func() {
 				layers, err := opsworks.FetchAllLayers(ctx, cfg, stackId)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching all Opsworks layers for stack %s: %w", stackId, err)
+					reportError(fmt.Errorf("error while fetching all Opsworks layers for stack %s: %w", stackId, err))
 				}
 				layersLock.Lock()
 				defer layersLock.Unlock()
@@ -350,7 +597,7 @@ func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Execu
 			executor.Launch(ctx, func() {
 				apps, err := opsworks.FetchAllApps(ctx, cfg, stackId)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching all Opsworks apps for stack %s: %w", stackId, err)
+					reportError(fmt.Errorf("error while fetching all Opsworks apps for stack %s: %w", stackId, err))
 				}
 				appsLock.Lock()
 				defer appsLock.Unlock()
@@ -360,7 +607,7 @@ func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Execu
 			executor.Launch(ctx, func() {
 				instances, err := opsworks.FetchAllInstances(ctx, cfg, stackId)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching all Opsworks instances for stack %s: %w", stackId, err)
+					reportError(fmt.Errorf("error while fetching all Opsworks instances for stack %s: %w", stackId, err))
 				}
 				instancesLock.Lock()
 				defer instancesLock.Unlock()
@@ -370,11 +617,11 @@ func fetchOpsworks(ctx context.Context, cfg aws.Config, executor *executor.Execu
 	})
 }
 
-func fetchElasticBeanstalk(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchElasticBeanstalk(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	executor.Launch(ctx, func() {
 		apps, err := elasticbeanstalk.FetchAllApplications(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all Elasticbeanstalk applications: %w", err)
+			reportError(fmt.Errorf("error while fetching all Elasticbeanstalk applications: %w", err))
 		}
 		result.ElasticBeanstalk.Applications = apps
 	})
@@ -382,17 +629,17 @@ func fetchElasticBeanstalk(ctx context.Context, cfg aws.Config, executor *execut
 	executor.Launch(ctx, func() {
 		envs, err := elasticbeanstalk.FetchAllEnvironments(ctx, cfg)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while fetching all Elasticbeanstalk environments: %w", err)
+			reportError(fmt.Errorf("error while fetching all Elasticbeanstalk environments: %w", err))
 		}
 		result.ElasticBeanstalk.Environments = envs
 	})
 }
 
-func fetchElasticsearch(ctx context.Context, cfg aws.Config, executor *executor.Executor, errorsCh chan<- error, result *awst.Region, options options) {
+func fetchElasticsearch(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
 	executor.Launch(ctx, func() {
 		domains, err := elasticsearch.ListAllDomainNames(ctx, cfg, options.esFetchOptions...)
 		if err != nil {
-			errorsCh <- fmt.Errorf("error while listing all Elasticsearch domain names: %w", err)
+			reportError(fmt.Errorf("error while listing all Elasticsearch domain names: %w", err))
 			return
 		}
 
@@ -415,14 +662,14 @@ func fetchElasticsearch(ctx context.Context, cfg aws.Config, executor *executor.
 			executor.Launch(ctx, func() {
 				status, err := elasticsearch.FetchDomainStatus(ctx, cfg, domain)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching the status for Elasticsearch domain %s: %w", domain, err)
+					reportError(fmt.Errorf("error while fetching the status for Elasticsearch domain %s: %w", domain, err))
 					return
 				}
 				domainResult(domain).Status = status
 
 				tags, err := elasticsearch.FetchDomainTags(ctx, cfg, *status.ARN)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching tags for Elasticsearch domain %s: %w", domain, err)
+					reportError(fmt.Errorf("error while fetching tags for Elasticsearch domain %s: %w", domain, err))
 					return
 				}
 				domainResult(domain).Tags = tags
@@ -431,7 +678,7 @@ func fetchElasticsearch(ctx context.Context, cfg aws.Config, executor *executor.
 			executor.Launch(ctx, func() {
 				config, err := elasticsearch.FetchDomainConfig(ctx, cfg, domain)
 				if err != nil {
-					errorsCh <- fmt.Errorf("error while fetching the config for Elasticsearch domain %s: %w", domain, err)
+					reportError(fmt.Errorf("error while fetching the config for Elasticsearch domain %s: %w", domain, err))
 					return
 				}
 				domainResult(domain).Config = config
@@ -440,6 +687,310 @@ func fetchElasticsearch(ctx context.Context, cfg aws.Config, executor *executor.
 	})
 }
 
+func fetchRDS(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		instances, err := rds.FetchAllInstances(ctx, cfg, options.rdsFetchOptions...)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB instances: %w", err))
+		}
+		result.RDS.Instances = instances
+	})
+
+	executor.Launch(ctx, func() {
+		clusters, err := rds.FetchAllClusters(ctx, cfg, options.rdsFetchOptions...)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB clusters: %w", err))
+		}
+		result.RDS.Clusters = clusters
+	})
+
+	if rds.DatabasesOnly(options.rdsFetchOptions...) {
+		return
+	}
+
+	executor.Launch(ctx, func() {
+		snapshots, err := rds.FetchAllSnapshots(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB snapshots: %w", err))
+		}
+		result.RDS.Snapshots = snapshots
+	})
+
+	executor.Launch(ctx, func() {
+		snapshots, err := rds.FetchAllClusterSnapshots(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB cluster snapshots: %w", err))
+		}
+		result.RDS.ClusterSnapshots = snapshots
+	})
+
+	var tagsLock sync.Mutex
+	fetchTags := func(arn string) {
+		executor.Launch(ctx, func() {
+			tags, err := rds.FetchTags(ctx, cfg, arn)
+			if err != nil {
+				reportError(fmt.Errorf("error while fetching tags for RDS resource %s: %w", arn, err))
+				return
+			}
+			tagsLock.Lock()
+			defer tagsLock.Unlock()
+			result.RDS.Tags[arn] = tags
+		})
+	}
+
+	executor.Launch(ctx, func() {
+		groups, err := rds.FetchAllParameterGroups(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB parameter groups: %w", err))
+		}
+		result.RDS.ParameterGroups = groups
+		for _, group := range groups {
+			fetchTags(aws.ToString(group.DBParameterGroupArn))
+		}
+	})
+
+	executor.Launch(ctx, func() {
+		groups, err := rds.FetchAllClusterParameterGroups(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB cluster parameter groups: %w", err))
+		}
+		result.RDS.ClusterParameterGroups = groups
+		for _, group := range groups {
+			fetchTags(aws.ToString(group.DBClusterParameterGroupArn))
+		}
+	})
+
+	executor.Launch(ctx, func() {
+		groups, err := rds.FetchAllSubnetGroups(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all RDS DB subnet groups: %w", err))
+		}
+		result.RDS.SubnetGroups = groups
+		for _, group := range groups {
+			fetchTags(aws.ToString(group.DBSubnetGroupArn))
+		}
+	})
+}
+
+func fetchLambda(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	functionsOnly := lambda.FunctionsOnly(options.lambdaFetchOptions...)
+
+	executor.Launch(ctx, func() {
+		functions, err := lambda.FetchAllFunctions(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Lambda functions: %w", err))
+		}
+
+		// functions are added before fanning out, so each fetch only touches its own function
+		for idx := range functions {
+			function := &awst.LambdaFunction{Configuration: &functions[idx]}
+			name := aws.ToString(function.Configuration.FunctionName)
+			result.Lambda.Functions[name] = function
+
+			executor.Launch(ctx, func() {
+				tags, err := lambda.FetchTags(ctx, cfg, aws.ToString(function.Configuration.FunctionArn))
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching tags for Lambda function %s: %w", name, err))
+					return
+				}
+				function.Tags = tags
+			})
+
+			if functionsOnly {
+				continue
+			}
+
+			executor.Launch(ctx, func() {
+				versions, err := lambda.FetchAllVersions(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching all versions for Lambda function %s: %w", name, err))
+				}
+				function.Versions = versions
+			})
+
+			executor.Launch(ctx, func() {
+				aliases, err := lambda.FetchAllAliases(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching all aliases for Lambda function %s: %w", name, err))
+				}
+				function.Aliases = aliases
+			})
+
+			executor.Launch(ctx, func() {
+				policy, err := lambda.FetchPolicy(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching the policy for Lambda function %s: %w", name, err))
+					return
+				}
+				function.Policy = policy
+			})
+		}
+	})
+
+	if functionsOnly {
+		return
+	}
+
+	executor.Launch(ctx, func() {
+		mappings, err := lambda.FetchAllEventSourceMappings(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all Lambda event source mappings: %w", err))
+		}
+		result.Lambda.EventSourceMappings = mappings
+	})
+}
+
+func fetchECS(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		clusters, err := ecs.FetchAllClusters(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while fetching all ECS clusters: %w", err))
+		}
+
+		// clusters are added before fanning out, so each fetch only touches its own cluster
+		for idx := range clusters {
+			cluster := &awst.ECSCluster{Cluster: &clusters[idx]}
+			name := aws.ToString(cluster.Cluster.ClusterName)
+			arn := aws.ToString(cluster.Cluster.ClusterArn)
+			result.ECS.Clusters[name] = cluster
+
+			executor.Launch(ctx, func() {
+				services, err := ecs.FetchAllServices(ctx, cfg, arn)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching all services for ECS cluster %s: %w", name, err))
+				}
+				cluster.Services = services
+			})
+
+			executor.Launch(ctx, func() {
+				tasks, err := ecs.FetchAllTasks(ctx, cfg, arn)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching all tasks for ECS cluster %s: %w", name, err))
+				}
+				cluster.Tasks = tasks
+			})
+
+			executor.Launch(ctx, func() {
+				instances, err := ecs.FetchAllContainerInstances(ctx, cfg, arn)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching all container instances for ECS cluster %s: %w", name, err))
+				}
+				cluster.ContainerInstances = instances
+			})
+		}
+	})
+
+	if ecs.WorkloadsOnly(options.ecsFetchOptions...) {
+		return
+	}
+
+	executor.Launch(ctx, func() {
+		families, err := ecs.ListAllTaskDefinitionFamilies(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while listing all ECS task definition families: %w", err))
+		}
+
+		var lock sync.Mutex
+		for _, f := range families {
+			family := f
+			executor.Launch(ctx, func() {
+				definition, tags, err := ecs.FetchLatestTaskDefinition(ctx, cfg, family)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching the latest ECS task definition of family %s: %w", family, err))
+					return
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				result.ECS.TaskDefinitions = append(result.ECS.TaskDefinitions, *definition)
+				if len(tags) > 0 {
+					result.ECS.TaskDefinitionTags[aws.ToString(definition.TaskDefinitionArn)] = tags
+				}
+			})
+		}
+	})
+}
+
+func fetchEKS(ctx context.Context, cfg aws.Config, executor *executor.Executor, reportError errorReporter, result *awst.Region, options options) {
+	executor.Launch(ctx, func() {
+		names, err := eks.ListAllClusterNames(ctx, cfg)
+		if err != nil {
+			reportError(fmt.Errorf("error while listing all EKS clusters: %w", err))
+		}
+
+		// clusters are added before fanning out, so each fetch only touches its own cluster.
+		// Node groups and fargate profiles are described one at a time, so they share a lock
+		var lock sync.Mutex
+		for _, n := range names {
+			name := n
+			cluster := &awst.EKSCluster{
+				Nodegroups:      []eksTypes.Nodegroup{},
+				FargateProfiles: []eksTypes.FargateProfile{},
+			}
+			result.EKS.Clusters[name] = cluster
+
+			executor.Launch(ctx, func() {
+				description, err := eks.FetchCluster(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while fetching EKS cluster %s: %w", name, err))
+					return
+				}
+				cluster.Cluster = description
+			})
+
+			executor.Launch(ctx, func() {
+				nodegroups, err := eks.ListAllNodegroupNames(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while listing all node groups for EKS cluster %s: %w", name, err))
+				}
+				for _, ng := range nodegroups {
+					nodegroup := ng
+					executor.Launch(ctx, func() {
+						description, err := eks.FetchNodegroup(ctx, cfg, name, nodegroup)
+						if err != nil {
+							reportError(fmt.Errorf("error while fetching node group %s for EKS cluster %s: %w", nodegroup, name, err))
+							return
+						}
+						lock.Lock()
+						defer lock.Unlock()
+						cluster.Nodegroups = append(cluster.Nodegroups, *description)
+					})
+				}
+			})
+
+			executor.Launch(ctx, func() {
+				profiles, err := eks.ListAllFargateProfileNames(ctx, cfg, name)
+				if err != nil {
+					reportError(fmt.Errorf("error while listing all fargate profiles for EKS cluster %s: %w", name, err))
+				}
+				for _, p := range profiles {
+					profile := p
+					executor.Launch(ctx, func() {
+						description, err := eks.FetchFargateProfile(ctx, cfg, name, profile)
+						if err != nil {
+							reportError(fmt.Errorf("error while fetching fargate profile %s for EKS cluster %s: %w", profile, name, err))
+							return
+						}
+						lock.Lock()
+						defer lock.Unlock()
+						cluster.FargateProfiles = append(cluster.FargateProfiles, *description)
+					})
+				}
+			})
+		}
+	})
+}
+
+// selectedServices lists the services, both global and regional, that the options select
+func selectedServices(options options) []string {
+	result := []string{}
+	for _, service := range ListServices() {
+		if shouldFetchService(service, options) {
+			result = append(result, service)
+		}
+	}
+	return result
+}
+
 func shouldFetchService(service string, options options) bool {
 	service = strings.ToLower(service)
 	_, excluded := options.excludeServices[service]
@@ -0,0 +1,113 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	awst "awstool/aws"
+	"awstool/common"
+	"awstool/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// replayConfig returns a config that answers requests with the responses recorded in the
+// given fixture file from testdata, instead of sending them to AWS
+func replayConfig(t *testing.T, fixture string) aws.Config {
+	replayer, err := http.LoadReplayer(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "SECRET", ""),
+		HTTPClient:  replayer,
+		Retryer: func() aws.Retryer {
+			return aws.NopRetryer{}
+		},
+	}
+}
+
+func instanceIds(region awst.Region) []string {
+	result := []string{}
+	for _, reservation := range region.EC2.Reservations {
+		for _, instance := range reservation.Instances {
+			result = append(result, aws.ToString(instance.InstanceId))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestLoadAWS(t *testing.T) {
+	cfg := replayConfig(t, "ec2.json")
+
+	// regions are listed from the api and filtered, and instances in us-east-1 span two pages
+	result, err := LoadAWS(context.Background(), cfg, WithServices("ec2"), WithoutRegions("ap-south-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regions := []string{}
+	for name := range result.Regions {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions)
+	if !reflect.DeepEqual(regions, []string{"eu-west-1", "us-east-1"}) {
+		t.Fatalf("expected regions eu-west-1 and us-east-1, got %v", regions)
+	}
+	if ids := instanceIds(result.Regions["us-east-1"]); !reflect.DeepEqual(ids, []string{"i-1", "i-2"}) {
+		t.Errorf("expected all pages of us-east-1 instances to be loaded, got %v", ids)
+	}
+	if ids := instanceIds(result.Regions["eu-west-1"]); !reflect.DeepEqual(ids, []string{"i-3"}) {
+		t.Errorf("expected eu-west-1 instances to be loaded, got %v", ids)
+	}
+	if len(result.Failures) != 0 {
+		t.Errorf("expected no failures, got %v", result.Failures)
+	}
+}
+
+func TestLoadAWSFailures(t *testing.T) {
+	options := []Option{WithServices("ec2", "ebs"), WithRegions("us-east-1", "eu-west-1")}
+
+	// describing volumes in us-east-1 is denied, which fails the whole load by default
+	_, err := LoadAWS(context.Background(), replayConfig(t, "ec2.json"), options...)
+	var errs common.Errors
+	if !errors.As(err, &errs) || len(errs.Errors) != 1 {
+		t.Fatalf("expected a single error, got %v", err)
+	}
+
+	options = append(options, WithPartialResults())
+	result, err := LoadAWS(context.Background(), replayConfig(t, "ec2.json"), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []awst.Failure{{
+		Service:    "ebs",
+		Region:     "us-east-1",
+		Operation:  "DescribeVolumes",
+		ErrorClass: ErrorClassAccessDenied,
+		ErrorCode:  "UnauthorizedOperation",
+	}}
+	for idx := range result.Failures {
+		result.Failures[idx].Message = ""
+	}
+	if !reflect.DeepEqual(result.Failures, expected) {
+		t.Errorf("expected failures %+v, got %+v", expected, result.Failures)
+	}
+
+	// everything else is still loaded
+	if ids := instanceIds(result.Regions["us-east-1"]); !reflect.DeepEqual(ids, []string{"i-1", "i-2"}) {
+		t.Errorf("expected us-east-1 instances to be loaded, got %v", ids)
+	}
+	volumes := result.Regions["eu-west-1"].EC2.Volumes
+	if len(volumes) != 1 || aws.ToString(volumes[0].VolumeId) != "vol-1" {
+		t.Errorf("expected eu-west-1 volumes to be loaded, got %v", volumes)
+	}
+}
@@ -4,9 +4,17 @@ import (
 	"strings"
 
 	"awstool/aws/ec2"
+	"awstool/aws/ecs"
 	"awstool/aws/elasticsearch"
+	"awstool/aws/lambda"
+	"awstool/aws/rds"
 )
 
+// DefaultAccountParallelism is how many accounts LoadAccounts loads at once unless
+// WithAccountParallelism says otherwise. Each account already loads its regions and services in
+// parallel, so loading all accounts at once would mostly get requests throttled
+const DefaultAccountParallelism = 4
+
 type options struct {
 	includeRegions map[string]struct{}
 	excludeRegions map[string]struct{}
@@ -14,8 +22,19 @@ type options struct {
 	includeServices map[string]struct{}
 	excludeServices map[string]struct{}
 
-	ec2FetchOptions []ec2.FetchOption
-	esFetchOptions  []elasticsearch.FetchOption
+	includeAccounts map[string]struct{}
+	excludeAccounts map[string]struct{}
+
+	accountParallelism int
+
+	ec2FetchOptions    []ec2.FetchOption
+	esFetchOptions     []elasticsearch.FetchOption
+	rdsFetchOptions    []rds.FetchOption
+	lambdaFetchOptions []lambda.FetchOption
+	ecsFetchOptions    []ecs.FetchOption
+
+	partialResults bool
+	recordHandler  RecordHandler
 }
 
 type Option func(opts *options)
@@ -52,6 +71,41 @@ func WithoutServices(services ...string) Option {
 	}
 }
 
+// WithAccounts limits which accounts LoadAccounts loads data from. Accounts can be
+// identified either by id or by name
+func WithAccounts(accounts ...string) Option {
+	return func(opts *options) {
+		for _, account := range accounts {
+			opts.includeAccounts[strings.ToLower(account)] = struct{}{}
+		}
+	}
+}
+
+// WithoutAccounts excludes accounts from being loaded by LoadAccounts. Accounts can be
+// identified either by id or by name
+func WithoutAccounts(accounts ...string) Option {
+	return func(opts *options) {
+		for _, account := range accounts {
+			opts.excludeAccounts[strings.ToLower(account)] = struct{}{}
+		}
+	}
+}
+
+// WithAccountParallelism sets how many accounts LoadAccounts loads at once
+func WithAccountParallelism(parallelism int) Option {
+	return func(opts *options) {
+		opts.accountParallelism = parallelism
+	}
+}
+
+// WithPartialResults makes LoadAWS return all data it could load even if some of it failed to
+// load, reporting what failed in the Failures field of the result instead of returning an error
+func WithPartialResults() Option {
+	return func(opts *options) {
+		opts.partialResults = true
+	}
+}
+
 func WithEC2FetchOptions(fetchOptions ...ec2.FetchOption) Option {
 	return func(opts *options) {
 		opts.ec2FetchOptions = append(opts.ec2FetchOptions, fetchOptions...)
@@ -64,12 +118,35 @@ func WithESFetchOptions(fetchOptions ...elasticsearch.FetchOption) Option {
 	}
 }
 
+func WithRDSFetchOptions(fetchOptions ...rds.FetchOption) Option {
+	return func(opts *options) {
+		opts.rdsFetchOptions = append(opts.rdsFetchOptions, fetchOptions...)
+	}
+}
+
+func WithLambdaFetchOptions(fetchOptions ...lambda.FetchOption) Option {
+	return func(opts *options) {
+		opts.lambdaFetchOptions = append(opts.lambdaFetchOptions, fetchOptions...)
+	}
+}
+
+func WithECSFetchOptions(fetchOptions ...ecs.FetchOption) Option {
+	return func(opts *options) {
+		opts.ecsFetchOptions = append(opts.ecsFetchOptions, fetchOptions...)
+	}
+}
+
 func newOptions(fns []Option) options {
 	options := options{
 		includeRegions:  map[string]struct{}{},
 		excludeRegions:  map[string]struct{}{},
 		includeServices: map[string]struct{}{},
 		excludeServices: map[string]struct{}{},
+		includeAccounts: map[string]struct{}{},
+		excludeAccounts: map[string]struct{}{},
+
+		accountParallelism: DefaultAccountParallelism,
+
 		ec2FetchOptions: []ec2.FetchOption{},
 	}
 	for _, fn := range fns {
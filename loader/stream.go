@@ -0,0 +1,151 @@
+package loader
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	awst "awstool/aws"
+	"awstool/executor"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Record is a single resource emitted when loading in streaming mode. Type is the path of the
+// resource collection in the awst.AWS model, eg "IAM.Users". Key is only set for resources
+// stored in maps (eg "Elasticsearch.Domains" are keyed by domain name). EC2 instances are the
+// exception: reservations are not resources of their own, so each instance is a record of type
+// "EC2.Instances" keyed by its reservation id. Account is only set when loading multiple accounts
+// with LoadAccounts
+type Record struct {
+	Account  string
+	Region   string
+	Service  string
+	Type     string
+	Key      string
+	Resource interface{}
+}
+
+// RecordHandler receives the records of a single service (and region, for regional services)
+// as soon as that service finishes loading. It may be called concurrently
+type RecordHandler = func(records []Record)
+
+// WithRecordHandler enables streaming mode: instead of accumulating all loaded data in the
+// result of LoadAWS, the resources of each service are passed to the handler as soon as the
+// service is loaded and are then released, which keeps memory usage bounded. Regions in the
+// result of LoadAWS are left empty in this mode
+func WithRecordHandler(handler RecordHandler) Option {
+	return func(opts *options) {
+		opts.recordHandler = handler
+	}
+}
+
+// streamRegionalService loads a single regional service into a fresh region struct and emits
+// its resources once all fetches of the service are done
+func streamRegionalService(
+	ctx context.Context,
+	cfg aws.Config,
+	service string,
+	fn regionalServiceFetchFunc,
+	reportError errorReporter,
+	opts options,
+) {
+	serviceResult := awst.NewRegion(cfg.Region)
+	serviceExecutor := executor.NewExecutor(0)
+	fn(ctx, cfg, serviceExecutor, reportError, &serviceResult, opts)
+	<-serviceExecutor.Done()
+	emitRecords(opts.recordHandler, cfg.Region, service, reflect.ValueOf(serviceResult))
+}
+
+// streamGlobalService is the same as streamRegionalService, but for global services
+func streamGlobalService(
+	ctx context.Context,
+	cfg aws.Config,
+	service string,
+	fn globalServiceFetchFunc,
+	reportError errorReporter,
+	opts options,
+) {
+	serviceResult := awst.New()
+	serviceExecutor := executor.NewExecutor(0)
+	fn(ctx, cfg, serviceExecutor, reportError, &serviceResult, opts)
+	<-serviceExecutor.Done()
+	// regions and failures are filled by LoadAWS itself, never by global services
+	serviceResult.Regions = nil
+	serviceResult.Failures = nil
+	emitRecords(opts.recordHandler, "", service, reflect.ValueOf(serviceResult))
+}
+
+func emitRecords(handler RecordHandler, region string, service string, value reflect.Value) {
+	records := []Record{}
+	collectRecords(value, nil, func(path []string, key string, resource interface{}) {
+		if reservation, ok := resource.(ec2Types.Reservation); ok {
+			for _, instance := range reservation.Instances {
+				records = append(records, Record{
+					Region:   region,
+					Service:  service,
+					Type:     "EC2.Instances",
+					Key:      aws.ToString(reservation.ReservationId),
+					Resource: instance,
+				})
+			}
+			return
+		}
+		records = append(records, Record{
+			Region:   region,
+			Service:  service,
+			Type:     strings.Join(path, "."),
+			Key:      key,
+			Resource: resource,
+		})
+	})
+	if len(records) > 0 {
+		handler(records)
+	}
+}
+
+// collectRecords walks the awst model. Structs defined in this module are containers and are
+// walked into, while every element of slices and maps and any other struct is a resource
+func collectRecords(value reflect.Value, path []string, emit func(path []string, key string, resource interface{})) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+		if isModelContainer(value.Elem()) {
+			collectRecords(value.Elem(), path, emit)
+		} else {
+			emit(path, "", value.Interface())
+		}
+	case reflect.Struct:
+		if !isModelContainer(value) {
+			emit(path, "", value.Interface())
+			return
+		}
+		for idx := 0; idx < value.NumField(); idx++ {
+			field := value.Type().Field(idx)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			collectRecords(value.Field(idx), append(append([]string{}, path...), field.Name), emit)
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < value.Len(); idx++ {
+			emit(path, "", value.Index(idx).Interface())
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			emit(path, key.String(), value.MapIndex(key).Interface())
+		}
+	}
+}
+
+func isModelContainer(value reflect.Value) bool {
+	return value.Kind() == reflect.Struct && strings.HasPrefix(value.Type().PkgPath(), "awstool/aws")
+}
@@ -0,0 +1,132 @@
+package loader
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+// recordSummary is a record without its resource, which is compared separately
+type recordSummary struct {
+	Account string
+	Region  string
+	Service string
+	Type    string
+	Key     string
+	Id      string
+}
+
+func summarize(records []Record) []recordSummary {
+	result := make([]recordSummary, len(records))
+	for idx, record := range records {
+		result[idx] = recordSummary{
+			Account: record.Account,
+			Region:  record.Region,
+			Service: record.Service,
+			Type:    record.Type,
+			Key:     record.Key,
+		}
+		switch resource := record.Resource.(type) {
+		case ec2Types.Instance:
+			result[idx].Id = aws.ToString(resource.InstanceId)
+		case ec2Types.Volume:
+			result[idx].Id = aws.ToString(resource.VolumeId)
+		case iamTypes.User:
+			result[idx].Id = aws.ToString(resource.UserName)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Region != result[j].Region {
+			return result[i].Region < result[j].Region
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
+
+func TestEmitRecords(t *testing.T) {
+	region := awst.NewRegion("us-east-1")
+	region.EC2.Reservations = []ec2Types.Reservation{
+		{
+			ReservationId: aws.String("r-1"),
+			Instances:     []ec2Types.Instance{{InstanceId: aws.String("i-1")}, {InstanceId: aws.String("i-2")}},
+		},
+		{ReservationId: aws.String("r-2"), Instances: []ec2Types.Instance{{InstanceId: aws.String("i-3")}}},
+	}
+	region.Elasticsearch.Domains["logs"] = &awst.ElasticsearchDomain{}
+
+	records := []Record{}
+	emitRecords(func(emitted []Record) { records = append(records, emitted...) }, "us-east-1", "ec2", reflect.ValueOf(region))
+
+	expected := []recordSummary{
+		{Region: "us-east-1", Service: "ec2", Type: "Elasticsearch.Domains", Key: "logs"},
+		{Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-1", Id: "i-1"},
+		{Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-1", Id: "i-2"},
+		{Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-2", Id: "i-3"},
+	}
+	if summary := summarize(records); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected records %+v, got %+v", expected, summary)
+	}
+
+	global := awst.New()
+	global.IAM.Users = []iamTypes.User{{UserName: aws.String("bob")}, {UserName: aws.String("alice")}}
+	records = []Record{}
+	emitRecords(func(emitted []Record) { records = append(records, emitted...) }, "", "iam", reflect.ValueOf(global))
+	expected = []recordSummary{
+		{Service: "iam", Type: "IAM.Users", Id: "alice"},
+		{Service: "iam", Type: "IAM.Users", Id: "bob"},
+	}
+	if summary := summarize(records); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected records %+v, got %+v", expected, summary)
+	}
+
+	// nothing loaded, nothing emitted
+	emitRecords(func(emitted []Record) {
+		t.Errorf("expected no records for an empty region, got %+v", emitted)
+	}, "us-east-1", "ec2", reflect.ValueOf(awst.NewRegion("us-east-1")))
+}
+
+func TestLoadAWSStreaming(t *testing.T) {
+	var lock sync.Mutex
+	records := []Record{}
+	handler := withAccountRecords(orgTypes.Account{Id: aws.String("123456789012")}, func(emitted []Record) {
+		lock.Lock()
+		defer lock.Unlock()
+		records = append(records, emitted...)
+	})
+
+	result, err := LoadAWS(
+		context.Background(), replayConfig(t, "ec2.json"),
+		WithServices("ec2", "ebs"), WithRegions("us-east-1", "eu-west-1"), WithPartialResults(), handler,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []recordSummary{
+		{Account: "123456789012", Region: "eu-west-1", Service: "ec2", Type: "EC2.Instances", Key: "r-i-3", Id: "i-3"},
+		{Account: "123456789012", Region: "eu-west-1", Service: "ebs", Type: "EC2.Volumes", Id: "vol-1"},
+		{Account: "123456789012", Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-i-1", Id: "i-1"},
+		{Account: "123456789012", Region: "us-east-1", Service: "ec2", Type: "EC2.Instances", Key: "r-i-2", Id: "i-2"},
+	}
+	if summary := summarize(records); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected records %+v, got %+v", expected, summary)
+	}
+
+	// streamed data is not accumulated, but failures are still reported
+	if len(result.Regions) != 0 {
+		t.Errorf("expected no regions in the result, got %v", result.Regions)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Service != "ebs" || result.Failures[0].Region != "us-east-1" {
+		t.Errorf("expected the us-east-1 ebs failure, got %+v", result.Failures)
+	}
+}
@@ -0,0 +1,389 @@
+// Package printer prints resources found by commands in the output format picked by the user:
+// aligned tables for humans, or json, yaml, csv and templates for scripts
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	TableFormat    = "table"
+	WideFormat     = "wide"
+	JSONFormat     = "json"
+	YAMLFormat     = "yaml"
+	CSVFormat      = "csv"
+	TemplateFormat = "template"
+)
+
+var formats = []string{TableFormat, WideFormat, JSONFormat, YAMLFormat, CSVFormat, TemplateFormat}
+
+const (
+	tagsColumn      = "tags"
+	tagColumnPrefix = "tag:"
+)
+
+// Column is a field printed for every row
+type Column struct {
+	Name string
+	// Wide columns are only printed by default with the wide, json, yaml and csv formats
+	Wide  bool
+	Value func(row interface{}) interface{}
+}
+
+// Table describes how to print the rows of a command
+type Table struct {
+	Columns []Column
+	// Tags returns the tags of a row. When set, the tags column and tag:KEY columns can be printed
+	Tags func(row interface{}) []Tag
+	// SortBy lists the columns rows are sorted by, unless the user picks another one with --sort-by.
+	// Rows keep their order when empty
+	SortBy []string
+}
+
+// Options are how the user wants rows printed. Set defaults before calling AddFlags, eg to print
+// headers by default
+type Options struct {
+	Format       string
+	Columns      []string
+	SortBy       string
+	Header       bool
+	Template     string
+	PrintTags    []string
+	PrintAllTags bool
+	NoURLEncode  bool
+
+	template *template.Template
+	columns  []Column
+}
+
+// AddFlags registers the output flags. The template help should tell which fields are available
+// to templates, eg "'{{.Region}} {{.Instance.InstanceId}}'"
+func (o *Options) AddFlags(flags *pflag.FlagSet, templateExample string) {
+	if o.Format == "" {
+		o.Format = TableFormat
+	}
+
+	flags.StringVarP(
+		&o.Format, "output", "o", o.Format,
+		"How to print results: "+strings.Join(formats, ", ")+". The wide table adds more columns, "+
+			"while json, yaml and csv print all columns by default",
+	)
+
+	flags.StringSliceVarP(
+		&o.Columns, "columns", "c", o.Columns,
+		"Print these columns instead of the default ones. Tags can be printed as columns with tag:KEY, "+
+			"eg --columns id,tag:Owner",
+	)
+
+	flags.StringVar(
+		&o.SortBy, "sort-by", o.SortBy,
+		"Sort by this column. Prefix it with - to sort in descending order, eg --sort-by -launchTime",
+	)
+
+	flags.BoolVarP(
+		&o.Header, "header", "H", o.Header,
+		"Also print a header on the first line of tables, which will name the columns being printed",
+	)
+
+	flags.StringVar(
+		&o.Template, "template", o.Template,
+		"Print using a golang template instead. Template syntax is defined at https://pkg.go.dev/text/template. "+
+			"A simple template: "+templateExample+". "+
+			"The struct passed to the template engine can be checked by reading the source code for this command. "+
+			"Instead you can also inspect and navigate the fields available to a template with the template "+
+			"'{{printf \"%#+v\" .}}' and navigating from that point forward",
+	)
+}
+
+// AddTagFlags registers the flags to print tags, for tables with tags
+func (o *Options) AddTagFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVarP(
+		&o.PrintTags, "print-tags", "T", o.PrintTags,
+		"Print the tags column with these tag keys. See also --print-all-tags",
+	)
+
+	flags.BoolVarP(
+		&o.PrintAllTags, "print-all-tags", "A", o.PrintAllTags,
+		"Print the tags column with all tags. Overrides --print-tags",
+	)
+
+	flags.BoolVarP(
+		&o.NoURLEncode, "no-url-encode", "E", o.NoURLEncode,
+		"By default tag values are URL encoded in tables to avoid whitespacing issues. "+
+			"Use this flag to avoid such mechanism",
+	)
+}
+
+// Validate checks the options against the table, so errors are found before loading any data
+func (o *Options) Validate(table *Table) error {
+	if o.Template != "" {
+		if o.Format != TableFormat && o.Format != TemplateFormat {
+			return fmt.Errorf("--template cannot be used with the %s output", o.Format)
+		}
+		o.Format = TemplateFormat
+	}
+	if !contains(formats, o.Format) {
+		return fmt.Errorf("invalid output %q, must be one of: %s", o.Format, strings.Join(formats, ", "))
+	}
+	if o.Format == TemplateFormat {
+		if o.Template == "" {
+			return fmt.Errorf("the template output requires --template")
+		}
+		var err error
+		o.template, err = template.New("user_input").Parse(o.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template %q: %w", o.Template, err)
+		}
+	}
+
+	names := o.Columns
+	if len(names) == 0 {
+		names = o.defaultColumns(table)
+	}
+	o.columns = make([]Column, 0, len(names))
+	for _, name := range names {
+		column, err := o.column(table, name)
+		if err != nil {
+			return err
+		}
+		o.columns = append(o.columns, column)
+	}
+
+	if o.SortBy != "" {
+		if _, err := o.column(table, strings.TrimPrefix(o.SortBy, "-")); err != nil {
+			return fmt.Errorf("invalid --sort-by: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *Options) defaultColumns(table *Table) []string {
+	all := o.Format != TableFormat
+	result := []string{}
+	for _, column := range table.Columns {
+		if all || !column.Wide {
+			result = append(result, column.Name)
+		}
+	}
+	if table.Tags != nil {
+		machineReadable := o.Format == JSONFormat || o.Format == YAMLFormat || o.Format == CSVFormat
+		if machineReadable || o.PrintAllTags || len(o.PrintTags) > 0 {
+			result = append(result, tagsColumn)
+		}
+	}
+	return result
+}
+
+// column finds a column by name, case insensitive, including the tags and tag:KEY columns
+func (o *Options) column(table *Table, name string) (Column, error) {
+	for _, column := range table.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column, nil
+		}
+	}
+	if table.Tags != nil {
+		if strings.EqualFold(name, tagsColumn) {
+			return Column{Name: tagsColumn, Value: func(row interface{}) interface{} {
+				return o.selectTags(table.Tags(row))
+			}}, nil
+		}
+		if strings.HasPrefix(strings.ToLower(name), tagColumnPrefix) && len(name) > len(tagColumnPrefix) {
+			key := name[len(tagColumnPrefix):]
+			return Column{Name: name, Value: func(row interface{}) interface{} {
+				for _, tag := range table.Tags(row) {
+					if tag.Key == key {
+						return TagValue(tag.Value)
+					}
+				}
+				return nil
+			}}, nil
+		}
+	}
+
+	available := make([]string, 0, len(table.Columns)+2)
+	for _, column := range table.Columns {
+		available = append(available, column.Name)
+	}
+	if table.Tags != nil {
+		available = append(available, tagsColumn, tagColumnPrefix+"KEY")
+	}
+	return Column{}, fmt.Errorf("unknown column %q, must be one of: %s", name, strings.Join(available, ", "))
+}
+
+// selectTags picks the tags printed in the tags column: the ones in --print-tags, in that order,
+// or all of them
+func (o *Options) selectTags(tags []Tag) Tags {
+	if o.PrintAllTags || len(o.PrintTags) == 0 {
+		return tags
+	}
+	result := Tags{}
+	for _, key := range o.PrintTags {
+		for _, tag := range tags {
+			if tag.Key == key {
+				result = append(result, tag)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Print prints the rows in the chosen format. Validate must be called first
+func (o *Options) Print(out io.Writer, table *Table, rows []interface{}) error {
+	rows = o.sort(table, rows)
+	switch o.Format {
+	case TemplateFormat:
+		return o.printTemplate(out, rows)
+	case JSONFormat:
+		return o.printJSON(out, rows)
+	case YAMLFormat:
+		return o.printYAML(out, rows)
+	case CSVFormat:
+		return o.printCSV(out, rows)
+	default:
+		return o.printTable(out, rows)
+	}
+}
+
+func (o *Options) sort(table *Table, rows []interface{}) []interface{} {
+	sortBy := table.SortBy
+	descending := false
+	if o.SortBy != "" {
+		descending = strings.HasPrefix(o.SortBy, "-")
+		sortBy = []string{strings.TrimPrefix(o.SortBy, "-")}
+	}
+	if len(sortBy) == 0 {
+		return rows
+	}
+	columns := make([]Column, 0, len(sortBy))
+	for _, name := range sortBy {
+		// columns were validated already, or are defined by the command
+		if column, err := o.column(table, name); err == nil {
+			columns = append(columns, column)
+		}
+	}
+
+	sorted := make([]interface{}, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, column := range columns {
+			a, b := normalize(column.Value(sorted[i])), normalize(column.Value(sorted[j]))
+			result := compare(a, b)
+			if result != 0 {
+				// missing values go last in both orders
+				if descending && a != nil && b != nil {
+					return result > 0
+				}
+				return result < 0
+			}
+		}
+		return false
+	})
+	return sorted
+}
+
+func (o *Options) printTemplate(out io.Writer, rows []interface{}) error {
+	for _, row := range rows {
+		buf := strings.Builder{}
+		if err := o.template.Execute(&buf, row); err != nil {
+			return fmt.Errorf("template execution error: %w", err)
+		}
+		fmt.Fprintln(out, buf.String())
+	}
+	return nil
+}
+
+func (o *Options) printTable(out io.Writer, rows []interface{}) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 1, ' ', 0)
+	if o.Header {
+		header := make([]string, len(o.columns))
+		for idx, column := range o.columns {
+			header[idx] = "#" + column.Name
+		}
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		values := make([]string, len(o.columns))
+		for idx, column := range o.columns {
+			values[idx] = text(normalize(column.Value(row)), !o.NoURLEncode)
+			if values[idx] == "" {
+				values[idx] = "<N/A>"
+			}
+		}
+		fmt.Fprintln(writer, strings.Join(values, "\t"))
+	}
+	return writer.Flush()
+}
+
+func (o *Options) printCSV(out io.Writer, rows []interface{}) error {
+	writer := csv.NewWriter(out)
+	header := make([]string, len(o.columns))
+	for idx, column := range o.columns {
+		header[idx] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		values := make([]string, len(o.columns))
+		for idx, column := range o.columns {
+			values[idx] = text(normalize(column.Value(row)), false)
+		}
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (o *Options) printJSON(out io.Writer, rows []interface{}) error {
+	records := make([]record, len(rows))
+	for idx, row := range rows {
+		records[idx] = o.record(row)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func (o *Options) printYAML(out io.Writer, rows []interface{}) error {
+	records := make([]record, len(rows))
+	for idx, row := range rows {
+		records[idx] = o.record(row)
+	}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(records); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (o *Options) record(row interface{}) record {
+	result := make(record, len(o.columns))
+	for idx, column := range o.columns {
+		result[idx] = field{name: column.Name, value: normalize(column.Value(row))}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
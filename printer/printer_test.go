@@ -0,0 +1,135 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type row struct {
+	Region string
+	Id     string
+	Size   *int
+	Tags   []Tag
+}
+
+func intP(value int) *int {
+	return &value
+}
+
+var testTable = Table{
+	Columns: []Column{
+		{Name: "region", Value: func(r interface{}) interface{} { return r.(row).Region }},
+		{Name: "id", Value: func(r interface{}) interface{} { return r.(row).Id }},
+		{Name: "size", Wide: true, Value: func(r interface{}) interface{} { return r.(row).Size }},
+	},
+	Tags:   func(r interface{}) []Tag { return r.(row).Tags },
+	SortBy: []string{"region", "id"},
+}
+
+var testRows = []interface{}{
+	row{Region: "us-west-2", Id: "b", Size: intP(10), Tags: []Tag{{"Name", "web 1"}, {"Env", "prod"}}},
+	row{Region: "us-east-1", Id: "c", Size: intP(9)},
+	row{Region: "us-east-1", Id: "a", Tags: []Tag{{"Env", "123"}}},
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		expected string
+	}{
+		{
+			name:    "table",
+			options: Options{Format: TableFormat, Header: true},
+			expected: "#region   #id\n" +
+				"us-east-1 a\n" +
+				"us-east-1 c\n" +
+				"us-west-2 b\n",
+		},
+		{
+			name:    "wide with tags",
+			options: Options{Format: WideFormat, PrintTags: []string{"Name"}},
+			expected: "us-east-1 a <N/A> <N/A>\n" +
+				"us-east-1 c 9     <N/A>\n" +
+				"us-west-2 b 10    Name:web%201\n",
+		},
+		{
+			name:     "columns and sort",
+			options:  Options{Format: TableFormat, Columns: []string{"ID", "tag:Name"}, SortBy: "-size", NoURLEncode: true},
+			expected: "b web 1\nc <N/A>\na <N/A>\n",
+		},
+		{
+			name:    "csv",
+			options: Options{Format: CSVFormat, Columns: []string{"id", "tags"}},
+			expected: "id,tags\n" +
+				"a,Env:123\n" +
+				"c,\n" +
+				"b,\"Name:web 1,Env:prod\"\n",
+		},
+		{
+			name:     "json",
+			options:  Options{Format: JSONFormat, Columns: []string{"id", "size", "tags"}, SortBy: "id"},
+			expected: `[{"id":"a","size":null,"tags":{"Env":"123"}},{"id":"b","size":10,"tags":{"Name":"web 1","Env":"prod"}},{"id":"c","size":9,"tags":{}}]`,
+		},
+		{
+			name:    "yaml",
+			options: Options{Format: YAMLFormat, Columns: []string{"id", "tags"}, SortBy: "id"},
+			expected: "- id: a\n  tags:\n    Env: \"123\"\n" +
+				"- id: b\n  tags:\n    Name: web 1\n    Env: prod\n" +
+				"- id: c\n  tags: {}\n",
+		},
+		{
+			name:     "template",
+			options:  Options{Format: TableFormat, Template: "{{.Id}}@{{.Region}}"},
+			expected: "a@us-east-1\nc@us-east-1\nb@us-west-2\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			if err := options.Validate(&testTable); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			buf := bytes.Buffer{}
+			if err := options.Print(&buf, &testTable, testRows); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := buf.String()
+			if options.Format == JSONFormat {
+				result = strings.Join(strings.Fields(result), "")
+				expected := strings.Join(strings.Fields(test.expected), "")
+				if result != expected {
+					t.Errorf("expected %s, got %s", expected, result)
+				}
+				return
+			}
+			if result != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		err     string
+	}{
+		{"unknown format", Options{Format: "xml"}, `invalid output "xml"`},
+		{"unknown column", Options{Format: TableFormat, Columns: []string{"bogus"}}, `unknown column "bogus", must be one of: region, id, size, tags, tag:KEY`},
+		{"unknown sort column", Options{Format: TableFormat, SortBy: "-bogus"}, `invalid --sort-by`},
+		{"template with json", Options{Format: JSONFormat, Template: "{{.Id}}"}, "--template cannot be used"},
+		{"template output without template", Options{Format: TemplateFormat}, "requires --template"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate(&testTable)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tag is a key/value pair, as most AWS resources have
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// TagValue is the value of a single tag, eg for a name column. Like tags, it is URL encoded in
+// tables unless asked not to
+type TagValue string
+
+// Tags keep the order they are given in. They are printed as KEY:VALUE pairs in tables and csv
+// and as objects in json and yaml
+type Tags []Tag
+
+func (t Tags) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for idx, tag := range t {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(tag.Key)
+		value, _ := json.Marshal(tag.Value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (t Tags) MarshalYAML() (interface{}, error) {
+	node := yaml.Node{Kind: yaml.MappingNode}
+	for _, tag := range t {
+		node.Content = append(
+			node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tag.Key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tag.Value},
+		)
+	}
+	return &node, nil
+}
+
+// field and record keep the order of columns in json and yaml objects
+type field struct {
+	name  string
+	value interface{}
+}
+
+type record []field
+
+func (r record) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for idx, field := range r {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.name)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r record) MarshalYAML() (interface{}, error) {
+	node := yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range r {
+		value := yaml.Node{}
+		if err := value.Encode(field.value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.name}, &value)
+	}
+	return &node, nil
+}
+
+// normalize dereferences pointers and converts values to the few types printed: strings,
+// numbers, booleans, times, tags, lists and anything else as json. Empty values are nil
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case Tags:
+		return v
+	case TagValue:
+		if v == "" {
+			return nil
+		}
+		return v
+	case []Tag:
+		return Tags(v)
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return nil
+		}
+		return *v
+	}
+
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Pointer {
+		if reflected.IsNil() {
+			return nil
+		}
+		reflected = reflected.Elem()
+	}
+	switch reflected.Kind() {
+	case reflect.String:
+		if reflected.String() == "" {
+			return nil
+		}
+		return reflected.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflected.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(reflected.Uint())
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float()
+	case reflect.Bool:
+		return reflected.Bool()
+	case reflect.Slice:
+		if reflected.Len() == 0 {
+			return nil
+		}
+		result := make([]interface{}, reflected.Len())
+		for idx := range result {
+			result[idx] = normalize(reflected.Index(idx).Interface())
+		}
+		return result
+	}
+	return reflected.Interface()
+}
+
+// text formats a normalized value for tables and csv. Tag values are URL encoded when asked to,
+// so tables keep a single word per column
+func text(value interface{}, urlEncode bool) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case TagValue:
+		if urlEncode {
+			return url.PathEscape(string(v))
+		}
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case Tags:
+		pairs := make([]string, len(v))
+		for idx, tag := range v {
+			tagValue := tag.Value
+			if urlEncode {
+				tagValue = url.PathEscape(tagValue)
+			}
+			pairs[idx] = tag.Key + ":" + tagValue
+		}
+		return strings.Join(pairs, ",")
+	case []interface{}:
+		values := make([]string, len(v))
+		for idx, item := range v {
+			values[idx] = text(item, urlEncode)
+		}
+		return strings.Join(values, ",")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// compare orders normalized values, numbers and times by their value and anything else by text.
+// Missing values go last
+func compare(a interface{}, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+	switch aValue := a.(type) {
+	case int64:
+		if bValue, ok := b.(int64); ok {
+			return compareOrdered(aValue, bValue)
+		}
+	case float64:
+		if bValue, ok := b.(float64); ok {
+			return compareOrdered(aValue, bValue)
+		}
+	case time.Time:
+		if bValue, ok := b.(time.Time); ok {
+			switch {
+			case aValue.Before(bValue):
+				return -1
+			case aValue.After(bValue):
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(text(a, false), text(b, false))
+}
+
+func compareOrdered[T int64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
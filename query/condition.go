@@ -0,0 +1,181 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition filters resources in the where clause of a query
+type Condition interface {
+	matches(resource *resource) bool
+}
+
+type andCondition struct {
+	left  Condition
+	right Condition
+}
+
+func (c andCondition) matches(resource *resource) bool {
+	return c.left.matches(resource) && c.right.matches(resource)
+}
+
+type orCondition struct {
+	left  Condition
+	right Condition
+}
+
+func (c orCondition) matches(resource *resource) bool {
+	return c.left.matches(resource) || c.right.matches(resource)
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+func (c notCondition) matches(resource *resource) bool {
+	return !c.condition.matches(resource)
+}
+
+type comparison struct {
+	field    string
+	operator string
+	values   []interface{}
+	regexp   *regexp.Regexp
+}
+
+func newComparison(c comparison) (Condition, error) {
+	switch c.operator {
+	case "like", "~":
+		pattern, ok := c.values[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s on field %s requires a quoted string", c.operator, c.field)
+		}
+		if c.operator == "like" {
+			pattern = wildcardPattern(pattern)
+		}
+		var err error
+		c.regexp, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for field %s: %w", c.field, err)
+		}
+	case "<", "<=", ">", ">=":
+		if c.values[0] == nil {
+			return nil, fmt.Errorf("%s on field %s can not be used with null", c.operator, c.field)
+		}
+	}
+	return &c, nil
+}
+
+// wildcardPattern converts a pattern with * and ? wildcards to a case insensitive regexp that
+// matches the whole value
+func wildcardPattern(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
+func (c *comparison) matches(resource *resource) bool {
+	value := resource.field(c.field)
+	switch c.operator {
+	case "=":
+		return equal(value, c.values[0])
+	case "!=":
+		return !equal(value, c.values[0])
+	case "in":
+		for _, candidate := range c.values {
+			if equal(value, candidate) {
+				return true
+			}
+		}
+		return false
+	case "like", "~":
+		if value == nil {
+			return false
+		}
+		return c.regexp.MatchString(valueString(value))
+	default:
+		if value == nil {
+			return false
+		}
+		result := compare(value, c.values[0])
+		switch c.operator {
+		case "<":
+			return result < 0
+		case "<=":
+			return result <= 0
+		case ">":
+			return result > 0
+		default:
+			return result >= 0
+		}
+	}
+}
+
+func equal(value interface{}, literal interface{}) bool {
+	if value == nil || literal == nil {
+		return value == nil && literal == nil
+	}
+	return compare(value, literal) == 0
+}
+
+// compare compares numerically when both sides are numbers (or strings holding numbers) and
+// falls back to comparing their string representations otherwise, which also orders timestamps
+func compare(a interface{}, b interface{}) int {
+	aNumber, aOk := number(a)
+	bNumber, bOk := number(b)
+	if aOk && bOk {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(valueString(a), valueString(b))
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		result, err := strconv.ParseFloat(v, 64)
+		return result, err == nil
+	}
+	return 0, false
+}
+
+// valueString is how values are compared as strings and printed
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,390 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed query expression, eg:
+//
+//	ec2.instance where tag.Env = "prod" and state = "running" select id, privateIp, tag.Name
+//
+// The full syntax is:
+//
+//	TYPE [where CONDITION] [select FIELD, ...] [order by FIELD [asc|desc]] [limit N]
+//
+// Conditions compare fields with =, !=, <, <=, >, >=, like (wildcards * and ?), ~ (regular
+// expression) and in ("a", "b"), and can be combined with and, or, not and parentheses
+type Query struct {
+	Type      string
+	Where     Condition
+	Fields    []string
+	OrderBy   string
+	OrderDesc bool
+	Limit     int
+}
+
+// Parse parses a query expression. See Query for the syntax
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return query, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenComma
+	tokenOpenParen
+	tokenCloseParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q at position %d", t.value, t.pos)
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// identifiers also accept characters commonly found in tag keys, eg aws:cloudformation:stack-name
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:-/*", r)
+}
+
+func tokenize(input string) ([]token, error) {
+	runes := []rune(input)
+	tokens := []token{}
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		start := idx
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", pos: start})
+			idx++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, value: "(", pos: start})
+			idx++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, value: ")", pos: start})
+			idx++
+		case r == '=' || r == '~':
+			tokens = append(tokens, token{kind: tokenOperator, value: string(r), pos: start})
+			idx++
+		case r == '!' || r == '<' || r == '>':
+			idx++
+			if idx < len(runes) && runes[idx] == '=' {
+				idx++
+			} else if r == '!' {
+				return nil, fmt.Errorf("invalid operator at position %d: expected !=", start)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: string(runes[start:idx]), pos: start})
+		case r == '"' || r == '\'' || r == '`':
+			value, end, err := readQuoted(runes, idx)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenString
+			// backticks quote field names with characters not allowed in identifiers, eg tag.`My Tag`
+			if r == '`' {
+				kind = tokenIdent
+			}
+			tokens = append(tokens, token{kind: kind, value: value, pos: start})
+			idx = end
+		case unicode.IsDigit(r) || (r == '-' && idx+1 < len(runes) && unicode.IsDigit(runes[idx+1])):
+			idx++
+			for idx < len(runes) && (unicode.IsDigit(runes[idx]) || runes[idx] == '.') {
+				idx++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:idx]), pos: start})
+		case isIdentStart(r) || r == '*':
+			for idx < len(runes) && (isIdentPart(runes[idx]) || runes[idx] == '`') {
+				if runes[idx] == '`' {
+					_, end, err := readQuoted(runes, idx)
+					if err != nil {
+						return nil, err
+					}
+					idx = end
+					continue
+				}
+				idx++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: unquoteIdent(string(runes[start:idx])), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, start)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// readQuoted reads a quoted value starting at idx, returning its unescaped content and the index
+// right after the closing quote
+func readQuoted(runes []rune, idx int) (string, int, error) {
+	quote := runes[idx]
+	var builder strings.Builder
+	for pos := idx + 1; pos < len(runes); pos++ {
+		switch runes[pos] {
+		case '\\':
+			if pos+1 < len(runes) {
+				pos++
+				builder.WriteRune(runes[pos])
+			}
+		case quote:
+			return builder.String(), pos + 1, nil
+		default:
+			builder.WriteRune(runes[pos])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quote starting at position %d", idx)
+}
+
+func unquoteIdent(ident string) string {
+	return strings.ReplaceAll(ident, "`", "")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given keyword. Keywords are case insensitive
+func (p *parser) keyword(keyword string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.value, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s but got %s", what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	typeToken, err := p.expect(tokenIdent, "a resource type")
+	if err != nil {
+		return nil, err
+	}
+	query := Query{Type: strings.ToLower(typeToken.value)}
+	if err := validateType(query.Type); err != nil {
+		return nil, err
+	}
+
+	if p.keyword("where") {
+		query.Where, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.keyword("select") {
+		for {
+			field, err := p.expect(tokenIdent, "a field name")
+			if err != nil {
+				return nil, err
+			}
+			query.Fields = append(query.Fields, field.value)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if p.keyword("order") {
+		if !p.keyword("by") {
+			return nil, fmt.Errorf("expected by but got %s", p.peek())
+		}
+		field, err := p.expect(tokenIdent, "a field name")
+		if err != nil {
+			return nil, err
+		}
+		query.OrderBy = field.value
+		if p.keyword("desc") {
+			query.OrderDesc = true
+		} else {
+			p.keyword("asc")
+		}
+	}
+
+	if p.keyword("limit") {
+		limit, err := p.expect(tokenNumber, "a number")
+		if err != nil {
+			return nil, err
+		}
+		query.Limit, err = strconv.Atoi(limit.value)
+		if err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit %s", limit)
+		}
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	return &query, nil
+}
+
+func (p *parser) parseOr() (Condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Condition, error) {
+	if p.keyword("not") {
+		condition, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{condition}, nil
+	}
+	if p.peek().kind == tokenOpenParen {
+		p.next()
+		condition, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return condition, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Condition, error) {
+	field, err := p.expect(tokenIdent, "a field name")
+	if err != nil {
+		return nil, err
+	}
+
+	var operator string
+	if t := p.peek(); t.kind == tokenOperator {
+		operator = p.next().value
+	} else if p.keyword("like") {
+		operator = "like"
+	} else if p.keyword("in") {
+		operator = "in"
+	} else {
+		return nil, fmt.Errorf("expected an operator but got %s", t)
+	}
+
+	comparison := comparison{field: field.value, operator: operator}
+	if operator == "in" {
+		if _, err := p.expect(tokenOpenParen, "("); err != nil {
+			return nil, err
+		}
+		for {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			comparison.values = append(comparison.values, value)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+	} else {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		comparison.values = []interface{}{value}
+	}
+
+	return newComparison(comparison)
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return t.value, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+		return value, nil
+	case tokenIdent:
+		switch strings.ToLower(t.value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a value (a quoted string, a number, true, false or null) but got %s", t)
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	awst "awstool/aws"
+)
+
+// Result holds the selected fields of every resource matching a query. Rows follow the order of
+// Fields, and missing values are nil
+type Result struct {
+	Fields []string
+	Rows   [][]interface{}
+}
+
+// Services lists which services need to be loaded to evaluate the query, as in
+// loader.WithServices
+func (q *Query) Services() []string {
+	return []string{resourceTypes[q.Type].service}
+}
+
+// Run evaluates the query against the loaded data
+func Run(q *Query, dump *awst.AWS) (*Result, error) {
+	resourceType := resourceTypes[q.Type]
+
+	resources := []*resource{}
+	var normalizeErr error
+	resourceType.extract(dump, func(region string, id string, tags map[string]string, value interface{}) {
+		if normalizeErr != nil {
+			return
+		}
+		data, err := normalize(value)
+		if err != nil {
+			normalizeErr = fmt.Errorf("failed to read %s %s: %w", q.Type, id, err)
+			return
+		}
+		if tags == nil {
+			tags = tagsOf(data)
+		}
+		resources = append(resources, &resource{
+			region:  region,
+			id:      id,
+			tags:    tags,
+			data:    data,
+			aliases: resourceType.aliases,
+		})
+	})
+	if normalizeErr != nil {
+		return nil, normalizeErr
+	}
+
+	// results come out of maps, so we sort them to always print them in the same order
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].region != resources[j].region {
+			return resources[i].region < resources[j].region
+		}
+		return resources[i].id < resources[j].id
+	})
+
+	matched := []*resource{}
+	for _, resource := range resources {
+		if q.Where == nil || q.Where.matches(resource) {
+			matched = append(matched, resource)
+		}
+	}
+
+	if q.OrderBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i].field(q.OrderBy), matched[j].field(q.OrderBy)
+			// missing values always go last
+			if a == nil || b == nil {
+				return a != nil && b == nil
+			}
+			if q.OrderDesc {
+				return compare(a, b) > 0
+			}
+			return compare(a, b) < 0
+		})
+	}
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+
+	fields := q.Fields
+	if len(fields) == 0 {
+		fields = resourceType.fields
+	}
+	result := Result{Fields: fields, Rows: make([][]interface{}, 0, len(matched))}
+	for _, resource := range matched {
+		row := make([]interface{}, len(fields))
+		for idx, field := range fields {
+			row[idx] = resource.field(field)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return &result, nil
+}
+
+func validateType(resourceType string) error {
+	if _, ok := resourceTypes[resourceType]; !ok {
+		return fmt.Errorf("unknown resource type %q, must be one of: %s", resourceType, strings.Join(Types(), ", "))
+	}
+	return nil
+}
+
+// String formats a value of a result row for printing. Missing values are printed as empty
+// strings and lists and objects as json
+func String(value interface{}) string {
+	return valueString(value)
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func instance(id string, state ec2Types.InstanceStateName, privateIp string, tags map[string]string) ec2Types.Instance {
+	result := ec2Types.Instance{
+		InstanceId:       aws.String(id),
+		InstanceType:     ec2Types.InstanceTypeM5Large,
+		State:            &ec2Types.InstanceState{Name: state},
+		PrivateIpAddress: aws.String(privateIp),
+	}
+	for key, value := range tags {
+		result.Tags = append(result.Tags, ec2Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
+
+func testDump() *awst.AWS {
+	result := awst.New()
+	region := awst.NewRegion("us-east-1")
+	region.EC2.Reservations = []ec2Types.Reservation{{
+		Instances: []ec2Types.Instance{
+			instance("i-1", ec2Types.InstanceStateNameRunning, "10.0.0.1", map[string]string{"Env": "prod", "Name": "web-1"}),
+			instance("i-2", ec2Types.InstanceStateNameStopped, "10.0.0.2", map[string]string{"Env": "prod", "Name": "web-2"}),
+			instance("i-3", ec2Types.InstanceStateNameRunning, "10.0.0.3", map[string]string{"Env": "dev", "Name": "db-1"}),
+			instance("i-4", ec2Types.InstanceStateNameRunning, "10.0.0.4", nil),
+		},
+	}}
+	result.Regions["us-east-1"] = region
+	return &result
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected [][]interface{}
+	}{
+		{
+			query:    `ec2.instance where tag.Env = "prod" and state = "running" select id, privateIp, tag.Name`,
+			expected: [][]interface{}{{"i-1", "10.0.0.1", "web-1"}},
+		},
+		{
+			query:    `ec2.instance where tag.Name like "web-*" or not (tag.Env != null) select id order by id desc`,
+			expected: [][]interface{}{{"i-4"}, {"i-2"}, {"i-1"}},
+		},
+		{
+			query:    `EC2.Instance WHERE State.Name IN ("stopped") OR PrivateIpAddress ~ '\.3$' SELECT id, type`,
+			expected: [][]interface{}{{"i-2", "m5.large"}, {"i-3", "m5.large"}},
+		},
+		{
+			query:    `ec2.instance where region = "us-east-1" select id, tag.Missing limit 1`,
+			expected: [][]interface{}{{"i-1", nil}},
+		},
+	}
+
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("%s: %v", test.query, err)
+		}
+		result, err := Run(q, testDump())
+		if err != nil {
+			t.Fatalf("%s: %v", test.query, err)
+		}
+		if !reflect.DeepEqual(result.Rows, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.query, test.expected, result.Rows)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`ec2.unknown`,
+		`ec2.instance where`,
+		`ec2.instance where id = `,
+		`ec2.instance where id "i-1"`,
+		`ec2.instance where (id = "i-1"`,
+		`ec2.instance where id = "i-1`,
+		`ec2.instance where id ~ "["`,
+		`ec2.instance select`,
+		`ec2.instance limit x`,
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("expected an error parsing %q", input)
+		}
+	}
+}
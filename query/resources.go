@@ -0,0 +1,791 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// adder registers a resource found in the data being queried. Pass an empty region for global
+// resources and nil tags to have them read from the Tags field of the resource
+type adder = func(region string, id string, tags map[string]string, value interface{})
+
+type resourceType struct {
+	// service is what needs to be loaded for this resource type, as in loader.WithServices
+	service string
+	// aliases are short, case insensitive, names for commonly used fields
+	aliases map[string]string
+	// fields are selected when the query has no select clause
+	fields  []string
+	extract func(dump *awst.AWS, add adder)
+}
+
+var resourceTypes = map[string]resourceType{
+	"ec2.instance": {
+		service: "ec2",
+		aliases: map[string]string{
+			"name":      "tag.Name",
+			"type":      "InstanceType",
+			"state":     "State.Name",
+			"privateip": "PrivateIpAddress",
+			"publicip":  "PublicIpAddress",
+			"az":        "Placement.AvailabilityZone",
+		},
+		fields: []string{"region", "id", "tag.Name", "type", "state", "privateIp", "publicIp"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, reservation := range region.EC2.Reservations {
+					for _, instance := range reservation.Instances {
+						add(name, aws.ToString(instance.InstanceId), nil, instance)
+					}
+				}
+			}
+		},
+	},
+	"ec2.volume": {
+		service: "ebs",
+		aliases: map[string]string{
+			"name":     "tag.Name",
+			"type":     "VolumeType",
+			"instance": "Attachments.0.InstanceId",
+		},
+		fields: []string{"region", "id", "type", "size", "state", "instance"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, volume := range region.EC2.Volumes {
+					add(name, aws.ToString(volume.VolumeId), nil, volume)
+				}
+			}
+		},
+	},
+	"ec2.vpc": {
+		service: "vpc",
+		aliases: map[string]string{
+			"name":    "tag.Name",
+			"cidr":    "CidrBlock",
+			"default": "IsDefault",
+		},
+		fields: []string{"region", "id", "tag.Name", "cidr", "state", "default"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, vpc := range region.EC2.Vpcs {
+					add(name, aws.ToString(vpc.VpcId), nil, vpc)
+				}
+			}
+		},
+	},
+	"ec2.subnet": {
+		service: "subnet",
+		aliases: map[string]string{
+			"name":         "tag.Name",
+			"vpc":          "VpcId",
+			"cidr":         "CidrBlock",
+			"az":           "AvailabilityZone",
+			"availableips": "AvailableIpAddressCount",
+		},
+		fields: []string{"region", "id", "tag.Name", "vpc", "cidr", "az", "availableIps"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, subnet := range region.EC2.Subnets {
+					add(name, aws.ToString(subnet.SubnetId), nil, subnet)
+				}
+			}
+		},
+	},
+	"ec2.routetable": {
+		service: "routetable",
+		aliases: map[string]string{
+			"name": "tag.Name",
+			"vpc":  "VpcId",
+		},
+		fields: []string{"region", "id", "tag.Name", "vpc"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, table := range region.EC2.RouteTables {
+					add(name, aws.ToString(table.RouteTableId), nil, table)
+				}
+			}
+		},
+	},
+	"ec2.securitygroup": {
+		service: "securitygroup",
+		aliases: map[string]string{
+			"name": "GroupName",
+			"vpc":  "VpcId",
+		},
+		fields: []string{"region", "id", "name", "vpc", "Description"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, group := range region.EC2.SecurityGroups {
+					add(name, aws.ToString(group.GroupId), nil, group)
+				}
+			}
+		},
+	},
+	"ec2.networkacl": {
+		service: "networkacl",
+		aliases: map[string]string{
+			"name":    "tag.Name",
+			"vpc":     "VpcId",
+			"default": "IsDefault",
+		},
+		fields: []string{"region", "id", "tag.Name", "vpc", "default"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, acl := range region.EC2.NetworkAcls {
+					add(name, aws.ToString(acl.NetworkAclId), nil, acl)
+				}
+			}
+		},
+	},
+	"ec2.natgateway": {
+		service: "natgateway",
+		aliases: map[string]string{
+			"name":     "tag.Name",
+			"vpc":      "VpcId",
+			"subnet":   "SubnetId",
+			"publicip": "NatGatewayAddresses.0.PublicIp",
+		},
+		fields: []string{"region", "id", "tag.Name", "vpc", "subnet", "state", "publicIp"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, gateway := range region.EC2.NatGateways {
+					add(name, aws.ToString(gateway.NatGatewayId), nil, gateway)
+				}
+			}
+		},
+	},
+	"ec2.internetgateway": {
+		service: "internetgateway",
+		aliases: map[string]string{
+			"name": "tag.Name",
+			"vpc":  "Attachments.0.VpcId",
+		},
+		fields: []string{"region", "id", "tag.Name", "vpc"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, gateway := range region.EC2.InternetGateways {
+					add(name, aws.ToString(gateway.InternetGatewayId), nil, gateway)
+				}
+			}
+		},
+	},
+	"ec2.vpcpeering": {
+		service: "vpcpeering",
+		aliases: map[string]string{
+			"name":      "tag.Name",
+			"requester": "RequesterVpcInfo.VpcId",
+			"accepter":  "AccepterVpcInfo.VpcId",
+			"status":    "Status.Code",
+		},
+		fields: []string{"region", "id", "tag.Name", "requester", "accepter", "status"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, peering := range region.EC2.VpcPeeringConnections {
+					add(name, aws.ToString(peering.VpcPeeringConnectionId), nil, peering)
+				}
+			}
+		},
+	},
+	"ec2.vpcendpoint": {
+		service: "vpcendpoint",
+		aliases: map[string]string{
+			"name":    "tag.Name",
+			"vpc":     "VpcId",
+			"service": "ServiceName",
+			"type":    "VpcEndpointType",
+		},
+		fields: []string{"region", "id", "vpc", "service", "type", "state"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, endpoint := range region.EC2.VpcEndpoints {
+					add(name, aws.ToString(endpoint.VpcEndpointId), nil, endpoint)
+				}
+			}
+		},
+	},
+	"ec2.eni": {
+		service: "eni",
+		aliases: map[string]string{
+			"vpc":       "VpcId",
+			"subnet":    "SubnetId",
+			"privateip": "PrivateIpAddress",
+			"publicip":  "Association.PublicIp",
+			"instance":  "Attachment.InstanceId",
+			"type":      "InterfaceType",
+		},
+		fields: []string{"region", "id", "subnet", "privateIp", "instance", "type", "status"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, eni := range region.EC2.NetworkInterfaces {
+					tags := map[string]string{}
+					for _, tag := range eni.TagSet {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					add(name, aws.ToString(eni.NetworkInterfaceId), tags, eni)
+				}
+			}
+		},
+	},
+	"ec2.eip": {
+		service: "eip",
+		aliases: map[string]string{
+			"name":      "tag.Name",
+			"privateip": "PrivateIpAddress",
+			"instance":  "InstanceId",
+			"eni":       "NetworkInterfaceId",
+		},
+		fields: []string{"region", "id", "publicIp", "instance", "eni", "privateIp"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, address := range region.EC2.Addresses {
+					// addresses of ec2 classic have no allocation id
+					id := aws.ToString(address.AllocationId)
+					if id == "" {
+						id = aws.ToString(address.PublicIp)
+					}
+					add(name, id, nil, address)
+				}
+			}
+		},
+	},
+	"elb.v1": {
+		service: "elb",
+		aliases: map[string]string{
+			"name": "LoadBalancerName",
+			"dns":  "DNSName",
+		},
+		fields: []string{"region", "id", "scheme", "dns"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, lb := range region.ELB.V1.LoadBalancers {
+					add(name, aws.ToString(lb.LoadBalancerName), nil, lb)
+				}
+			}
+		},
+	},
+	"elb.v2": {
+		service: "elb",
+		aliases: map[string]string{
+			"name":  "LoadBalancerName",
+			"dns":   "DNSName",
+			"state": "State.Code",
+		},
+		fields: []string{"region", "name", "type", "scheme", "state", "dns"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, lb := range region.ELB.V2.LoadBalancers {
+					add(name, aws.ToString(lb.LoadBalancerArn), nil, lb)
+				}
+			}
+		},
+	},
+	"s3.bucket": {
+		service: "s3",
+		aliases: map[string]string{
+			"created": "CreationDate",
+		},
+		fields: []string{"id", "created"},
+		extract: func(dump *awst.AWS, add adder) {
+			// bucket listing is global, so the same buckets show up in every region
+			seen := map[string]struct{}{}
+			for _, region := range dump.Regions {
+				for _, bucket := range region.S3.Buckets {
+					name := aws.ToString(bucket.Name)
+					if _, ok := seen[name]; ok {
+						continue
+					}
+					seen[name] = struct{}{}
+					tags := map[string]string{}
+					for _, tag := range region.S3.BucketTags[name] {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					add("", name, tags, bucket)
+				}
+			}
+		},
+	},
+	"elasticsearch.domain": {
+		service: "elasticsearch",
+		aliases: map[string]string{
+			"name":          "Status.DomainName",
+			"version":       "Status.ElasticsearchVersion",
+			"endpoint":      "Status.Endpoint",
+			"instancetype":  "Status.ElasticsearchClusterConfig.InstanceType",
+			"instancecount": "Status.ElasticsearchClusterConfig.InstanceCount",
+		},
+		fields: []string{"region", "id", "version", "instanceType", "instanceCount", "endpoint"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for domainName, domain := range region.Elasticsearch.Domains {
+					add(name, domainName, nil, domain)
+				}
+			}
+		},
+	},
+	"lambda.function": {
+		service: "lambda",
+		aliases: map[string]string{
+			"name":    "Configuration.FunctionName",
+			"runtime": "Configuration.Runtime",
+			"memory":  "Configuration.MemorySize",
+			"timeout": "Configuration.Timeout",
+		},
+		fields: []string{"region", "id", "runtime", "memory", "timeout"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for functionName, function := range region.Lambda.Functions {
+					add(name, functionName, function.Tags, function)
+				}
+			}
+		},
+	},
+	"ec2.launchtemplate": {
+		service: "launchtemplate",
+		aliases: map[string]string{
+			"name":    "Template.LaunchTemplateName",
+			"default": "Template.DefaultVersionNumber",
+			"latest":  "Template.LatestVersionNumber",
+			"image":   "DefaultVersion.LaunchTemplateData.ImageId",
+			"type":    "DefaultVersion.LaunchTemplateData.InstanceType",
+		},
+		fields: []string{"region", "id", "name", "default", "latest", "image", "type"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, template := range region.EC2.LaunchTemplates {
+					tags := map[string]string{}
+					for _, tag := range template.Template.Tags {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					add(name, aws.ToString(template.Template.LaunchTemplateId), tags, template)
+				}
+			}
+		},
+	},
+	"autoscaling.group": {
+		service: "autoscaling",
+		aliases: map[string]string{
+			"min":                 "MinSize",
+			"max":                 "MaxSize",
+			"desired":             "DesiredCapacity",
+			"launchtemplate":      "LaunchTemplate.LaunchTemplateName",
+			"launchconfiguration": "LaunchConfigurationName",
+		},
+		fields: []string{"region", "id", "min", "max", "desired", "launchTemplate", "launchConfiguration"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, group := range region.AutoScaling.Groups {
+					add(name, aws.ToString(group.AutoScalingGroupName), nil, group)
+				}
+			}
+		},
+	},
+	"autoscaling.launchconfiguration": {
+		service: "autoscaling",
+		aliases: map[string]string{
+			"image": "ImageId",
+			"type":  "InstanceType",
+		},
+		fields: []string{"region", "id", "image", "type", "CreatedTime"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, configuration := range region.AutoScaling.LaunchConfigurations {
+					add(name, aws.ToString(configuration.LaunchConfigurationName), nil, configuration)
+				}
+			}
+		},
+	},
+	"autoscaling.policy": {
+		service: "autoscaling",
+		aliases: map[string]string{
+			"group": "AutoScalingGroupName",
+			"type":  "PolicyType",
+		},
+		fields: []string{"region", "id", "group", "type", "enabled"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, policy := range region.AutoScaling.Policies {
+					id := aws.ToString(policy.AutoScalingGroupName) + "/" + aws.ToString(policy.PolicyName)
+					add(name, id, nil, policy)
+				}
+			}
+		},
+	},
+	"autoscaling.scheduledaction": {
+		service: "autoscaling",
+		aliases: map[string]string{
+			"group":   "AutoScalingGroupName",
+			"min":     "MinSize",
+			"max":     "MaxSize",
+			"desired": "DesiredCapacity",
+		},
+		fields: []string{"region", "id", "recurrence", "min", "max", "desired"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, action := range region.AutoScaling.ScheduledActions {
+					id := aws.ToString(action.AutoScalingGroupName) + "/" + aws.ToString(action.ScheduledActionName)
+					add(name, id, nil, action)
+				}
+			}
+		},
+	},
+	"ecs.cluster": {
+		service: "ecs",
+		aliases: map[string]string{
+			"services":  "ActiveServicesCount",
+			"tasks":     "RunningTasksCount",
+			"instances": "RegisteredContainerInstancesCount",
+		},
+		fields: []string{"region", "id", "status", "services", "tasks", "instances"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.ECS.Clusters {
+					if cluster.Cluster != nil {
+						add(name, clusterName, nil, cluster.Cluster)
+					}
+				}
+			}
+		},
+	},
+	"ecs.service": {
+		service: "ecs",
+		aliases: map[string]string{
+			"name":       "ServiceName",
+			"cluster":    "ClusterArn",
+			"desired":    "DesiredCount",
+			"running":    "RunningCount",
+			"definition": "TaskDefinition",
+		},
+		fields: []string{"region", "id", "status", "desired", "running", "launchType", "definition"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.ECS.Clusters {
+					for _, service := range cluster.Services {
+						add(name, clusterName+"/"+aws.ToString(service.ServiceName), nil, service)
+					}
+				}
+			}
+		},
+	},
+	"ecs.task": {
+		service: "ecs",
+		aliases: map[string]string{
+			"cluster":    "ClusterArn",
+			"definition": "TaskDefinitionArn",
+			"status":     "LastStatus",
+		},
+		fields: []string{"region", "id", "group", "status", "launchType", "definition"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.ECS.Clusters {
+					for _, task := range cluster.Tasks {
+						arn := aws.ToString(task.TaskArn)
+						add(name, clusterName+"/"+arn[strings.LastIndex(arn, "/")+1:], nil, task)
+					}
+				}
+			}
+		},
+	},
+	"ecs.taskdefinition": {
+		service: "ecs",
+		aliases: map[string]string{
+			"role":          "TaskRoleArn",
+			"executionrole": "ExecutionRoleArn",
+		},
+		fields: []string{"region", "id", "status", "cpu", "memory", "networkMode"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, definition := range region.ECS.TaskDefinitions {
+					tags := map[string]string{}
+					for _, tag := range region.ECS.TaskDefinitionTags[aws.ToString(definition.TaskDefinitionArn)] {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					id := fmt.Sprintf("%s:%d", aws.ToString(definition.Family), definition.Revision)
+					add(name, id, tags, definition)
+				}
+			}
+		},
+	},
+	"eks.cluster": {
+		service: "eks",
+		fields:  []string{"region", "id", "status", "version", "endpoint"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.EKS.Clusters {
+					if cluster.Cluster != nil {
+						add(name, clusterName, cluster.Cluster.Tags, cluster.Cluster)
+					}
+				}
+			}
+		},
+	},
+	"eks.nodegroup": {
+		service: "eks",
+		aliases: map[string]string{
+			"cluster": "ClusterName",
+			"desired": "ScalingConfig.DesiredSize",
+			"min":     "ScalingConfig.MinSize",
+			"max":     "ScalingConfig.MaxSize",
+		},
+		fields: []string{"region", "id", "status", "instanceTypes", "desired", "version"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.EKS.Clusters {
+					for _, nodegroup := range cluster.Nodegroups {
+						add(name, clusterName+"/"+aws.ToString(nodegroup.NodegroupName), nodegroup.Tags, nodegroup)
+					}
+				}
+			}
+		},
+	},
+	"eks.fargateprofile": {
+		service: "eks",
+		aliases: map[string]string{
+			"cluster": "ClusterName",
+		},
+		fields: []string{"region", "id", "status"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for clusterName, cluster := range region.EKS.Clusters {
+					for _, profile := range cluster.FargateProfiles {
+						add(name, clusterName+"/"+aws.ToString(profile.FargateProfileName), profile.Tags, profile)
+					}
+				}
+			}
+		},
+	},
+	"rds.instance": {
+		service: "rds",
+		aliases: map[string]string{
+			"version":  "EngineVersion",
+			"class":    "DBInstanceClass",
+			"status":   "DBInstanceStatus",
+			"cluster":  "DBClusterIdentifier",
+			"endpoint": "Endpoint.Address",
+		},
+		fields: []string{"region", "id", "engine", "version", "class", "multiAZ", "endpoint"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, instance := range region.RDS.Instances {
+					tags := map[string]string{}
+					for _, tag := range instance.TagList {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					add(name, aws.ToString(instance.DBInstanceIdentifier), tags, instance)
+				}
+			}
+		},
+	},
+	"rds.cluster": {
+		service: "rds",
+		aliases: map[string]string{
+			"version": "EngineVersion",
+		},
+		fields: []string{"region", "id", "engine", "version", "multiAZ", "endpoint"},
+		extract: func(dump *awst.AWS, add adder) {
+			for name, region := range dump.Regions {
+				for _, cluster := range region.RDS.Clusters {
+					tags := map[string]string{}
+					for _, tag := range cluster.TagList {
+						tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+					}
+					add(name, aws.ToString(cluster.DBClusterIdentifier), tags, cluster)
+				}
+			}
+		},
+	},
+	"iam.user": {
+		service: "iam",
+		aliases: map[string]string{
+			"name": "UserName",
+		},
+		fields: []string{"id", "CreateDate", "PasswordLastUsed"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, user := range dump.IAM.Users {
+				add("", aws.ToString(user.UserName), nil, user)
+			}
+		},
+	},
+	"iam.accesskey": {
+		service: "iam",
+		aliases: map[string]string{
+			"user": "UserName",
+		},
+		fields: []string{"id", "user", "status", "CreateDate"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, keys := range dump.IAM.AccessKeys {
+				for _, key := range keys {
+					add("", aws.ToString(key.AccessKeyId), nil, key)
+				}
+			}
+		},
+	},
+	"iam.role": {
+		service: "iam",
+		aliases: map[string]string{
+			"name": "RoleName",
+		},
+		fields: []string{"id", "CreateDate", "Description"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, role := range dump.IAM.Roles {
+				add("", aws.ToString(role.RoleName), nil, role)
+			}
+		},
+	},
+	"iam.group": {
+		service: "iam",
+		aliases: map[string]string{
+			"name": "GroupName",
+		},
+		fields: []string{"id", "CreateDate"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, group := range dump.IAM.Groups {
+				add("", aws.ToString(group.GroupName), nil, group)
+			}
+		},
+	},
+	"iam.policy": {
+		service: "iam",
+		aliases: map[string]string{
+			"name": "PolicyName",
+		},
+		fields: []string{"id", "name", "AttachmentCount"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, policy := range dump.IAM.Policies {
+				add("", aws.ToString(policy.Arn), nil, policy)
+			}
+		},
+	},
+	"organizations.account": {
+		service: "organizations",
+		fields:  []string{"id", "name", "email", "status"},
+		extract: func(dump *awst.AWS, add adder) {
+			for _, account := range dump.Accounts {
+				add("", aws.ToString(account.Id), nil, account)
+			}
+		},
+	},
+}
+
+// Types lists the resource types that can be queried
+func Types() []string {
+	result := make([]string, 0, len(resourceTypes))
+	for name := range resourceTypes {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// DefaultFields lists the fields printed for a resource type when a query has no select clause
+func DefaultFields(resourceType string) []string {
+	return resourceTypes[resourceType].fields
+}
+
+type resource struct {
+	region  string
+	id      string
+	tags    map[string]string
+	data    interface{}
+	aliases map[string]string
+}
+
+// field resolves a field of the resource. Besides region, id, tag.KEY and the aliases of the
+// resource type, any field of the resource can be reached by its path, eg Placement.Tenancy or
+// BlockDeviceMappings.0.DeviceName. Paths are case insensitive, tag keys are not
+func (r *resource) field(name string) interface{} {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == "*":
+		return r.data
+	case lower == "region":
+		if r.region == "" {
+			return nil
+		}
+		return r.region
+	case lower == "id":
+		return r.id
+	case lower == "tags":
+		return r.tags
+	case strings.HasPrefix(lower, "tag."):
+		value, ok := r.tags[name[len("tag."):]]
+		if !ok {
+			return nil
+		}
+		return value
+	}
+	if path, ok := r.aliases[lower]; ok {
+		return r.field(path)
+	}
+	return lookup(r.data, strings.Split(name, "."))
+}
+
+func lookup(value interface{}, path []string) interface{} {
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				for key, candidate := range v {
+					if strings.EqualFold(key, segment) {
+						next = candidate
+						break
+					}
+				}
+			}
+			value = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil
+			}
+			value = v[idx]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// normalize converts a resource to generic json values (maps, slices and scalars), so fields
+// are resolved in the same way for all resource types and match what is seen in a dump
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// tagsOf reads tags from the Tags field of a resource, which is how AWS represents them: a list
+// of Key/Value pairs
+func tagsOf(data interface{}) map[string]string {
+	result := map[string]string{}
+	list, ok := lookup(data, []string{"Tags"}).([]interface{})
+	if !ok {
+		return result
+	}
+	for _, item := range list {
+		tag, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, ok := tag["Key"].(string)
+		if !ok {
+			continue
+		}
+		value, _ := tag["Value"].(string)
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,445 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	awst "awstool/aws"
+	"awstool/loader"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// schema has one table per resource type. Every table has the id of the dumped account, which is
+// empty unless the dump was generated for multiple accounts, and a data column with the whole
+// resource as json, so fields without a column can still be queried with json_extract
+var schema = []string{
+	`CREATE TABLE ec2_instances (
+		account_id TEXT, region TEXT, instance_id TEXT, name TEXT, instance_type TEXT, state TEXT,
+		image_id TEXT, key_name TEXT, vpc_id TEXT, subnet_id TEXT, availability_zone TEXT,
+		private_ip TEXT, public_ip TEXT, launch_time TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE ec2_volumes (
+		account_id TEXT, region TEXT, volume_id TEXT, instance_id TEXT, volume_type TEXT, size INTEGER,
+		state TEXT, encrypted BOOLEAN, availability_zone TEXT, create_time TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE tags (
+		account_id TEXT, region TEXT, resource_type TEXT, resource_id TEXT, key TEXT, value TEXT
+	)`,
+	`CREATE TABLE elb_load_balancers (
+		account_id TEXT, region TEXT, name TEXT, arn TEXT, type TEXT, scheme TEXT, dns_name TEXT,
+		vpc_id TEXT, state TEXT, created_time TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE elb_instances (
+		account_id TEXT, region TEXT, load_balancer_name TEXT, instance_id TEXT
+	)`,
+	`CREATE TABLE s3_buckets (
+		account_id TEXT, name TEXT, creation_date TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE es_domains (
+		account_id TEXT, region TEXT, domain_name TEXT, arn TEXT, version TEXT, endpoint TEXT,
+		instance_type TEXT, instance_count INTEGER, data JSON
+	)`,
+	`CREATE TABLE iam_users (
+		account_id TEXT, user_name TEXT, user_id TEXT, arn TEXT, path TEXT, create_date TIMESTAMP,
+		password_last_used TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE iam_roles (
+		account_id TEXT, role_name TEXT, role_id TEXT, arn TEXT, path TEXT, create_date TIMESTAMP,
+		assume_role_policy JSON, data JSON
+	)`,
+	`CREATE TABLE iam_groups (
+		account_id TEXT, group_name TEXT, group_id TEXT, arn TEXT, path TEXT, create_date TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE iam_user_groups (
+		account_id TEXT, user_name TEXT, group_name TEXT
+	)`,
+	`CREATE TABLE iam_policies (
+		account_id TEXT, policy_name TEXT, policy_id TEXT, arn TEXT, path TEXT, attachment_count INTEGER,
+		default_version_id TEXT, create_date TIMESTAMP, update_date TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE iam_access_keys (
+		account_id TEXT, user_name TEXT, access_key_id TEXT, status TEXT, create_date TIMESTAMP
+	)`,
+	`CREATE TABLE organizations_accounts (
+		id TEXT PRIMARY KEY, name TEXT, email TEXT, arn TEXT, status TEXT, joined_method TEXT,
+		joined_timestamp TIMESTAMP, data JSON
+	)`,
+	`CREATE TABLE failures (
+		account_id TEXT, service TEXT, region TEXT, operation TEXT, error_class TEXT, error_code TEXT, message TEXT
+	)`,
+}
+
+// Write creates a SQLite database at the given path, replacing it if it already exists, with
+// one table per resource type of the dump
+func Write(path string, dump *awst.AWS) error {
+	return write(path, func(w *writer) error {
+		return w.writeAWS("", dump)
+	})
+}
+
+// WriteAccounts is the same as Write, but for data loaded from multiple accounts. Resources of
+// each account are identified by the account_id column
+func WriteAccounts(path string, accounts *awst.Accounts) error {
+	return write(path, func(w *writer) error {
+		accountIds := make([]string, 0, len(accounts.Accounts))
+		for accountId := range accounts.Accounts {
+			accountIds = append(accountIds, accountId)
+		}
+		sort.Strings(accountIds)
+
+		for _, accountId := range accountIds {
+			account := accounts.Accounts[accountId]
+			if err := w.writeOrganizationAccount(account.Account); err != nil {
+				return err
+			}
+			for _, message := range account.Errors {
+				if err := w.insert("failures", accountId, nil, nil, nil, loader.ErrorClassOther, nil, message); err != nil {
+					return err
+				}
+			}
+			if account.AWS != nil {
+				if err := w.writeAWS(accountId, account.AWS); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func write(path string, fn func(w *writer) error) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove existing database %s: %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	defer db.Close()
+
+	for _, statement := range schema {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	// a single transaction makes inserts orders of magnitude faster
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	w := writer{tx: tx, statements: map[string]*sql.Stmt{}}
+	if err := fn(&w); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Infof("Wrote SQLite database %s", path)
+	return nil
+}
+
+type writer struct {
+	tx         *sql.Tx
+	statements map[string]*sql.Stmt
+}
+
+// insert adds a row to the table. Values are passed in the order of the table columns and may
+// be pointers, which are stored as NULL when nil
+func (w *writer) insert(table string, values ...interface{}) error {
+	statement, ok := w.statements[table]
+	if !ok {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+		var err error
+		statement, err = w.tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", table, placeholders))
+		if err != nil {
+			return fmt.Errorf("failed to prepare insert into %s: %w", table, err)
+		}
+		w.statements[table] = statement
+	}
+	if _, err := statement.Exec(values...); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+func (w *writer) insertTags(accountId string, region string, resourceType string, resourceId string, tags map[string]string) error {
+	for key, value := range tags {
+		if err := w.insert("tags", accountId, region, resourceType, resourceId, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeAWS(accountId string, dump *awst.AWS) error {
+	for _, account := range dump.Accounts {
+		if err := w.writeOrganizationAccount(account); err != nil {
+			return err
+		}
+	}
+	if err := w.writeIAM(accountId, dump); err != nil {
+		return err
+	}
+
+	regions := make([]string, 0, len(dump.Regions))
+	for name := range dump.Regions {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions)
+
+	buckets := map[string]struct{}{}
+	for _, name := range regions {
+		region := dump.Regions[name]
+		if err := w.writeEC2(accountId, name, region.EC2); err != nil {
+			return err
+		}
+		if err := w.writeELB(accountId, name, region.ELB); err != nil {
+			return err
+		}
+		if err := w.writeElasticsearch(accountId, name, region.Elasticsearch); err != nil {
+			return err
+		}
+		// bucket listing is global, so the same buckets show up in every region
+		for _, bucket := range region.S3.Buckets {
+			bucketName := aws.ToString(bucket.Name)
+			if _, ok := buckets[bucketName]; ok {
+				continue
+			}
+			buckets[bucketName] = struct{}{}
+			if err := w.insert("s3_buckets", accountId, bucket.Name, bucket.CreationDate, jsonValue(bucket)); err != nil {
+				return err
+			}
+			tags := map[string]string{}
+			for _, tag := range region.S3.BucketTags[bucketName] {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			if err := w.insertTags(accountId, "", "s3.bucket", bucketName, tags); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, failure := range dump.Failures {
+		err := w.insert(
+			"failures", accountId, failure.Service, failure.Region, failure.Operation,
+			failure.ErrorClass, failure.ErrorCode, failure.Message,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeOrganizationAccount(account orgTypes.Account) error {
+	// the same account shows up in the organization data of every dumped account
+	_, err := w.tx.Exec(
+		"INSERT OR REPLACE INTO organizations_accounts VALUES (?,?,?,?,?,?,?,?)",
+		account.Id, account.Name, account.Email, account.Arn, account.Status, account.JoinedMethod,
+		account.JoinedTimestamp, jsonValue(account),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert into organizations_accounts: %w", err)
+	}
+	return nil
+}
+
+func (w *writer) writeIAM(accountId string, dump *awst.AWS) error {
+	for _, user := range dump.IAM.Users {
+		err := w.insert(
+			"iam_users", accountId, user.UserName, user.UserId, user.Arn, user.Path, user.CreateDate,
+			user.PasswordLastUsed, jsonValue(user),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, role := range dump.IAM.Roles {
+		err := w.insert(
+			"iam_roles", accountId, role.RoleName, role.RoleId, role.Arn, role.Path, role.CreateDate,
+			jsonValue(role.AssumeRolePolicyDocument), jsonValue(role),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range dump.IAM.Groups {
+		err := w.insert(
+			"iam_groups", accountId, group.GroupName, group.GroupId, group.Arn, group.Path, group.CreateDate,
+			jsonValue(group),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for userName, groups := range dump.IAM.UserGroups {
+		for _, group := range groups {
+			if err := w.insert("iam_user_groups", accountId, userName, group.GroupName); err != nil {
+				return err
+			}
+		}
+	}
+	for _, policy := range dump.IAM.Policies {
+		err := w.insert(
+			"iam_policies", accountId, policy.PolicyName, policy.PolicyId, policy.Arn, policy.Path,
+			policy.AttachmentCount, policy.DefaultVersionId, policy.CreateDate, policy.UpdateDate, jsonValue(policy),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for userName, keys := range dump.IAM.AccessKeys {
+		for _, key := range keys {
+			err := w.insert("iam_access_keys", accountId, userName, key.AccessKeyId, key.Status, key.CreateDate)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeEC2(accountId string, region string, data awst.EC2) error {
+	for _, reservation := range data.Reservations {
+		for _, instance := range reservation.Instances {
+			instanceId := aws.ToString(instance.InstanceId)
+			tags := ec2Tags(instance.Tags)
+			var state, availabilityZone interface{}
+			if instance.State != nil {
+				state = instance.State.Name
+			}
+			if instance.Placement != nil {
+				availabilityZone = instance.Placement.AvailabilityZone
+			}
+			var name interface{}
+			if value, ok := tags["Name"]; ok {
+				name = value
+			}
+			err := w.insert(
+				"ec2_instances", accountId, region, instanceId, name, instance.InstanceType, state,
+				instance.ImageId, instance.KeyName, instance.VpcId, instance.SubnetId, availabilityZone,
+				instance.PrivateIpAddress, instance.PublicIpAddress, instance.LaunchTime, jsonValue(instance),
+			)
+			if err != nil {
+				return err
+			}
+			if err := w.insertTags(accountId, region, "ec2.instance", instanceId, tags); err != nil {
+				return err
+			}
+		}
+	}
+	for _, volume := range data.Volumes {
+		volumeId := aws.ToString(volume.VolumeId)
+		var instanceId interface{}
+		if len(volume.Attachments) > 0 {
+			instanceId = volume.Attachments[0].InstanceId
+		}
+		err := w.insert(
+			"ec2_volumes", accountId, region, volumeId, instanceId, volume.VolumeType, volume.Size,
+			volume.State, volume.Encrypted, volume.AvailabilityZone, volume.CreateTime, jsonValue(volume),
+		)
+		if err != nil {
+			return err
+		}
+		if err := w.insertTags(accountId, region, "ec2.volume", volumeId, ec2Tags(volume.Tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeELB(accountId string, region string, data awst.ELB) error {
+	for _, lb := range data.V1.LoadBalancers {
+		err := w.insert(
+			"elb_load_balancers", accountId, region, lb.LoadBalancerName, nil, "classic", lb.Scheme,
+			lb.DNSName, lb.VPCId, nil, lb.CreatedTime, jsonValue(lb),
+		)
+		if err != nil {
+			return err
+		}
+		for _, instance := range lb.Instances {
+			if err := w.insert("elb_instances", accountId, region, lb.LoadBalancerName, instance.InstanceId); err != nil {
+				return err
+			}
+		}
+	}
+	for _, lb := range data.V2.LoadBalancers {
+		var state interface{}
+		if lb.State != nil {
+			state = lb.State.Code
+		}
+		err := w.insert(
+			"elb_load_balancers", accountId, region, lb.LoadBalancerName, lb.LoadBalancerArn, lb.Type,
+			lb.Scheme, lb.DNSName, lb.VpcId, state, lb.CreatedTime, jsonValue(lb),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeElasticsearch(accountId string, region string, data awst.Elasticsearch) error {
+	for domainName, domain := range data.Domains {
+		var arn, version, endpoint, instanceType, instanceCount interface{}
+		if status := domain.Status; status != nil {
+			arn = status.ARN
+			version = status.ElasticsearchVersion
+			endpoint = status.Endpoint
+			if status.Endpoint == nil && len(status.Endpoints) > 0 {
+				endpoint = status.Endpoints["vpc"]
+			}
+			if status.ElasticsearchClusterConfig != nil {
+				instanceType = status.ElasticsearchClusterConfig.InstanceType
+				instanceCount = status.ElasticsearchClusterConfig.InstanceCount
+			}
+		}
+		err := w.insert(
+			"es_domains", accountId, region, domainName, arn, version, endpoint, instanceType, instanceCount,
+			jsonValue(domain),
+		)
+		if err != nil {
+			return err
+		}
+		tags := map[string]string{}
+		for _, tag := range domain.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		if err := w.insertTags(accountId, region, "elasticsearch.domain", domainName, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ec2Tags(tags []ec2Types.Tag) map[string]string {
+	result := map[string]string{}
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
+
+// jsonValue encodes values for json columns. Encoding errors are not expected for dumped data,
+// as the same data is encoded to json by the dump command
+func jsonValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Warnf("Failed to encode %T to json: %v", value, err)
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	awst "awstool/aws"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestWrite(t *testing.T) {
+	dump := awst.New()
+	for _, name := range []string{"us-east-1", "eu-west-1"} {
+		region := awst.NewRegion(name)
+		region.S3.Buckets = []s3Types.Bucket{{Name: aws.String("bucket")}}
+		dump.Regions[name] = region
+	}
+	region := dump.Regions["us-east-1"]
+	region.EC2.Reservations = []ec2Types.Reservation{{
+		Instances: []ec2Types.Instance{
+			{
+				InstanceId:   aws.String("i-owned"),
+				InstanceType: ec2Types.InstanceTypeM5Large,
+				State:        &ec2Types.InstanceState{Name: ec2Types.InstanceStateNameRunning},
+				Tags: []ec2Types.Tag{
+					{Key: aws.String("Env"), Value: aws.String("prod")},
+					{Key: aws.String("Owner"), Value: aws.String("me")},
+				},
+			},
+			{
+				InstanceId:   aws.String("i-unowned"),
+				InstanceType: ec2Types.InstanceTypeM5Large,
+				Tags:         []ec2Types.Tag{{Key: aws.String("Env"), Value: aws.String("prod")}},
+			},
+		},
+	}}
+	region.EC2.Volumes = []ec2Types.Volume{{
+		VolumeId:    aws.String("vol-1"),
+		Size:        aws.Int32(8),
+		Attachments: []ec2Types.VolumeAttachment{{InstanceId: aws.String("i-owned")}},
+	}}
+	dump.Regions["us-east-1"] = region
+
+	path := filepath.Join(t.TempDir(), "dump.db")
+	if err := Write(path, &dump); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var instanceId string
+	err = db.QueryRow(`
+		SELECT i.instance_id FROM ec2_instances i
+		JOIN tags env ON env.resource_id = i.instance_id AND env.key = 'Env' AND env.value = 'prod'
+		LEFT JOIN tags owner ON owner.resource_id = i.instance_id AND owner.key = 'Owner'
+		WHERE i.instance_type LIKE 'm5.%' AND owner.key IS NULL
+	`).Scan(&instanceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instanceId != "i-unowned" {
+		t.Errorf("expected i-unowned, got %s", instanceId)
+	}
+
+	var size int
+	err = db.QueryRow(`
+		SELECT v.size FROM ec2_volumes v JOIN ec2_instances i ON i.instance_id = v.instance_id
+		WHERE i.state = 'running'
+	`).Scan(&size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 8 {
+		t.Errorf("expected volume size 8, got %d", size)
+	}
+
+	var buckets int
+	if err := db.QueryRow("SELECT COUNT(*) FROM s3_buckets").Scan(&buckets); err != nil {
+		t.Fatal(err)
+	}
+	if buckets != 1 {
+		t.Errorf("expected buckets listed in every region to be written once, got %d", buckets)
+	}
+}